@@ -0,0 +1,100 @@
+// Package secret 提供密钥管理的API控制器
+package secret
+
+import (
+	"server/core/app/request"
+	"server/core/app/response"
+	"server/core/app/webapi"
+	"server/service/secret"
+
+	"github.com/gin-gonic/gin"
+)
+
+// SecretApp 密钥接口控制器
+// 提供密钥的增删改查、轮换和使用审计查询，接口不返回密钥值
+type SecretApp struct {
+	webapi.BaseApp[secret.Secret] // 继承通用接口实现
+}
+
+// AddRoutes 添加密钥相关路由
+// 参数 parentGroup: 父路由组
+func AddRoutes(parentGroup *gin.RouterGroup) {
+	// 创建密钥路由组
+	group := parentGroup.Group("/secret")
+	app := SecretApp{}
+
+	// 添加基础CRUD路由
+	webapi.AddBaseRoutes(group, &app)
+
+	// 设置更新操作时允许更新的字段，密钥值只能通过轮换修改
+	app.UpdateFields = []string{"name", "project_dir_id", "remark"}
+	// 轮换密钥值
+	group.POST("/rotate/:id", app.Rotate)
+	// 查询密钥的使用情况
+	group.GET("/usage/:id", app.Usage)
+}
+
+// Load 加载密钥，不返回密钥值
+// 参数 ctx: 请求上下文
+func (app SecretApp) Load(ctx *gin.Context) {
+	var entity secret.Secret
+	entity, err := entity.Load(ctx.Param("id"))
+	if err != nil {
+		response.Error(ctx, err)
+		return
+	}
+	entity.Value = ""
+	response.Data(ctx, "", entity)
+}
+
+// Save 保存密钥，不返回密钥值
+// 参数 ctx: 请求上下文
+func (app SecretApp) Save(ctx *gin.Context) {
+	var entity secret.Secret
+	if err := ctx.BindJSON(&entity); err != nil {
+		response.BadRequest(ctx, "参数错误！")
+		return
+	}
+	entity.SetOperatorUID(request.GetUserID(ctx))
+	if err := entity.Save(&entity, app.UpdateFields...); err != nil {
+		response.Error(ctx, err)
+		return
+	}
+	entity.Value = ""
+	response.Data(ctx, "", entity)
+}
+
+// Rotate 轮换密钥值，返回使用该密钥的实体
+// 参数 ctx: 请求上下文
+func (app SecretApp) Rotate(ctx *gin.Context) {
+	var body struct {
+		Value string `json:"value"`
+	}
+	if err := ctx.BindJSON(&body); err != nil {
+		response.BadRequest(ctx, "参数错误！")
+		return
+	}
+	usage, err := secret.Secret{}.Rotate(ctx.Param("id"), body.Value)
+	if err != nil {
+		response.Error(ctx, err)
+		return
+	}
+	response.Data(ctx, "轮换成功！", usage)
+}
+
+// Usage 查询使用密钥的流程和任务
+// 参数 ctx: 请求上下文
+func (app SecretApp) Usage(ctx *gin.Context) {
+	var entity secret.Secret
+	entity, err := entity.Load(ctx.Param("id"))
+	if err != nil {
+		response.Error(ctx, err)
+		return
+	}
+	list, err := secret.SecretUsage{}.ListBySecret(entity.ID)
+	if err != nil {
+		response.Error(ctx, err)
+		return
+	}
+	response.Data(ctx, "", list)
+}
@@ -0,0 +1,244 @@
+// Package logtail 提供执行中的计划任务和流程日志的实时查看接口
+// 与终端一致：先通过认证接口获取一次性令牌，再使用令牌建立SSE连接跟踪日志文件
+package logtail
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"os"
+	"server/app/term"
+	"server/core/app/request"
+	"server/core/app/response"
+	"server/middleware"
+	"server/service/basic"
+	"server/service/scheduled"
+	"server/service/scheduled/log"
+	"server/service/sflow"
+	"server/utils"
+	"server/utils/cache"
+	"server/utils/data"
+	"server/utils/logger"
+	"strings"
+	"time"
+
+	"github.com/duke-git/lancet/v2/convertor"
+	"github.com/duke-git/lancet/v2/random"
+	"github.com/gin-gonic/gin"
+)
+
+// 日志类型
+const (
+	KindSchLog   = "sch"   // 计划任务日志
+	KindSFlowLog = "sflow" // 流程日志
+)
+
+const (
+	tailClientPrefix      = "logtail_"             // 日志跟踪客户端ID前缀
+	tailPollInterval      = 500 * time.Millisecond // 检查日志文件和执行状态的间隔
+	tailHeartbeatInterval = 15 * time.Second       // 心跳间隔
+	tailChunkSize         = 64 * 1024              // 每次推送的最大字节数
+)
+
+// logState 日志所属的计划任务或流程、执行状态、日志文件路径和日志内容
+type logState struct {
+	OwnerID uint // 计划任务ID或流程ID
+	Status  int
+	LogPath string
+	LogText string
+}
+
+// LogChunk 推送的日志内容
+type LogChunk struct {
+	Offset int64  `json:"offset"` // 内容在日志文件中的起始位置
+	Next   int64  `json:"next"`   // 下一次读取的位置，断线重连时作为offset参数
+	Text   string `json:"text"`   // 日志内容
+}
+
+// LogEnd 执行结束时推送的结束标记
+type LogEnd struct {
+	Status int    `json:"status"` // 最终状态
+	Offset int64  `json:"offset"` // 日志文件的结束位置
+	Text   string `json:"text"`   // 日志记录中保存的执行结果
+}
+
+// AddRoutes 注册日志实时查看相关的路由
+func AddRoutes(parentGroup *gin.RouterGroup) {
+	group1 := parentGroup.Group("/auth", middleware.AuthMiddleware) // 需要认证的路由组
+	group2 := parentGroup.Group("/sse")                             // SSE路由组
+	group1.GET("/token/:kind/:logId", GetToken)                     // 获取日志跟踪token，kind为sch或sflow
+	group2.GET("/:clientId/:token", StreamLog)                      // 打开日志跟踪
+}
+
+// loadState 查询日志的执行状态
+func loadState(kind string, logID uint) (logState, error) {
+	switch kind {
+	case KindSchLog:
+		entity, err := log.SchLog{}.State(logID)
+		return logState{OwnerID: entity.TaskId, Status: entity.Status, LogPath: entity.LogPath, LogText: entity.LogText}, err
+	case KindSFlowLog:
+		entity, err := sflow.SFlowLog{}.State(logID)
+		return logState{OwnerID: entity.SFlowId, Status: entity.Status, LogPath: entity.LogPath, LogText: entity.LogText}, err
+	}
+	return logState{}, errors.New("不支持的日志类型: " + kind)
+}
+
+// checkAccess 检查用户是否可以查看日志
+// 登录用户拥有全部权限，用户已删除或日志所属的计划任务、流程已删除时不能查看
+func checkAccess(kind string, state logState, userID uint) error {
+	exists, err := (&basic.User{}).Exists(userID)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return errors.New("无权查看该日志")
+	}
+	var ownerID uint
+	switch kind {
+	case KindSchLog:
+		task, err := scheduled.SchTask{}.Load(state.OwnerID)
+		if err != nil {
+			return err
+		}
+		ownerID = task.ID
+	case KindSFlowLog:
+		flow, err := sflow.SFlow{}.Load(state.OwnerID)
+		if err != nil {
+			return err
+		}
+		ownerID = flow.ID
+	}
+	if ownerID == 0 {
+		return errors.New("无权查看该日志：所属的任务或流程不存在")
+	}
+	return nil
+}
+
+// GetToken 获取日志跟踪连接的一次性令牌
+func GetToken(ctx *gin.Context) {
+	kind := ctx.Param("kind")
+	logID := utils.ToUint(ctx.Param("logId"))
+	if logID == 0 {
+		response.BadRequest(ctx, "日志ID不能为空")
+		return
+	}
+	state, err := loadState(kind, logID)
+	if err != nil {
+		response.Error(ctx, err)
+		return
+	}
+	// 只为可以查看该日志的用户签发令牌
+	if err := checkAccess(kind, state, request.GetUserID(ctx)); err != nil {
+		response.Forbidden(ctx, err.Error())
+		return
+	}
+	userId := convertor.ToString(request.GetUserID(ctx))
+	token, _ := random.UUIdV4()
+	clientId := tailClientPrefix + kind + "_" + convertor.ToString(logID) + "_" + userId
+	// 设置令牌缓存，有效期30秒
+	cache.GetCacheSystem().SetExpire(clientId, token, 30)
+	response.Data(ctx, "", data.Map{"clientId": clientId, "token": token})
+}
+
+// StreamLog 以SSE方式跟踪日志文件，推送新写入的内容
+// 查询参数offset为开始读取的位置，默认从头开始；执行结束并推送完剩余内容后发送end事件并关闭连接
+func StreamLog(ctx *gin.Context) {
+	clientId := ctx.Param("clientId")
+	token := ctx.Param("token")
+	if !strings.HasPrefix(clientId, tailClientPrefix) || !term.CheckToken(clientId, token) {
+		response.Unauthorized(ctx, "日志跟踪令牌无效")
+		return
+	}
+	parts := strings.Split(strings.TrimPrefix(clientId, tailClientPrefix), "_")
+	if len(parts) < 3 {
+		response.Unauthorized(ctx, "日志跟踪令牌无效")
+		return
+	}
+	kind, logID := parts[0], utils.ToUint(parts[1])
+	// 签发令牌后用户或日志所属的任务、流程可能已被删除，连接时重新检查
+	state, err := loadState(kind, logID)
+	if err == nil {
+		err = checkAccess(kind, state, utils.ToUint(parts[2]))
+	}
+	if err != nil {
+		response.Forbidden(ctx, err.Error())
+		return
+	}
+	offset := max(int64(utils.ToUint(ctx.Query("offset"))), 0)
+	logger.LOG.Infof("打开日志跟踪 clientId:%s, offset:%d", clientId, offset)
+
+	ctx.Writer.Header().Set("Cache-Control", "no-cache")
+	ctx.Writer.Header().Set("Connection", "keep-alive")
+	ctx.Writer.Header().Set("X-Accel-Buffering", "no")
+
+	poll := time.NewTicker(tailPollInterval)
+	defer poll.Stop()
+	heartbeat := time.NewTicker(tailHeartbeatInterval)
+	defer heartbeat.Stop()
+	ctx.Stream(func(w io.Writer) bool {
+		select {
+		case <-poll.C:
+			// 先查询状态再读取文件，执行结束时日志文件已写完，读到末尾即可发送结束标记
+			state, err := loadState(kind, logID)
+			if err != nil {
+				ctx.SSEvent("error", err.Error())
+				return false
+			}
+			finished := state.Status != 0
+			for {
+				chunk, next, err := readChunk(state.LogPath, offset, finished)
+				if err != nil {
+					ctx.SSEvent("error", err.Error())
+					return false
+				}
+				if len(chunk) == 0 {
+					break
+				}
+				ctx.SSEvent("log", LogChunk{Offset: offset, Next: next, Text: string(chunk)})
+				offset = next
+			}
+			if finished {
+				ctx.SSEvent("end", LogEnd{Status: state.Status, Offset: offset, Text: state.LogText})
+				return false
+			}
+			return true
+		case <-heartbeat.C:
+			ctx.SSEvent("heartbeat", time.Now().UnixMilli())
+			return true
+		case <-ctx.Request.Context().Done():
+			return false
+		}
+	})
+}
+
+// readChunk 从offset开始读取日志文件，返回读取的内容和下一次读取的位置
+// 执行中时只返回到最后一个换行符，避免截断一行或多字节字符；文件不存在时返回空内容
+func readChunk(path string, offset int64, finished bool) ([]byte, int64, error) {
+	if path == "" {
+		return nil, offset, nil
+	}
+	file, err := os.Open(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, offset, nil
+		}
+		return nil, offset, err
+	}
+	defer file.Close()
+	buf := make([]byte, tailChunkSize)
+	n, err := file.ReadAt(buf, offset)
+	if err != nil && err != io.EOF {
+		return nil, offset, err
+	}
+	chunk := buf[:n]
+	if !finished && n > 0 {
+		index := bytes.LastIndexByte(chunk, '\n')
+		if index >= 0 {
+			chunk = chunk[:index+1]
+		} else if n < tailChunkSize {
+			// 没有完整的行时等待写入更多内容，超过单次推送大小的长行直接推送
+			chunk = nil
+		}
+	}
+	return chunk, offset + int64(len(chunk)), nil
+}
@@ -0,0 +1,98 @@
+// Package notify 提供通知中心的API控制器：通知渠道、通知规则和发送记录
+package notify
+
+import (
+	"server/core/app/request"
+	"server/core/app/response"
+	"server/core/app/webapi"
+	"server/service/notify"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ChannelApp 通知渠道接口控制器
+type ChannelApp struct {
+	webapi.BaseApp[notify.NotifyChannel] // 继承通用接口实现
+}
+
+// RuleApp 通知规则接口控制器
+type RuleApp struct {
+	webapi.BaseApp[notify.NotifyRule] // 继承通用接口实现
+}
+
+// DeliveryApp 通知发送记录接口控制器
+type DeliveryApp struct {
+}
+
+// AddRoutes 添加通知中心相关路由
+// 参数 parentGroup: 父路由组
+func AddRoutes(parentGroup *gin.RouterGroup) {
+	// 通知渠道
+	channelGroup := parentGroup.Group("/channel")
+	channelApp := ChannelApp{}
+	webapi.AddBaseRoutes(channelGroup, &channelApp)
+	channelApp.UpdateFields = []string{"name", "type", "config", "remark"}
+	// 查询支持的渠道类型
+	channelGroup.GET("/types", channelApp.Types)
+	// 发送测试消息
+	channelGroup.POST("/test/:id", channelApp.Test)
+
+	// 通知规则
+	ruleGroup := parentGroup.Group("/rule")
+	ruleApp := RuleApp{}
+	webapi.AddBaseRoutes(ruleGroup, &ruleApp)
+	ruleApp.UpdateFields = []string{"name", "event", "target_id", "channel_ids", "threshold", "remark"}
+
+	// 发送记录
+	deliveryGroup := parentGroup.Group("/delivery")
+	deliveryApp := DeliveryApp{}
+	deliveryGroup.GET("/list", deliveryApp.List)
+	// 立即重新发送
+	deliveryGroup.POST("/retry/:id", deliveryApp.Retry)
+}
+
+// Types 查询支持的渠道类型
+// 参数 ctx: 请求上下文
+func (app ChannelApp) Types(ctx *gin.Context) {
+	response.Data(ctx, "", notify.SenderTypes())
+}
+
+// Test 向渠道发送测试消息，返回发送记录
+// 参数 ctx: 请求上下文
+func (app ChannelApp) Test(ctx *gin.Context) {
+	id, err := strconv.ParseUint(ctx.Param("id"), 10, 64)
+	if err != nil {
+		response.BadRequest(ctx, "参数错误！")
+		return
+	}
+	delivery, err := notify.TestSend(uint(id))
+	if err != nil {
+		response.Error(ctx, err)
+		return
+	}
+	response.Data(ctx, "发送成功！", delivery)
+}
+
+// List 分页查询发送记录
+// 参数 ctx: 请求上下文
+func (app DeliveryApp) List(ctx *gin.Context) {
+	query := request.GetPageQuery(ctx)
+	list, count, err := notify.NotifyDelivery{}.List(query)
+	if err != nil {
+		response.Error(ctx, err)
+		return
+	}
+	response.List(ctx, "", count, list)
+}
+
+// Retry 立即重新发送通知
+// 参数 ctx: 请求上下文
+func (app DeliveryApp) Retry(ctx *gin.Context) {
+	delivery, err := notify.Retry(ctx.Param("id"))
+	if err != nil {
+		response.Error(ctx, err)
+		return
+	}
+	response.Data(ctx, "发送成功！", delivery)
+}
@@ -7,6 +7,7 @@ import (
 	"server/core/app/response"
 	"server/core/app/webapi"
 	"server/service/scheduled"
+	"strconv"
 
 	"github.com/gin-gonic/gin"
 )
@@ -26,10 +27,123 @@ func (SchTaskApp) AddRoutes(parentGroup *gin.RouterGroup) {
 	// 注册基础路由（CRUD操作）
 	webapi.AddBaseRoutes(group, &app)
 	// 设置允许更新的字段列表
-	app.UpdateFields = []string{"name", "type", "cron", "source", "log_keep_num", "script", "project_dir_id", "remark"}
+	app.UpdateFields = []string{"name", "type", "cron", "timezone", "source", "log_keep_num", "script", "project_dir_id", "remark", "upstream", "depend_mode", "retry_max", "retry_delay", "retry_backoff", "retry_on", "misfire_policy", "misfire_max", "timeout", "work_dir", "isolate_dir", "env", "interpreter", "run_as", "limit_nice", "limit_io_class", "limit_io_level", "limit_memory", "limit_no_file", "limit_cpu"}
 
 	// 注册手动执行任务的路由
 	group.POST("/exec/:id", app.Exec)
+	// 注册获取任务依赖图的路由
+	group.GET("/graph", app.Graph)
+	// 注册调度表达式预览的路由
+	group.GET("/cron/preview", app.CronPreview)
+	// 注册正在执行的任务列表和取消执行的路由
+	group.GET("/running", app.Running)
+	group.POST("/cancel/:logId", app.Cancel)
+	// 注册集群调度状态的路由
+	group.GET("/cluster", app.Cluster)
+	// 注册数据库备份列表和恢复备份的路由
+	group.GET("/dbbackup/list/:id", app.ListBackups)
+	group.POST("/dbbackup/restore/:id", app.RestoreBackup)
+}
+
+// ListBackups 列出数据库备份任务保存的备份文件，按时间倒序
+func (app SchTaskApp) ListBackups(ctx *gin.Context) {
+	list, err := scheduled.SchTask{}.ListBackups(ctx.Param("id"))
+	if err != nil {
+		response.Error(ctx, err)
+		return
+	}
+	response.Data(ctx, "", list)
+}
+
+// RestoreBackup 将数据库备份任务的指定备份恢复到SQLite数据库
+// 请求体：{"file": "备份文件名"}，恢复前会保存当前数据库的副本
+func (app SchTaskApp) RestoreBackup(ctx *gin.Context) {
+	var body struct {
+		File string `json:"file"`
+	}
+	if err := ctx.ShouldBindJSON(&body); err != nil || body.File == "" {
+		response.BadRequest(ctx, "请指定要恢复的备份文件")
+		return
+	}
+	result, err := scheduled.SchTask{}.RestoreBackup(ctx.Param("id"), body.File)
+	if err != nil {
+		response.Error(ctx, err)
+		return
+	}
+	response.Data(ctx, "恢复成功", result)
+}
+
+// Cluster 获取集群调度状态，包括是否启用、当前节点标识和锁的实现
+func (app SchTaskApp) Cluster(ctx *gin.Context) {
+	response.Data(ctx, "", scheduled.SchTask{}.Cluster())
+}
+
+// Running 获取正在执行的计划任务
+func (app SchTaskApp) Running(ctx *gin.Context) {
+	response.Data(ctx, "", scheduled.SchTask{}.Running())
+}
+
+// Cancel 取消正在执行的任务，结束其启动的整个进程组
+// 路径参数为正在执行的日志ID
+func (app SchTaskApp) Cancel(ctx *gin.Context) {
+	logID, err := strconv.ParseUint(ctx.Param("logId"), 10, 64)
+	if err != nil {
+		response.BadRequest(ctx, "日志ID格式错误")
+		return
+	}
+	if err := (scheduled.SchTask{}).Cancel(uint(logID)); err != nil {
+		response.Error(ctx, err)
+		return
+	}
+	response.Success(ctx, "已取消")
+}
+
+// Disable 禁用计划任务，查询参数cancel=true时同时取消正在执行的实例
+func (app SchTaskApp) Disable(ctx *gin.Context) {
+	schTask := scheduled.SchTask{}
+	if err := schTask.Disable(ctx.Param("id")); err != nil {
+		response.Error(ctx, err)
+		return
+	}
+	if ctx.Query("cancel") == "true" {
+		schTask.CancelTask(ctx.Param("id"))
+	}
+	response.Success(ctx, "")
+}
+
+// Delete 删除计划任务，查询参数cancel=true时同时取消正在执行的实例
+func (app SchTaskApp) Delete(ctx *gin.Context) {
+	schTask := scheduled.SchTask{}
+	if err := schTask.Delete(ctx.Param("id")); err != nil {
+		response.Error(ctx, err)
+		return
+	}
+	if ctx.Query("cancel") == "true" {
+		schTask.CancelTask(ctx.Param("id"))
+	}
+	response.Success(ctx, "删除成功！")
+}
+
+// CronPreview 校验调度表达式，返回中英文描述和后续的执行时间
+// 查询参数：expr调度表达式（秒 分 时 日 月 周），tz时区，count预览次数（默认5，最多50）
+func (app SchTaskApp) CronPreview(ctx *gin.Context) {
+	count, _ := strconv.Atoi(ctx.DefaultQuery("count", "0"))
+	preview, err := scheduled.SchTask{}.Preview(ctx.Query("expr"), ctx.Query("tz"), count)
+	if err != nil {
+		response.BadRequest(ctx, err.Error())
+		return
+	}
+	response.Data(ctx, "", preview)
+}
+
+// Graph 获取计划任务之间的依赖图
+func (app SchTaskApp) Graph(ctx *gin.Context) {
+	graph, err := scheduled.SchTask{}.Graph()
+	if err != nil {
+		response.Error(ctx, err)
+		return
+	}
+	response.Data(ctx, "", graph)
 }
 
 // Exec 手动执行指定的计划任务
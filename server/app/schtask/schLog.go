@@ -5,6 +5,7 @@ import (
 	"server/core/app/request"
 	"server/core/app/response"
 	"server/service/scheduled/log"
+	"server/utils"
 
 	"github.com/gin-gonic/gin"
 )
@@ -18,8 +19,24 @@ type SchLogApp struct {
 func (SchLogApp) AddRoutes(parentGroup *gin.RouterGroup) {
 	group := parentGroup.Group("/log")
 	app := SchLogApp{}
-	group.GET("/list", app.List)     // 获取日志列表
-	group.GET("/load/:id", app.Load) // 加载单个日志详情
+	group.GET("/list", app.List)            // 获取日志列表
+	group.GET("/load/:id", app.Load)        // 加载单个日志详情
+	group.POST("/purge/:taskId", app.Purge) // 清除任务的所有日志
+}
+
+// Purge 删除任务所有已执行结束的日志和日志文件，执行中的日志保留
+func (SchLogApp) Purge(ctx *gin.Context) {
+	taskID := utils.ToUint(ctx.Param("taskId"))
+	if taskID == 0 {
+		response.BadRequest(ctx, "任务ID不能为空")
+		return
+	}
+	count, err := log.SchLog{}.Purge(taskID)
+	if err != nil {
+		response.Error(ctx, err)
+		return
+	}
+	response.Data(ctx, "日志已清除", gin.H{"count": count})
 }
 
 // List 处理获取计划任务日志列表的请求
@@ -6,6 +6,7 @@ import (
 	"server/core/app/request"
 	"server/core/app/response"
 	"server/core/app/webapi"
+	"server/dagflow"
 	"server/service/sflow"
 	"server/utils/logger"
 
@@ -28,17 +29,27 @@ func (SFlowApp) AddRoutes(parentGroup *gin.RouterGroup) {
 	// 注册基础路由（CRUD操作）
 	webapi.AddBaseRoutes(group, &app)
 	// 设置允许更新的字段列表
-	app.UpdateFields = []string{"name", "type", "log_level", "uri", "project_dir_id", "remark"}
+	app.UpdateFields = []string{"name", "type", "log_level", "uri", "project_dir_id", "remark", "timeout", "workers", "concurrency", "max_queue", "log_keep_num"}
 
 	// 注册手动执行任务的路由
 	group.POST("/exec/:id", app.Exec)
 	group.POST("/test/:id", app.Test)
 	group.POST("/saveContent", app.saveContent)
 }
-func (app SFlowApp) Test(ctx *gin.Context) {
-
-	log.Println("----------Test------")
 
+// Test 运行作业流程的所有测试用例
+// 返回测试报告，包含每个用例是否通过及未通过的断言
+func (app SFlowApp) Test(ctx *gin.Context) {
+	report, err := dagflow.GetService().RunTestCases(ctx.Request.Context(), ctx.Param("id"))
+	if err != nil {
+		response.Error(ctx, err)
+		return
+	}
+	if report.Success() {
+		response.Data(ctx, "测试通过！", report)
+	} else {
+		response.Data(ctx, "测试未通过！", report)
+	}
 }
 func (app SFlowApp) saveContent(ctx *gin.Context) {
 	log.Println("----------saveContent------")
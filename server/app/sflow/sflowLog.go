@@ -4,7 +4,8 @@ package sflow
 import (
 	"server/core/app/request"
 	"server/core/app/response"
-	"server/service/scheduled/log"
+	"server/service/sflow"
+	"server/utils"
 
 	"github.com/gin-gonic/gin"
 )
@@ -18,8 +19,24 @@ type SFlowLogApp struct {
 func (SFlowLogApp) AddRoutes(parentGroup *gin.RouterGroup) {
 	group := parentGroup.Group("/log")
 	app := SFlowLogApp{}
-	group.GET("/list", app.List)     // 获取日志列表
-	group.GET("/load/:id", app.Load) // 加载单个日志详情
+	group.GET("/list", app.List)             // 获取日志列表
+	group.GET("/load/:id", app.Load)         // 加载单个日志详情
+	group.POST("/purge/:sflowId", app.Purge) // 清除流程的所有日志
+}
+
+// Purge 删除流程所有已执行结束的日志和日志文件，执行中的日志保留
+func (SFlowLogApp) Purge(ctx *gin.Context) {
+	sflowID := utils.ToUint(ctx.Param("sflowId"))
+	if sflowID == 0 {
+		response.BadRequest(ctx, "流程ID不能为空")
+		return
+	}
+	count, err := sflow.SFlowLog{}.Purge(sflowID)
+	if err != nil {
+		response.Error(ctx, err)
+		return
+	}
+	response.Data(ctx, "日志已清除", gin.H{"count": count})
 }
 
 // List 处理获取作业流程日志列表的请求
@@ -27,9 +44,21 @@ func (SFlowLogApp) AddRoutes(parentGroup *gin.RouterGroup) {
 func (SFlowLogApp) List(ctx *gin.Context) {
 	// 获取分页查询参数
 	query := request.GetPageQuery(ctx)
-	flow_id := ctx.Query("flow_id")
-	query.AddFilter(request.NewEqualFilter("flow_id", flow_id))
-	var entity log.SchLog
+	// 只添加有值的过滤条件，流程ID字段按默认命名规则对应s_flow_id列
+	// 流程ID参数为sflow_id，兼容原来的flow_id参数
+	sflowID := ctx.Query("sflow_id")
+	if sflowID == "" {
+		sflowID = ctx.Query("flow_id")
+	}
+	if sflowID != "" {
+		query.AddFilter(request.NewEqualFilter("s_flow_id", sflowID))
+	}
+	for _, column := range []string{"execution_id", "replay_of"} {
+		if value := ctx.Query(column); value != "" {
+			query.AddFilter(request.NewEqualFilter(column, value))
+		}
+	}
+	var entity sflow.SFlowLog
 	// 调用服务层获取日志列表
 	list, count, err := entity.List(query)
 	if err == nil {
@@ -44,7 +73,7 @@ func (SFlowLogApp) List(ctx *gin.Context) {
 // Load 处理加载单个作业流程日志详情的请求
 // ctx: Gin上下文
 func (SFlowLogApp) Load(ctx *gin.Context) {
-	var entity log.SchLog
+	var entity sflow.SFlowLog
 	// 根据ID加载日志详情
 	entity, err := entity.Load(ctx.Param("id"))
 	if err == nil {
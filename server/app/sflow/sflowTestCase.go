@@ -0,0 +1,44 @@
+// Package sflow 提供作业流程测试用例相关的HTTP接口
+package sflow
+
+import (
+	"server/core/app/request"
+	"server/core/app/response"
+	"server/core/app/webapi"
+	"server/service/sflow"
+
+	"github.com/gin-gonic/gin"
+)
+
+// SFlowTestCaseApp 作业流程测试用例应用结构体
+type SFlowTestCaseApp struct {
+	webapi.BaseApp[sflow.SFlowTestCase] // 内嵌基类，指定模型类型为sflow.SFlowTestCase
+}
+
+// AddRoutes 注册作业流程测试用例相关的路由
+// parentGroup: 父路由组
+func (SFlowTestCaseApp) AddRoutes(parentGroup *gin.RouterGroup) {
+	group := parentGroup.Group("/testcase")
+	app := SFlowTestCaseApp{}
+	// 注册基础路由（CRUD操作）
+	webapi.AddBaseRoutes(group, &app)
+	// 设置允许更新的字段列表
+	app.UpdateFields = []string{"name", "params", "mocks", "expect", "remark"}
+}
+
+// List 获取流程的测试用例列表
+// ctx: Gin上下文
+func (SFlowTestCaseApp) List(ctx *gin.Context) {
+	// 获取分页查询参数，按流程ID过滤
+	query := request.GetPageQuery(ctx)
+	if sflowID := ctx.Query("sflow_id"); sflowID != "" {
+		query.AddFilter(request.NewEqualFilter("sflow_id", sflowID))
+	}
+	var entity sflow.SFlowTestCase
+	list, count, err := entity.List(query)
+	if err == nil {
+		response.List(ctx, "", count, list)
+	} else {
+		response.NoContent(ctx, "无数据！")
+	}
+}
@@ -1,12 +1,16 @@
 package cmd
 
 import (
+	"context"
+	"encoding/json"
 	"fmt"
 	"log"
 	"os"
 	"path/filepath"
+	"server/dagflow"
 	"server/service/basic"
 	"server/service/database"
+	"server/service/sflow"
 	"server/utils/global"
 	"server/utils/logger"
 	"server/utils/mfa"
@@ -73,13 +77,147 @@ var genCertCmd = &cobra.Command{
 	},
 }
 
+var flowTestCmd = &cobra.Command{
+	Use:   "flowtest",
+	Short: "运行流程测试用例",
+	Long:  `运行流程测试用例，用于持续集成。有用例未通过时以状态码1退出.`,
+	PreRun: func(cmd *cobra.Command, args []string) {
+		InitConfig(cmd)
+		// 初始化日志
+		logger.Init()
+		database.Init()
+	},
+	Run: func(cmd *cobra.Command, args []string) {
+		flows, _ := cmd.Flags().GetString("flow")
+		asJSON, _ := cmd.Flags().GetBool("json")
+		if !flowTest(flows, asJSON) {
+			os.Exit(1)
+		}
+	},
+}
+
+var flowExportCmd = &cobra.Command{
+	Use:   "flowexport",
+	Short: "导出流程图",
+	Long:  `将流程导出为Graphviz DOT或Mermaid文本，可叠加一次执行的节点状态和耗时.`,
+	PreRun: func(cmd *cobra.Command, args []string) {
+		InitConfig(cmd)
+		// 初始化日志
+		logger.Init()
+		database.Init()
+	},
+	Run: func(cmd *cobra.Command, args []string) {
+		flow, _ := cmd.Flags().GetString("flow")
+		format, _ := cmd.Flags().GetString("format")
+		execution, _ := cmd.Flags().GetString("execution")
+		output, _ := cmd.Flags().GetString("output")
+		if !flowExport(flow, format, execution, output) {
+			os.Exit(1)
+		}
+	},
+}
+
 func init() {
 	// 添加命令
 	rootCmd.AddCommand(resetPwdCmd)
 	rootCmd.AddCommand(resetMfaCmd)
 	rootCmd.AddCommand(disableMfaCmd)
 	rootCmd.AddCommand(genCertCmd)
+	rootCmd.AddCommand(flowTestCmd)
+	rootCmd.AddCommand(flowExportCmd)
+
+	flowTestCmd.Flags().String("flow", "", "流程ID，多个以逗号分隔，为空时运行所有流程的测试用例")
+	flowTestCmd.Flags().Bool("json", false, "以JSON格式输出测试报告")
 
+	flowExportCmd.Flags().String("flow", "", "流程ID，指定执行ID时可为空")
+	flowExportCmd.Flags().String("format", "dot", "导出格式：dot、mermaid")
+	flowExportCmd.Flags().String("execution", "", "执行ID，叠加该次执行的节点状态和耗时")
+	flowExportCmd.Flags().StringP("output", "o", "", "输出文件，为空时输出到标准输出")
+}
+
+// flowExport 导出流程图到文件或标准输出，成功时返回true
+func flowExport(flow, format, execution, output string) bool {
+	if flow == "" && execution == "" {
+		log.Println("请指定流程ID或执行ID")
+		return false
+	}
+	text, err := dagflow.NewService().ExportFlow(flow, format, execution)
+	if err != nil {
+		log.Printf("导出流程图失败: %s\n", err.Error())
+		return false
+	}
+	if output == "" {
+		fmt.Print(text)
+		return true
+	}
+	if err := os.WriteFile(output, []byte(text), 0644); err != nil {
+		log.Printf("写入文件失败: %s\n", err.Error())
+		return false
+	}
+	log.Printf("流程图已导出到 %s\n", output)
+	return true
+}
+
+// flowTest 运行流程测试用例并输出报告，全部通过时返回true
+func flowTest(flows string, asJSON bool) bool {
+	ids := make([]string, 0)
+	for _, id := range strings.Split(flows, ",") {
+		if id = strings.TrimSpace(id); id != "" {
+			ids = append(ids, id)
+		}
+	}
+	if len(ids) == 0 {
+		flowIDs, err := sflow.SFlowTestCase{}.ListFlowIDs()
+		if err != nil {
+			log.Printf("查询测试用例失败: %s\n", err.Error())
+			return false
+		}
+		for _, id := range flowIDs {
+			ids = append(ids, fmt.Sprint(id))
+		}
+	}
+	if len(ids) == 0 {
+		log.Println("没有可运行的测试用例")
+		return true
+	}
+
+	// 使用独立的服务实例，不恢复暂停中的执行
+	service := dagflow.NewService()
+	service.LoadPlugins(dagflow.PluginDir())
+	defer service.StopPlugins()
+	success := true
+	reports := make([]*dagflow.TestReport, 0, len(ids))
+	for _, id := range ids {
+		report, err := service.RunTestCases(context.Background(), id)
+		if err != nil {
+			log.Printf("流程 %s 测试失败: %s\n", id, err.Error())
+			success = false
+			continue
+		}
+		reports = append(reports, report)
+		success = success && report.Success()
+	}
+
+	if asJSON {
+		bytes, _ := json.MarshalIndent(reports, "", "  ")
+		fmt.Println(string(bytes))
+		return success
+	}
+	for _, report := range reports {
+		fmt.Printf("流程 %s (ID: %d): %d 个用例，通过 %d，未通过 %d，耗时 %dms\n",
+			report.FlowName, report.FlowID, report.Total, report.Passed, report.Failed, report.Duration)
+		for _, result := range report.Cases {
+			if result.Passed {
+				fmt.Printf("  PASS %s (%dms)\n", result.Name, result.Duration)
+				continue
+			}
+			fmt.Printf("  FAIL %s (%dms)\n", result.Name, result.Duration)
+			for _, failure := range result.Failures {
+				fmt.Printf("       - %s\n", failure)
+			}
+		}
+	}
+	return success
 }
 
 func genCert() {
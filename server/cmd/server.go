@@ -18,6 +18,7 @@ import (
 	"server/middleware"        // 中间件
 	"server/route"             // 路由定义
 	"server/service/database"  // 数据库服务
+	"server/service/notify"    // 通知中心
 	"server/service/scheduled" // 定时任务服务
 	"server/utils/config"      // 配置工具
 	"server/utils/logger"      // 日志工具
@@ -163,6 +164,8 @@ func StartServer() {
 
 	// 初始化数据库
 	database.Init()
+	// 启动通知重试任务
+	notify.Start()
 	// 启动定时任务
 	scheduled.Start()
 	if err != nil {
@@ -3,8 +3,11 @@ package api
 
 import (
 	"errors"
+	"net/http"
 	"server/core/app/response"
 	"server/dagflow"
+	"server/dagflow/model"
+	"server/utils/simple"
 
 	"github.com/gin-gonic/gin"
 )
@@ -19,7 +22,28 @@ func (api *DAGFlowAPI) AddRoutes(group *gin.RouterGroup) {
 	group.POST("/execute/:id", api.ExecuteFlow)
 	group.POST("/validate/:id", api.ValidateFlow)
 	group.GET("/handlers", api.GetHandlers)
+	group.GET("/params/:id", api.GetFlowParams)
+	group.GET("/export/:id", api.ExportFlow)
+	group.GET("/handlers/leaked", api.GetLeakedHandlers)
+	group.GET("/plugins", api.GetPlugins)
 	group.POST("/debug/:id", api.DebugFlow)
+	group.POST("/start/:id", api.StartFlow)
+	group.POST("/replay/:executionId", api.ReplayExecution)
+	group.GET("/runs", api.ListFlowRuns)
+	group.GET("/runs/:id", api.GetFlowRuns)
+	api.addDebuggerRoutes(group.Group("/debugger"))
+	api.addWaitRoutes(group)
+	api.addArtifactRoutes(group)
+}
+
+// flowError 返回流程执行错误，参数校验失败时同时返回各字段的错误信息
+func flowError(ctx *gin.Context, err error) {
+	var paramErrs model.ParamErrors
+	if errors.As(err, &paramErrs) {
+		response.Error(ctx, simple.NewSimpleError(http.StatusBadRequest, err.Error(), paramErrs))
+		return
+	}
+	response.Error(ctx, err)
 }
 
 // ExecuteRequest 执行请求参数
@@ -58,7 +82,7 @@ func (api *DAGFlowAPI) ExecuteFlow(ctx *gin.Context) {
 	// 执行流程
 	execCtx, err := service.ExecuteFlow(ctx, id, req.Data, false)
 	if err != nil {
-		response.Error(ctx, err)
+		flowError(ctx, err)
 		return
 	}
 
@@ -112,6 +136,67 @@ func (api *DAGFlowAPI) GetHandlers(ctx *gin.Context) {
 	response.Data(ctx, "获取处理器类型成功", handlers)
 }
 
+// GetPlugins 获取已加载的插件及其提供的节点类型
+func (api *DAGFlowAPI) GetPlugins(ctx *gin.Context) {
+	service := dagflow.GetService()
+	if service == nil {
+		response.Error(ctx, errors.New("DAGFlow服务未初始化"))
+		return
+	}
+	response.Data(ctx, "", service.GetPlugins())
+}
+
+// GetFlowParams 获取流程的输入参数定义，用于生成输入表单
+func (api *DAGFlowAPI) GetFlowParams(ctx *gin.Context) {
+	id := ctx.Param("id")
+	if id == "" {
+		response.BadRequest(ctx, "流程ID不能为空")
+		return
+	}
+	service := dagflow.GetService()
+	if service == nil {
+		response.Error(ctx, errors.New("DAGFlow服务未初始化"))
+		return
+	}
+	params, err := service.GetFlowParams(id)
+	if err != nil {
+		response.Error(ctx, err)
+		return
+	}
+	response.Data(ctx, "", params)
+}
+
+// ExportFlow 将流程导出为Graphviz DOT或Mermaid文本
+// 查询参数：format 导出格式dot或mermaid，默认dot；executionId 叠加该次执行的节点状态和耗时
+func (api *DAGFlowAPI) ExportFlow(ctx *gin.Context) {
+	id := ctx.Param("id")
+	if id == "" {
+		response.BadRequest(ctx, "流程ID不能为空")
+		return
+	}
+	service := dagflow.GetService()
+	if service == nil {
+		response.Error(ctx, errors.New("DAGFlow服务未初始化"))
+		return
+	}
+	text, err := service.ExportFlow(id, ctx.Query("format"), ctx.Query("executionId"))
+	if err != nil {
+		flowError(ctx, err)
+		return
+	}
+	ctx.String(http.StatusOK, text)
+}
+
+// GetLeakedHandlers 获取超时后仍未退出的节点处理器
+func (api *DAGFlowAPI) GetLeakedHandlers(ctx *gin.Context) {
+	service := dagflow.GetService()
+	if service == nil {
+		response.Error(ctx, errors.New("DAGFlow服务未初始化"))
+		return
+	}
+	response.Data(ctx, "", service.GetLeakedHandlers())
+}
+
 // DebugFlow 调试流程
 func (api *DAGFlowAPI) DebugFlow(ctx *gin.Context) {
 	// 获取流程ID
@@ -149,3 +234,93 @@ func (api *DAGFlowAPI) DebugFlow(ctx *gin.Context) {
 	// 返回执行结果和调试信息
 	response.Data(ctx, "流程调试完成", execCtx)
 }
+
+// StartFlow 异步执行流程
+// 立即返回执行ID，配合事件流接口实时查看执行进度
+func (api *DAGFlowAPI) StartFlow(ctx *gin.Context) {
+	// 获取流程ID
+	id := ctx.Param("id")
+	if id == "" {
+		response.BadRequest(ctx, "流程ID不能为空")
+		return
+	}
+
+	// 解析请求体
+	var req ExecuteRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(ctx, "请求参数错误")
+		return
+	}
+
+	// 确保初始数据不为空
+	if req.Data == nil {
+		req.Data = make(map[string]any)
+	}
+
+	// 获取服务实例
+	service := dagflow.GetService()
+	if service == nil {
+		response.Error(ctx, errors.New("DAGFlow服务未初始化"))
+		return
+	}
+
+	executionID, err := service.StartFlow(id, req.Data, ctx.Query("debug") == "true")
+	if err != nil {
+		flowError(ctx, err)
+		return
+	}
+	response.Data(ctx, "流程已开始执行", gin.H{"executionId": executionID})
+}
+
+// ReplayExecution 按原执行的输入参数和流程版本回放执行
+// 请求体可选，可覆盖部分输入参数、选择使用流程当前版本或演练模式
+func (api *DAGFlowAPI) ReplayExecution(ctx *gin.Context) {
+	var req dagflow.ReplayOptions
+	if ctx.Request.ContentLength > 0 {
+		if err := ctx.ShouldBindJSON(&req); err != nil {
+			response.BadRequest(ctx, "请求参数错误")
+			return
+		}
+	}
+	service := dagflow.GetService()
+	if service == nil {
+		response.Error(ctx, errors.New("DAGFlow服务未初始化"))
+		return
+	}
+	executionID, err := service.Replay(ctx.Param("executionId"), req)
+	if err != nil {
+		flowError(ctx, err)
+		return
+	}
+	response.Data(ctx, "回放已开始执行", gin.H{"executionId": executionID, "replayOf": ctx.Param("executionId")})
+}
+
+// ListFlowRuns 获取所有正在执行或排队的流程
+func (api *DAGFlowAPI) ListFlowRuns(ctx *gin.Context) {
+	service := dagflow.GetService()
+	if service == nil {
+		response.Error(ctx, errors.New("DAGFlow服务未初始化"))
+		return
+	}
+	response.Data(ctx, "", service.ListFlowRuns())
+}
+
+// GetFlowRuns 获取流程的并发策略、队列上限和当前执行情况
+func (api *DAGFlowAPI) GetFlowRuns(ctx *gin.Context) {
+	id := ctx.Param("id")
+	if id == "" {
+		response.BadRequest(ctx, "流程ID不能为空")
+		return
+	}
+	service := dagflow.GetService()
+	if service == nil {
+		response.Error(ctx, errors.New("DAGFlow服务未初始化"))
+		return
+	}
+	runs, err := service.GetFlowRuns(id)
+	if err != nil {
+		response.Error(ctx, err)
+		return
+	}
+	response.Data(ctx, "", runs)
+}
@@ -0,0 +1,90 @@
+package api
+
+import (
+	"archive/zip"
+	"io"
+	"mime"
+	"net/http"
+	"server/core/app/response"
+	"server/dagflow/artifact"
+	"server/utils/logger"
+
+	"github.com/gin-gonic/gin"
+)
+
+// addArtifactRoutes 注册制品相关的路由
+func (api *DAGFlowAPI) addArtifactRoutes(group *gin.RouterGroup) {
+	group.GET("/artifacts/:executionId", api.ListArtifacts)
+	group.GET("/artifacts/:executionId/download", api.DownloadArtifacts)
+	group.GET("/artifact/:id/download", api.DownloadArtifact)
+}
+
+// ListArtifacts 查询一次执行的所有制品
+func (api *DAGFlowAPI) ListArtifacts(ctx *gin.Context) {
+	list, err := artifact.ListByExecution(ctx.Param("executionId"))
+	if err != nil {
+		response.Error(ctx, err)
+		return
+	}
+	response.Data(ctx, "", list)
+}
+
+// DownloadArtifact 下载单个制品
+func (api *DAGFlowAPI) DownloadArtifact(ctx *gin.Context) {
+	record, reader, err := artifact.Open(ctx.Param("id"))
+	if err != nil {
+		response.Error(ctx, err)
+		return
+	}
+	defer reader.Close()
+	ctx.DataFromReader(http.StatusOK, record.Size, record.MimeType, reader, map[string]string{
+		"Content-Disposition": attachment(record.Name),
+		"Digest":              record.Checksum,
+	})
+}
+
+// DownloadArtifacts 将一次执行的所有制品打包为zip下载，包内按节点ID分目录
+func (api *DAGFlowAPI) DownloadArtifacts(ctx *gin.Context) {
+	executionID := ctx.Param("executionId")
+	list, err := artifact.ListByExecution(executionID)
+	if err != nil {
+		response.Error(ctx, err)
+		return
+	}
+	if len(list) == 0 {
+		response.NotFound(ctx, "执行没有制品")
+		return
+	}
+
+	ctx.Header("Content-Type", "application/zip")
+	ctx.Header("Content-Disposition", attachment(executionID+".zip"))
+	writer := zip.NewWriter(ctx.Writer)
+	defer writer.Close()
+	for _, record := range list {
+		if err := writeZipEntry(writer, record.ID, record.NodeID+"/"+record.Name); err != nil {
+			// 响应已开始输出，只能中断打包并记录日志
+			logger.LOG.Errorf("打包制品 %s 失败: %v", record.URI, err)
+			return
+		}
+	}
+}
+
+// writeZipEntry 将制品写入zip包
+func writeZipEntry(writer *zip.Writer, id uint, name string) error {
+	_, reader, err := artifact.Open(id)
+	if err != nil {
+		return err
+	}
+	defer reader.Close()
+	entry, err := writer.Create(name)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(entry, reader)
+	return err
+}
+
+// attachment 生成附件下载的Content-Disposition，支持中文文件名
+func attachment(name string) string {
+	return mime.FormatMediaType("attachment", map[string]string{"filename": name})
+}
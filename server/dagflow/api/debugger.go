@@ -0,0 +1,164 @@
+package api
+
+import (
+	"errors"
+	"server/core/app/response"
+	"server/dagflow"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// addDebuggerRoutes 注册交互式调试相关的路由
+func (api *DAGFlowAPI) addDebuggerRoutes(group *gin.RouterGroup) {
+	group.POST("/start/:id", api.StartDebugSession)
+	group.GET("/session/:sid", api.GetDebugSession)
+	group.POST("/session/:sid/step", api.debugCommand(dagflow.DebugStep))
+	group.POST("/session/:sid/continue", api.debugCommand(dagflow.DebugContinue))
+	group.POST("/session/:sid/abort", api.debugCommand(dagflow.DebugAbort))
+	group.POST("/session/:sid/breakpoints", api.SetDebugBreakpoints)
+	group.POST("/session/:sid/data", api.SetDebugData)
+	group.POST("/session/:sid/eval", api.EvaluateDebugExpression)
+}
+
+// DebugStartRequest 启动调试会话请求参数
+type DebugStartRequest struct {
+	Data        map[string]any `json:"data"`        // 初始数据
+	Breakpoints []string       `json:"breakpoints"` // 断点节点ID
+	Timeout     int            `json:"timeout"`     // 暂停超时时间（秒），为0时使用默认值
+}
+
+// DebugBreakpointsRequest 设置断点请求参数
+type DebugBreakpointsRequest struct {
+	Breakpoints []string `json:"breakpoints"` // 断点节点ID
+}
+
+// DebugDataRequest 修改数据请求参数
+type DebugDataRequest struct {
+	Key   string `json:"key"`   // 数据键
+	Value any    `json:"value"` // 数据值，为null时删除
+}
+
+// DebugEvalRequest 计算表达式请求参数
+type DebugEvalRequest struct {
+	Expr string `json:"expr"` // EL表达式
+}
+
+// StartDebugSession 启动交互式调试会话
+func (api *DAGFlowAPI) StartDebugSession(ctx *gin.Context) {
+	id := ctx.Param("id")
+	if id == "" {
+		response.BadRequest(ctx, "流程ID不能为空")
+		return
+	}
+	var req DebugStartRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(ctx, "请求参数错误")
+		return
+	}
+	if req.Data == nil {
+		req.Data = make(map[string]any)
+	}
+	service := dagflow.GetService()
+	if service == nil {
+		response.Error(ctx, errors.New("DAGFlow服务未初始化"))
+		return
+	}
+	session, err := service.StartDebugSession(id, req.Data, req.Breakpoints, time.Duration(req.Timeout)*time.Second)
+	if err != nil {
+		flowError(ctx, err)
+		return
+	}
+	response.Data(ctx, "调试会话已启动", session.Snapshot())
+}
+
+// GetDebugSession 获取调试会话状态和执行上下文
+func (api *DAGFlowAPI) GetDebugSession(ctx *gin.Context) {
+	session, ok := api.loadDebugSession(ctx)
+	if !ok {
+		return
+	}
+	response.Data(ctx, "", session.Snapshot())
+}
+
+// debugCommand 生成发送调试命令的处理函数
+func (api *DAGFlowAPI) debugCommand(cmd dagflow.DebugCommand) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		session, ok := api.loadDebugSession(ctx)
+		if !ok {
+			return
+		}
+		if err := session.Command(cmd); err != nil {
+			response.Error(ctx, err)
+			return
+		}
+		response.Success(ctx, "调试命令已发送")
+	}
+}
+
+// SetDebugBreakpoints 设置调试会话的断点
+func (api *DAGFlowAPI) SetDebugBreakpoints(ctx *gin.Context) {
+	session, ok := api.loadDebugSession(ctx)
+	if !ok {
+		return
+	}
+	var req DebugBreakpointsRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(ctx, "请求参数错误")
+		return
+	}
+	session.SetBreakpoints(req.Breakpoints)
+	response.Success(ctx, "断点设置成功")
+}
+
+// SetDebugData 修改暂停中调试会话的执行数据
+func (api *DAGFlowAPI) SetDebugData(ctx *gin.Context) {
+	session, ok := api.loadDebugSession(ctx)
+	if !ok {
+		return
+	}
+	var req DebugDataRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(ctx, "请求参数错误")
+		return
+	}
+	if err := session.SetData(req.Key, req.Value); err != nil {
+		response.Error(ctx, err)
+		return
+	}
+	response.Success(ctx, "数据修改成功")
+}
+
+// EvaluateDebugExpression 在暂停中调试会话的数据上计算表达式
+func (api *DAGFlowAPI) EvaluateDebugExpression(ctx *gin.Context) {
+	session, ok := api.loadDebugSession(ctx)
+	if !ok {
+		return
+	}
+	var req DebugEvalRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(ctx, "请求参数错误")
+		return
+	}
+	result, err := session.Evaluate(req.Expr)
+	if err != nil {
+		response.Error(ctx, err)
+		return
+	}
+	response.Data(ctx, "", result)
+}
+
+// loadDebugSession 根据路径参数获取调试会话，失败时直接写入响应
+func (api *DAGFlowAPI) loadDebugSession(ctx *gin.Context) (*dagflow.DebugSession, bool) {
+	service := dagflow.GetService()
+	if service == nil {
+		response.Error(ctx, errors.New("DAGFlow服务未初始化"))
+		return nil, false
+	}
+	session, err := service.GetDebugSession(ctx.Param("sid"))
+	if err != nil {
+		response.NotFound(ctx, err.Error())
+		return nil, false
+	}
+	return session, true
+}
@@ -0,0 +1,118 @@
+package api
+
+import (
+	"errors"
+	"io"
+	"server/app/term"
+	"server/core/app/request"
+	"server/core/app/response"
+	"server/dagflow"
+	"server/dagflow/model"
+	"server/middleware"
+	"server/utils"
+	"server/utils/cache"
+	"server/utils/data"
+	"server/utils/logger"
+	"strings"
+	"time"
+
+	"github.com/duke-git/lancet/v2/convertor"
+	"github.com/duke-git/lancet/v2/random"
+	"github.com/gin-gonic/gin"
+)
+
+// eventClientPrefix 事件流客户端ID前缀
+const eventClientPrefix = "dagflow_"
+
+// eventHeartbeatInterval 事件流心跳间隔
+const eventHeartbeatInterval = 15 * time.Second
+
+// AddEventRoutes 注册流程执行事件流相关的路由
+// 与终端一致：先通过认证接口获取一次性令牌，再使用令牌建立SSE连接
+func (api *DAGFlowAPI) AddEventRoutes(parentGroup *gin.RouterGroup) {
+	group1 := parentGroup.Group("/auth", middleware.AuthMiddleware) // 需要认证的路由组
+	group2 := parentGroup.Group("/sse")                             // SSE路由组
+	group1.GET("/token/:flowId", api.GetEventToken)                 // 获取事件流token
+	group2.GET("/:clientId/:token", api.StreamEvents)               // 打开事件流
+}
+
+// GetEventToken 获取事件流连接的一次性令牌
+func (api *DAGFlowAPI) GetEventToken(ctx *gin.Context) {
+	flowId := ctx.Param("flowId")
+	if utils.ToUint(flowId) == 0 {
+		response.BadRequest(ctx, "流程ID不能为空")
+		return
+	}
+	service := dagflow.GetService()
+	if service == nil {
+		response.Error(ctx, errors.New("DAGFlow服务未初始化"))
+		return
+	}
+	// 只为可以访问该流程的用户签发令牌
+	if err := service.CheckFlowAccess(flowId, request.GetUserID(ctx)); err != nil {
+		response.Forbidden(ctx, err.Error())
+		return
+	}
+	userId := convertor.ToString(request.GetUserID(ctx))
+	token, _ := random.UUIdV4()
+	clientId := eventClientPrefix + flowId + "_" + userId
+	// 设置令牌缓存，有效期30秒
+	cache.GetCacheSystem().SetExpire(clientId, token, 30)
+	response.Data(ctx, "", data.Map{"clientId": clientId, "token": token})
+}
+
+// StreamEvents 以SSE方式推送流程执行事件
+// 可通过executionId参数只接收指定执行的事件，先收到该执行已发生的事件，该执行结束后自动关闭连接
+func (api *DAGFlowAPI) StreamEvents(ctx *gin.Context) {
+	clientId := ctx.Param("clientId")
+	token := ctx.Param("token")
+	if !strings.HasPrefix(clientId, eventClientPrefix) || !term.CheckToken(clientId, token) {
+		response.Unauthorized(ctx, "事件流令牌无效")
+		return
+	}
+	parts := strings.Split(strings.TrimPrefix(clientId, eventClientPrefix), "_")
+	if len(parts) < 2 {
+		response.Unauthorized(ctx, "事件流令牌无效")
+		return
+	}
+	flowID := utils.ToUint(parts[0])
+
+	service := dagflow.GetService()
+	if service == nil {
+		response.Error(ctx, errors.New("DAGFlow服务未初始化"))
+		return
+	}
+	// 签发令牌后用户或流程可能已被删除，连接时重新检查
+	if err := service.CheckFlowAccess(parts[0], utils.ToUint(parts[1])); err != nil {
+		response.Forbidden(ctx, err.Error())
+		return
+	}
+
+	executionID := ctx.Query("executionId")
+	events, cancel := service.SubscribeEvents(flowID, executionID)
+	defer cancel()
+	logger.LOG.Infof("打开流程事件流 clientId:%s, executionId:%s", clientId, executionID)
+
+	ctx.Writer.Header().Set("Cache-Control", "no-cache")
+	ctx.Writer.Header().Set("Connection", "keep-alive")
+	ctx.Writer.Header().Set("X-Accel-Buffering", "no")
+
+	heartbeat := time.NewTicker(eventHeartbeatInterval)
+	defer heartbeat.Stop()
+	ctx.Stream(func(w io.Writer) bool {
+		select {
+		case event, ok := <-events:
+			if !ok {
+				return false
+			}
+			ctx.SSEvent(string(event.Type), event)
+			// 指定执行结束后关闭事件流
+			return executionID == "" || event.Type != model.EventFlowFinished
+		case <-heartbeat.C:
+			ctx.SSEvent("heartbeat", time.Now().UnixMilli())
+			return true
+		case <-ctx.Request.Context().Done():
+			return false
+		}
+	})
+}
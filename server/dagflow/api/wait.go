@@ -0,0 +1,80 @@
+package api
+
+import (
+	"server/core/app/request"
+	"server/core/app/response"
+	"server/dagflow"
+
+	"github.com/gin-gonic/gin"
+)
+
+// addWaitRoutes 注册审批和信号相关的路由
+func (api *DAGFlowAPI) addWaitRoutes(group *gin.RouterGroup) {
+	group.GET("/waits", api.ListWaits)
+	group.POST("/approval/:id/approve", api.decideApproval(true))
+	group.POST("/approval/:id/reject", api.decideApproval(false))
+	group.POST("/signal/:executionId/:name", api.SendSignal)
+}
+
+// ApprovalRequest 审批请求参数
+type ApprovalRequest struct {
+	Comment string `json:"comment"` // 审批意见
+}
+
+// SignalRequest 发送信号请求参数
+type SignalRequest struct {
+	Payload any `json:"payload"` // 信号携带的数据
+}
+
+// ListWaits 分页查询等待记录，可按状态、类型和流程过滤
+func (api *DAGFlowAPI) ListWaits(ctx *gin.Context) {
+	query := request.GetPageQuery(ctx)
+	// 只添加有值的过滤条件，空值会匹配不到任何记录
+	for _, column := range []string{"status", "kind", "sflow_id", "execution_id"} {
+		if value := ctx.Query(column); value != "" {
+			query.AddFilter(request.NewEqualFilter(column, value))
+		}
+	}
+	list, count, err := dagflow.GetService().ListWaits(query)
+	if err != nil {
+		response.Error(ctx, err)
+		return
+	}
+	response.List(ctx, "", count, list)
+}
+
+// decideApproval 创建批准或拒绝审批的处理函数
+func (api *DAGFlowAPI) decideApproval(approved bool) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		var req ApprovalRequest
+		if ctx.Request.ContentLength > 0 {
+			if err := ctx.ShouldBindJSON(&req); err != nil {
+				response.BadRequest(ctx, "请求参数错误")
+				return
+			}
+		}
+		err := dagflow.GetService().DecideApproval(ctx.Param("id"), request.GetUserID(ctx), approved, req.Comment)
+		if err != nil {
+			response.Error(ctx, err)
+			return
+		}
+		response.Success(ctx, "审批成功")
+	}
+}
+
+// SendSignal 向等待中的执行发送信号
+func (api *DAGFlowAPI) SendSignal(ctx *gin.Context) {
+	var req SignalRequest
+	if ctx.Request.ContentLength > 0 {
+		if err := ctx.ShouldBindJSON(&req); err != nil {
+			response.BadRequest(ctx, "请求参数错误")
+			return
+		}
+	}
+	count, err := dagflow.GetService().Signal(ctx.Param("executionId"), ctx.Param("name"), req.Payload)
+	if err != nil {
+		response.Error(ctx, err)
+		return
+	}
+	response.Data(ctx, "", map[string]any{"resumed": count})
+}
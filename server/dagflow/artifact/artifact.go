@@ -0,0 +1,261 @@
+// Package artifact 实现DAGFlow的制品存储
+// 节点产生的文件和超过大小阈值的结果保存到工作目录或外部存储中，流程数据中只保存制品引用，
+// 避免大数据常驻内存、写入日志或随调试结果返回。制品按保留天数定期清理
+package artifact
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"server/dagflow/model"
+	"server/service/sflow"
+	"server/utils/config"
+	"server/utils/global"
+	"server/utils/logger"
+	"time"
+)
+
+const (
+	// defaultThreshold 默认的结果转存阈值(KB)
+	defaultThreshold = 256
+	// defaultRetention 默认的制品保留天数
+	defaultRetention = 7
+	// cleanupInterval 清理过期制品的间隔
+	cleanupInterval = time.Hour
+	// cleanupBatch 每批清理的制品数量
+	cleanupBatch = 100
+	// sniffLen 检测MIME类型读取的字节数
+	sniffLen = 512
+)
+
+// Save 保存节点产生的制品，返回制品引用
+// 名称在同一节点内唯一，重复保存时覆盖原制品；MIME类型按扩展名判断，无法判断时按内容检测
+func Save(execCtx *model.ExecutionContext, nodeID, name string, reader io.Reader) (model.ArtifactRef, error) {
+	return save(execCtx, nodeID, name, "", reader)
+}
+
+// SaveFile 将本地文件保存为节点的制品，制品名称为文件名
+func SaveFile(execCtx *model.ExecutionContext, nodeID, file string) (model.ArtifactRef, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return model.ArtifactRef{}, err
+	}
+	defer f.Close()
+	return save(execCtx, nodeID, filepath.Base(file), "", f)
+}
+
+// save 写入临时文件并计算大小和校验和，然后保存到制品存储并创建制品记录
+func save(execCtx *model.ExecutionContext, nodeID, name, mimeType string, reader io.Reader) (model.ArtifactRef, error) {
+	name = filepath.Base(filepath.Clean(name))
+	if name == "" || name == "." || name == ".." || name == string(filepath.Separator) {
+		return model.ArtifactRef{}, errors.New("制品名称无效")
+	}
+	dir, err := tempDir()
+	if err != nil {
+		return model.ArtifactRef{}, err
+	}
+	temp, err := os.CreateTemp(dir, "upload-*")
+	if err != nil {
+		return model.ArtifactRef{}, err
+	}
+	defer os.Remove(temp.Name())
+
+	hash := sha256.New()
+	head := &headWriter{limit: sniffLen}
+	size, err := io.Copy(io.MultiWriter(temp, hash, head), reader)
+	if closeErr := temp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return model.ArtifactRef{}, fmt.Errorf("写入制品失败: %v", err)
+	}
+	if mimeType == "" {
+		mimeType = mime.TypeByExtension(filepath.Ext(name))
+	}
+	if mimeType == "" {
+		mimeType = http.DetectContentType(head.bytes)
+	}
+
+	key := path.Join(execCtx.ExecutionID, nodeID, name)
+	ref := model.ArtifactRef{
+		Kind:     model.ArtifactKind,
+		URI:      model.ArtifactScheme + key,
+		Name:     name,
+		Size:     size,
+		MimeType: mimeType,
+		Checksum: "sha256:" + hex.EncodeToString(hash.Sum(nil)),
+	}
+	store := currentStore()
+	if err := store.Put(key, temp.Name()); err != nil {
+		return model.ArtifactRef{}, fmt.Errorf("保存制品失败: %v", err)
+	}
+	if global.DB == nil {
+		return ref, nil
+	}
+	record := sflow.SFlowArtifact{
+		SFlowId:     execCtx.FlowID,
+		ExecutionID: execCtx.ExecutionID,
+		NodeID:      nodeID,
+		Name:        name,
+		URI:         ref.URI,
+		Store:       store.Name(),
+		Key:         key,
+		Size:        ref.Size,
+		MimeType:    ref.MimeType,
+		Checksum:    ref.Checksum,
+	}
+	if err := record.Create(); err != nil {
+		store.Delete(key)
+		return model.ArtifactRef{}, fmt.Errorf("保存制品记录失败: %v", err)
+	}
+	return ref, nil
+}
+
+// Spill 节点结果超过大小阈值时转存为制品，返回制品引用，否则返回原结果
+// 字节数组和字符串按原内容保存，其他结果保存为JSON；转存失败时保留原结果
+func Spill(node model.TaskNode, execCtx *model.ExecutionContext, result any) any {
+	limit := threshold()
+	if limit < 0 || result == nil || model.IsArtifactRef(result) {
+		return result
+	}
+	var content []byte
+	var name, mimeType string
+	switch v := result.(type) {
+	case []byte:
+		content, name = v, "result.bin"
+		mimeType = http.DetectContentType(v)
+	case string:
+		if len(v) <= limit {
+			return result
+		}
+		content, name, mimeType = []byte(v), "result.txt", "text/plain; charset=utf-8"
+	default:
+		raw, err := json.Marshal(result)
+		if err != nil {
+			return result
+		}
+		content, name, mimeType = raw, "result.json", "application/json"
+	}
+	if len(content) <= limit {
+		return result
+	}
+	ref, err := save(execCtx, node.ID, name, mimeType, bytes.NewReader(content))
+	if err != nil {
+		execCtx.Log("warn", "节点 %s 的结果转存为制品失败，保留原结果: %v", node.Name, err)
+		return result
+	}
+	execCtx.Log("info", "节点 %s 的结果(%d 字节)已转存为制品: %s", node.Name, ref.Size, ref.URI)
+	return ref
+}
+
+// Open 按记录ID读取制品内容
+func Open(id any) (sflow.SFlowArtifact, io.ReadCloser, error) {
+	record, err := sflow.SFlowArtifact{}.Load(id)
+	if err != nil {
+		return record, nil, err
+	}
+	reader, err := storeOf(record.Store).Open(record.Key)
+	return record, reader, err
+}
+
+// OpenURI 按制品URI读取制品内容，用于后续节点读取前序节点的制品
+func OpenURI(uri string) (sflow.SFlowArtifact, io.ReadCloser, error) {
+	record, err := sflow.SFlowArtifact{}.FindByURI(uri)
+	if err != nil {
+		return record, nil, err
+	}
+	reader, err := storeOf(record.Store).Open(record.Key)
+	return record, reader, err
+}
+
+// ListByExecution 查询一次执行的所有制品
+func ListByExecution(executionID string) ([]sflow.SFlowArtifact, error) {
+	return sflow.SFlowArtifact{}.ListByExecution(executionID)
+}
+
+// Cleanup 删除超过保留天数的制品，返回删除的数量
+func Cleanup() (int, error) {
+	before := time.Now().AddDate(0, 0, -retention())
+	deleted := 0
+	for {
+		list, err := sflow.SFlowArtifact{}.ListExpired(before, cleanupBatch)
+		if err != nil {
+			return deleted, err
+		}
+		count := 0
+		for _, record := range list {
+			if err := storeOf(record.Store).Delete(record.Key); err != nil {
+				logger.LOG.Warnf("删除制品 %s 失败: %v", record.URI, err)
+				continue
+			}
+			if err := (sflow.SFlowArtifact{}).Delete(record.ID); err != nil {
+				return deleted, err
+			}
+			count++
+		}
+		deleted += count
+		// 本批全部删除失败时等待下次清理，避免重复处理同一批记录
+		if len(list) < cleanupBatch || count == 0 {
+			return deleted, nil
+		}
+	}
+}
+
+// StartCleanup 启动定期清理过期制品
+func StartCleanup() {
+	go func() {
+		ticker := time.NewTicker(cleanupInterval)
+		defer ticker.Stop()
+		for {
+			if deleted, err := Cleanup(); err != nil {
+				logger.LOG.Errorf("清理过期制品失败: %v", err)
+			} else if deleted > 0 {
+				logger.LOG.Infof("已清理 %d 个过期制品", deleted)
+			}
+			<-ticker.C
+		}
+	}()
+}
+
+// threshold 结果转存阈值(字节)，小于0表示不转存
+func threshold() int {
+	switch kb := config.CONF.DagFlow.ArtifactThreshold; {
+	case kb < 0:
+		return -1
+	case kb == 0:
+		return defaultThreshold * 1024
+	default:
+		return kb * 1024
+	}
+}
+
+// retention 制品保留天数
+func retention() int {
+	if days := config.CONF.DagFlow.ArtifactRetention; days > 0 {
+		return days
+	}
+	return defaultRetention
+}
+
+// headWriter 记录写入内容的前若干字节，用于检测MIME类型
+type headWriter struct {
+	limit int
+	bytes []byte
+}
+
+// Write 实现io.Writer接口
+func (w *headWriter) Write(p []byte) (int, error) {
+	if remain := w.limit - len(w.bytes); remain > 0 {
+		w.bytes = append(w.bytes, p[:min(remain, len(p))]...)
+	}
+	return len(p), nil
+}
@@ -0,0 +1,156 @@
+package artifact
+
+import (
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"server/data"
+	"server/utils/config"
+	"server/utils/rclone"
+	"strings"
+)
+
+// Store 制品存储
+type Store interface {
+	// Name 存储位置，为空表示工作目录
+	Name() string
+	// Put 将本地临时文件保存到存储的指定路径，成功后临时文件不再可用
+	Put(key, file string) error
+	// Open 读取制品内容
+	Open(key string) (io.ReadCloser, error)
+	// Delete 删除制品，制品不存在时不返回错误
+	Delete(key string) error
+}
+
+// currentStore 获取配置的制品存储，新制品保存到该存储
+func currentStore() Store {
+	return storeOf(config.CONF.DagFlow.ArtifactStore)
+}
+
+// storeOf 获取指定位置的制品存储，读取和删除制品时使用记录中保存的位置
+func storeOf(name string) Store {
+	if name == "" {
+		return localStore{root: filepath.Join(data.WorkDir, "artifacts")}
+	}
+	return remoteStore{name: name, root: config.CONF.DagFlow.ArtifactPath}
+}
+
+// tempDir 制品临时文件目录
+func tempDir() (string, error) {
+	dir, err := filepath.Abs(filepath.Join(data.WorkDir, "artifacts", ".tmp"))
+	if err != nil {
+		return "", err
+	}
+	return dir, os.MkdirAll(dir, os.ModePerm)
+}
+
+// localStore 保存在工作目录中的制品存储
+type localStore struct {
+	root string
+}
+
+// Name 存储位置
+func (s localStore) Name() string {
+	return ""
+}
+
+// Put 将临时文件移动到存储目录
+func (s localStore) Put(key, file string) error {
+	target := s.path(key)
+	if err := os.MkdirAll(filepath.Dir(target), os.ModePerm); err != nil {
+		return err
+	}
+	return os.Rename(file, target)
+}
+
+// Open 打开制品文件
+func (s localStore) Open(key string) (io.ReadCloser, error) {
+	return os.Open(s.path(key))
+}
+
+// Delete 删除制品文件，并删除因此变空的节点和执行目录
+func (s localStore) Delete(key string) error {
+	file := s.path(key)
+	if err := os.Remove(file); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	for dir := filepath.Dir(file); dir != s.root && strings.HasPrefix(dir, s.root); dir = filepath.Dir(dir) {
+		if os.Remove(dir) != nil {
+			break
+		}
+	}
+	return nil
+}
+
+// path 制品文件路径
+func (s localStore) path(key string) string {
+	return filepath.Join(s.root, filepath.FromSlash(key))
+}
+
+// remoteStore 保存在外部存储中的制品存储，通过rclone读写
+type remoteStore struct {
+	name string
+	root string
+}
+
+// Name 存储位置
+func (s remoteStore) Name() string {
+	return s.name
+}
+
+// Put 将临时文件复制到外部存储后删除临时文件
+func (s remoteStore) Put(key, file string) error {
+	defer os.Remove(file)
+	return rclone.CopyFile(filepath.Dir(file), filepath.Base(file), s.name+":", s.path(key))
+}
+
+// Open 将制品复制到临时文件后打开，关闭时删除临时文件
+func (s remoteStore) Open(key string) (io.ReadCloser, error) {
+	dir, err := tempDir()
+	if err != nil {
+		return nil, err
+	}
+	temp, err := os.CreateTemp(dir, "download-*")
+	if err != nil {
+		return nil, err
+	}
+	temp.Close()
+	name := temp.Name()
+	if err := rclone.CopyFile(s.name+":", s.path(key), dir, filepath.Base(name)); err != nil {
+		os.Remove(name)
+		return nil, err
+	}
+	file, err := os.Open(name)
+	if err != nil {
+		os.Remove(name)
+		return nil, err
+	}
+	return &tempFile{File: file}, nil
+}
+
+// Delete 删除外部存储中的制品
+func (s remoteStore) Delete(key string) error {
+	err := rclone.DeleteFile(s.name+":", s.path(key))
+	if err != nil && strings.Contains(err.Error(), "not found") {
+		return nil
+	}
+	return err
+}
+
+// path 制品在外部存储中的路径
+func (s remoteStore) path(key string) string {
+	return path.Join(s.root, key)
+}
+
+// tempFile 关闭时删除的临时文件
+type tempFile struct {
+	*os.File
+}
+
+// Close 关闭并删除临时文件
+func (f *tempFile) Close() error {
+	err := f.File.Close()
+	os.Remove(f.Name())
+	return err
+}
@@ -0,0 +1,239 @@
+package dagflow
+
+import (
+	"context"
+	"errors"
+	"server/dagflow/model"
+	"sync"
+)
+
+// 流程并发策略
+const (
+	PolicyParallel = "parallel" // 允许同时执行多次（默认）
+	PolicySkip     = "skip"     // 已在执行时跳过本次执行
+	PolicyQueue    = "queue"    // 排队等待之前的执行完成
+	PolicyReplace  = "replace"  // 取消正在执行的实例后执行
+)
+
+// defaultMaxQueue 排队策略下默认的最大等待数量
+const defaultMaxQueue = 10
+
+// ErrFlowRunning 流程正在执行，本次执行被跳过
+var ErrFlowRunning = errors.New("流程正在执行中，本次执行已跳过")
+
+// ErrFlowQueueFull 流程等待队列已满
+var ErrFlowQueueFull = errors.New("流程等待队列已满，本次执行已拒绝")
+
+// ErrFlowReplaced 流程被新的执行取代
+var ErrFlowReplaced = errors.New("流程被新的执行取代，已取消")
+
+// FlowRuns 流程的并发执行情况
+type FlowRuns struct {
+	FlowID   uint     `json:"flowId"`   // 流程ID
+	Policy   string   `json:"policy"`   // 并发策略
+	MaxQueue int      `json:"maxQueue"` // 最大等待数量
+	Running  []string `json:"running"`  // 正在执行的执行ID
+	Queued   []string `json:"queued"`   // 排队等待的执行ID
+}
+
+// queuedRun 排队等待的执行
+type queuedRun struct {
+	executionID string
+	cancel      context.CancelCauseFunc
+	ready       chan struct{} // 轮到执行时关闭
+}
+
+// activeRun 正在执行的实例
+type activeRun struct {
+	cancel context.CancelCauseFunc
+	done   chan struct{} // 释放后关闭
+}
+
+// flowRunState 单个流程的执行状态
+type flowRunState struct {
+	policy   string
+	maxQueue int
+	running  map[string]*activeRun
+	queue    []*queuedRun
+}
+
+// runGate 按流程的并发策略控制执行
+type runGate struct {
+	mu    sync.Mutex
+	flows map[uint]*flowRunState
+}
+
+// newRunGate 创建执行控制器
+func newRunGate() *runGate {
+	return &runGate{flows: make(map[uint]*flowRunState)}
+}
+
+// flowPolicy 获取流程的并发策略和最大等待数量
+func flowPolicy(flow model.Flow) (string, int) {
+	policy := flow.Concurrency
+	switch policy {
+	case PolicySkip, PolicyQueue, PolicyReplace:
+	default:
+		policy = PolicyParallel
+	}
+	maxQueue := flow.MaxQueue
+	if maxQueue <= 0 {
+		maxQueue = defaultMaxQueue
+	}
+	return policy, maxQueue
+}
+
+// acquire 按并发策略申请执行，返回本次执行使用的上下文和执行结束后的释放函数
+// 排队策略下会阻塞直到轮到本次执行或ctx结束，取代策略下会等待被取消的实例释放后再返回
+func (g *runGate) acquire(ctx context.Context, flow model.Flow, executionID string) (context.Context, func(), error) {
+	runCtx, cancel := context.WithCancelCause(ctx)
+	release := func() { g.release(flow.ID, executionID) }
+
+	g.mu.Lock()
+	state, ok := g.flows[flow.ID]
+	if !ok {
+		state = &flowRunState{running: make(map[string]*activeRun)}
+		g.flows[flow.ID] = state
+	}
+	// 每次执行时使用流程的最新配置
+	state.policy, state.maxQueue = flowPolicy(flow)
+
+	switch state.policy {
+	case PolicySkip:
+		if len(state.running) > 0 {
+			g.cleanup(flow.ID)
+			g.mu.Unlock()
+			cancel(nil)
+			return nil, nil, ErrFlowRunning
+		}
+	case PolicyReplace:
+		replaced := make([]chan struct{}, 0, len(state.running))
+		for _, run := range state.running {
+			run.cancel(ErrFlowReplaced)
+			replaced = append(replaced, run.done)
+		}
+		state.running[executionID] = &activeRun{cancel: cancel, done: make(chan struct{})}
+		g.mu.Unlock()
+		return g.replace(runCtx, replaced, release)
+	case PolicyQueue:
+		if len(state.running) > 0 || len(state.queue) > 0 {
+			if len(state.queue) >= state.maxQueue {
+				g.mu.Unlock()
+				cancel(nil)
+				return nil, nil, ErrFlowQueueFull
+			}
+			waiter := &queuedRun{executionID: executionID, cancel: cancel, ready: make(chan struct{})}
+			state.queue = append(state.queue, waiter)
+			g.mu.Unlock()
+			return g.wait(ctx, runCtx, flow.ID, waiter, release)
+		}
+	}
+
+	state.running[executionID] = &activeRun{cancel: cancel, done: make(chan struct{})}
+	g.mu.Unlock()
+	return runCtx, release, nil
+}
+
+// replace 等待被取代的实例释放
+// 等待期间ctx结束或本次执行又被更新的执行取代时释放并返回原因
+func (g *runGate) replace(runCtx context.Context, replaced []chan struct{}, release func()) (context.Context, func(), error) {
+	for _, done := range replaced {
+		select {
+		case <-done:
+		case <-runCtx.Done():
+			release()
+			return nil, nil, context.Cause(runCtx)
+		}
+	}
+	return runCtx, release, nil
+}
+
+// wait 排队等待轮到本次执行
+func (g *runGate) wait(ctx, runCtx context.Context, flowID uint, waiter *queuedRun, release func()) (context.Context, func(), error) {
+	select {
+	case <-waiter.ready:
+		return runCtx, release, nil
+	case <-ctx.Done():
+	}
+
+	g.mu.Lock()
+	state := g.flows[flowID]
+	for i, queued := range state.queue {
+		if queued == waiter {
+			state.queue = append(state.queue[:i], state.queue[i+1:]...)
+			g.cleanup(flowID)
+			g.mu.Unlock()
+			waiter.cancel(nil)
+			return nil, nil, context.Cause(ctx)
+		}
+	}
+	g.mu.Unlock()
+
+	// 取消的同时已轮到本次执行，需要释放以便后续排队的执行继续
+	release()
+	return nil, nil, context.Cause(ctx)
+}
+
+// release 执行结束后释放，排队策略下唤醒下一个等待的执行
+func (g *runGate) release(flowID uint, executionID string) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	state, ok := g.flows[flowID]
+	if !ok {
+		return
+	}
+	if run, ok := state.running[executionID]; ok {
+		run.cancel(nil)
+		close(run.done)
+		delete(state.running, executionID)
+	}
+	if len(state.running) == 0 && len(state.queue) > 0 {
+		next := state.queue[0]
+		state.queue = state.queue[1:]
+		state.running[next.executionID] = &activeRun{cancel: next.cancel, done: make(chan struct{})}
+		close(next.ready)
+	}
+	g.cleanup(flowID)
+}
+
+// cleanup 流程没有正在执行和排队的实例时移除状态，需在持有锁时调用
+func (g *runGate) cleanup(flowID uint) {
+	if state, ok := g.flows[flowID]; ok && len(state.running) == 0 && len(state.queue) == 0 {
+		delete(g.flows, flowID)
+	}
+}
+
+// runs 获取流程当前的执行情况
+func (g *runGate) runs(flowID uint) FlowRuns {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	runs := FlowRuns{FlowID: flowID, Running: make([]string, 0), Queued: make([]string, 0)}
+	state, ok := g.flows[flowID]
+	if !ok {
+		return runs
+	}
+	runs.Policy, runs.MaxQueue = state.policy, state.maxQueue
+	for executionID := range state.running {
+		runs.Running = append(runs.Running, executionID)
+	}
+	for _, queued := range state.queue {
+		runs.Queued = append(runs.Queued, queued.executionID)
+	}
+	return runs
+}
+
+// allRuns 获取所有正在执行或排队的流程
+func (g *runGate) allRuns() []FlowRuns {
+	g.mu.Lock()
+	flowIDs := make([]uint, 0, len(g.flows))
+	for flowID := range g.flows {
+		flowIDs = append(flowIDs, flowID)
+	}
+	g.mu.Unlock()
+
+	list := make([]FlowRuns, 0, len(flowIDs))
+	for _, flowID := range flowIDs {
+		list = append(list, g.runs(flowID))
+	}
+	return list
+}
@@ -0,0 +1,66 @@
+package dagflow
+
+import (
+	"context"
+	"server/dagflow/model"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestReplaceWaitsForRelease 测试取代策略在被取消的实例释放后才开始执行
+func TestReplaceWaitsForRelease(t *testing.T) {
+	gate := newRunGate()
+	flow := model.Flow{ID: 1, Concurrency: PolicyReplace}
+
+	oldCtx, oldRelease, err := gate.acquire(context.Background(), flow, "old")
+	require.NoError(t, err)
+
+	acquired := make(chan error, 1)
+	var newRelease func()
+	go func() {
+		var err error
+		_, newRelease, err = gate.acquire(context.Background(), flow, "new")
+		acquired <- err
+	}()
+
+	// 旧的实例被取消，但释放前新的执行不能开始
+	<-oldCtx.Done()
+	assert.ErrorIs(t, context.Cause(oldCtx), ErrFlowReplaced)
+	select {
+	case <-acquired:
+		t.Fatal("旧的实例释放前新的执行已开始")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	oldRelease()
+	select {
+	case err := <-acquired:
+		require.NoError(t, err)
+	case <-time.After(time.Second):
+		t.Fatal("旧的实例释放后新的执行没有开始")
+	}
+	assert.Equal(t, []string{"new"}, gate.runs(1).Running)
+	newRelease()
+	assert.Empty(t, gate.allRuns())
+}
+
+// TestReplaceWaitCanceled 测试等待被取代的实例释放时上下文结束，本次执行不再占用
+func TestReplaceWaitCanceled(t *testing.T) {
+	gate := newRunGate()
+	flow := model.Flow{ID: 1, Concurrency: PolicyReplace}
+
+	_, oldRelease, err := gate.acquire(context.Background(), flow, "old")
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	_, _, err = gate.acquire(ctx, flow, "new")
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Equal(t, []string{"old"}, gate.runs(1).Running)
+
+	oldRelease()
+	assert.Empty(t, gate.allRuns())
+}
@@ -3,21 +3,60 @@ package el
 
 import (
 	"fmt"
+	"sync"
 
 	"github.com/expr-lang/expr"
 )
 
+// ScopeKey 表达式环境中保存执行范围的键，作用域函数通过它获取调用方信息
+const ScopeKey = "__scope"
+
+// ScopedFunction 作用域函数，调用时传入执行范围
+type ScopedFunction func(scope any, args ...any) (any, error)
+
+var (
+	scopedMu        sync.RWMutex
+	scopedFunctions = make(map[string]ScopedFunction)
+)
+
+// RegisterScopedFunction 注册表达式中可调用的作用域函数
+func RegisterScopedFunction(name string, fn ScopedFunction) {
+	scopedMu.Lock()
+	defer scopedMu.Unlock()
+	scopedFunctions[name] = fn
+}
+
+// CallScopedFunction 调用已注册的作用域函数，供脚本节点等非表达式场景使用
+func CallScopedFunction(name string, scope any, args ...any) (any, error) {
+	scopedMu.RLock()
+	fn, ok := scopedFunctions[name]
+	scopedMu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("未注册的函数: %s", name)
+	}
+	return fn(scope, args...)
+}
+
+// Evaluate 计算表达式
 func Evaluate(el string, data map[string]interface{}) (any, error) {
-	fmt.Printf("Evaluating expression: %s\n", el)
-	fmt.Printf("Using environment: %v\n", data)
-	program, err := expr.Compile(el, expr.Env(data))
+	options := []expr.Option{expr.Env(data)}
+	scope := data[ScopeKey]
+	scopedMu.RLock()
+	for name, fn := range scopedFunctions {
+		fn := fn
+		options = append(options, expr.Function(name, func(args ...any) (any, error) {
+			return fn(scope, args...)
+		}))
+	}
+	scopedMu.RUnlock()
+
+	program, err := expr.Compile(el, options...)
 	if err != nil {
-		panic(err)
+		return nil, fmt.Errorf("表达式编译失败: %v", err)
 	}
 	output, err := expr.Run(program, data)
 	if err != nil {
-		panic(err)
+		return nil, fmt.Errorf("表达式执行失败: %v", err)
 	}
-	fmt.Printf("output: %v type: %T\n", output, output)
 	return output, nil
 }
@@ -0,0 +1,294 @@
+package dagflow
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"server/dagflow/core/el"
+	"server/dagflow/engine"
+	"server/dagflow/model"
+	"sync"
+	"time"
+)
+
+// DebugStatus 调试会话状态
+type DebugStatus string
+
+const (
+	DebugRunning   DebugStatus = "running"   // 运行中
+	DebugPaused    DebugStatus = "paused"    // 在断点处暂停
+	DebugCompleted DebugStatus = "completed" // 执行完成
+	DebugFailed    DebugStatus = "failed"    // 执行失败
+	DebugAborted   DebugStatus = "aborted"   // 已中止（手动中止或暂停超时）
+)
+
+// DebugCommand 调试控制命令
+type DebugCommand string
+
+const (
+	DebugStep     DebugCommand = "step"     // 执行当前节点并在下一个节点前暂停
+	DebugContinue DebugCommand = "continue" // 继续执行直到下一个断点
+	DebugAbort    DebugCommand = "abort"    // 中止执行
+)
+
+// 调试会话默认配置
+const (
+	defaultDebugPauseTimeout = 10 * time.Minute // 暂停等待命令的默认超时时间
+	debugSessionKeepTime     = 30 * time.Minute // 会话结束后保留供查看的时间
+)
+
+// ErrDebugAborted 调试会话被中止
+var ErrDebugAborted = errors.New("调试会话已中止")
+
+// ErrDebugPauseTimeout 调试会话暂停超时
+var ErrDebugPauseTimeout = errors.New("调试会话暂停超时，已自动中止")
+
+// DebugSession 交互式调试会话
+// 在断点节点执行前暂停流程，暂停期间可查看和修改数据、计算表达式，并通过命令单步、继续或中止
+type DebugSession struct {
+	ID           string          `json:"id"`           // 会话ID，与执行ID一致
+	FlowID       uint            `json:"flowId"`       // 流程ID
+	Status       DebugStatus     `json:"status"`       // 会话状态
+	Breakpoints  []string        `json:"breakpoints"`  // 断点节点ID
+	PausedNode   string          `json:"pausedNode"`   // 当前暂停的节点ID
+	PausedAt     time.Time       `json:"pausedAt"`     // 暂停时间
+	PauseTimeout int             `json:"pauseTimeout"` // 暂停超时时间（秒）
+	Error        string          `json:"error"`        // 执行错误
+	StartTime    time.Time       `json:"startTime"`    // 开始时间
+	EndTime      time.Time       `json:"endTime"`      // 结束时间
+	breakpoints  map[string]bool // 断点集合
+	pauseTimeout time.Duration   // 暂停超时时间
+	stepping     bool            // 是否单步执行
+	commands     chan DebugCommand
+	pauseMu      sync.Mutex // 保证同一时间只有一个节点处于暂停状态
+	mu           sync.RWMutex
+	execCtx      *model.ExecutionContext
+}
+
+// debugSessions 所有调试会话
+var debugSessions sync.Map
+
+// StartDebugSession 启动交互式调试会话
+// 流程在后台执行，遇到断点节点时暂停等待调试命令
+func (s *Service) StartDebugSession(flowID string, params map[string]any, breakpoints []string, pauseTimeout time.Duration) (*DebugSession, error) {
+	flow, err := s.loadFlow(flowID)
+	if err != nil {
+		return nil, err
+	}
+	for _, nodeID := range breakpoints {
+		if _, ok := flow.Nodes[nodeID]; !ok {
+			return nil, fmt.Errorf("断点节点不存在: %s", nodeID)
+		}
+	}
+	if _, err := model.ValidateParams(flow.Params, params); err != nil {
+		return nil, err
+	}
+	if pauseTimeout <= 0 {
+		pauseTimeout = defaultDebugPauseTimeout
+	}
+
+	execCtx := model.NewExecutionContext(flow.ID, params, s.logger)
+	execCtx.Debug = true
+	session := &DebugSession{
+		ID:           execCtx.ExecutionID,
+		FlowID:       flow.ID,
+		Status:       DebugRunning,
+		PauseTimeout: int(pauseTimeout / time.Second),
+		StartTime:    time.Now(),
+		pauseTimeout: pauseTimeout,
+		commands:     make(chan DebugCommand),
+		execCtx:      execCtx,
+	}
+	session.SetBreakpoints(breakpoints)
+	debugSessions.Store(session.ID, session)
+
+	go func() {
+		_, err := s.runFlow(context.Background(), flow, execCtx, engine.WithBeforeNode(session.beforeNode))
+		session.finish(err)
+		// 会话结束后保留一段时间供查看，之后自动清理
+		time.AfterFunc(debugSessionKeepTime, func() {
+			debugSessions.Delete(session.ID)
+		})
+	}()
+	return session, nil
+}
+
+// GetDebugSession 获取调试会话
+func (s *Service) GetDebugSession(id string) (*DebugSession, error) {
+	if session, ok := debugSessions.Load(id); ok {
+		return session.(*DebugSession), nil
+	}
+	return nil, fmt.Errorf("调试会话不存在: %s", id)
+}
+
+// beforeNode 节点执行前检查断点，命中时暂停等待调试命令
+func (d *DebugSession) beforeNode(ctx context.Context, node model.TaskNode, execCtx *model.ExecutionContext) error {
+	d.mu.RLock()
+	hit := d.stepping || d.breakpoints[node.ID]
+	d.mu.RUnlock()
+	if !hit {
+		return nil
+	}
+
+	d.pauseMu.Lock()
+	defer d.pauseMu.Unlock()
+
+	d.mu.Lock()
+	d.Status = DebugPaused
+	d.PausedNode = node.ID
+	d.PausedAt = time.Now()
+	d.mu.Unlock()
+	execCtx.Emit(model.Event{Type: model.EventNodePaused, NodeID: node.ID, Status: model.Pending})
+	execCtx.Log("info", "【调试模式】在节点 %s (%s) 前暂停", node.Name, node.ID)
+
+	timer := time.NewTimer(d.pauseTimeout)
+	defer timer.Stop()
+
+	var cmd DebugCommand
+	select {
+	case cmd = <-d.commands:
+	case <-timer.C:
+		d.setStatus(DebugAborted, "")
+		return ErrDebugPauseTimeout
+	case <-ctx.Done():
+		d.setStatus(DebugAborted, "")
+		return ctx.Err()
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.PausedNode = ""
+	switch cmd {
+	case DebugStep:
+		d.stepping = true
+	case DebugContinue:
+		d.stepping = false
+	case DebugAbort:
+		d.Status = DebugAborted
+		return ErrDebugAborted
+	}
+	d.Status = DebugRunning
+	return nil
+}
+
+// Command 向暂停中的会话发送调试命令
+func (d *DebugSession) Command(cmd DebugCommand) error {
+	switch cmd {
+	case DebugStep, DebugContinue, DebugAbort:
+	default:
+		return fmt.Errorf("未知的调试命令: %s", cmd)
+	}
+	if err := d.requirePaused(); err != nil {
+		return err
+	}
+	select {
+	case d.commands <- cmd:
+		return nil
+	case <-time.After(time.Second):
+		return errors.New("调试会话未在等待命令")
+	}
+}
+
+// SetBreakpoints 设置断点节点
+func (d *DebugSession) SetBreakpoints(breakpoints []string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.breakpoints = make(map[string]bool, len(breakpoints))
+	d.Breakpoints = make([]string, 0, len(breakpoints))
+	for _, nodeID := range breakpoints {
+		if nodeID != "" && !d.breakpoints[nodeID] {
+			d.breakpoints[nodeID] = true
+			d.Breakpoints = append(d.Breakpoints, nodeID)
+		}
+	}
+}
+
+// SetData 修改暂停中会话的执行数据，value为nil时删除该数据
+func (d *DebugSession) SetData(key string, value any) error {
+	if key == "" {
+		return errors.New("数据键不能为空")
+	}
+	if err := d.requirePaused(); err != nil {
+		return err
+	}
+	if value == nil {
+		d.execCtx.DeleteData(key)
+	} else {
+		d.execCtx.SetData(key, value)
+	}
+	d.execCtx.Log("info", "【调试模式】修改数据 %s = %v", key, value)
+	return nil
+}
+
+// Evaluate 基于暂停中会话的执行数据计算EL表达式
+func (d *DebugSession) Evaluate(expression string) (any, error) {
+	if expression == "" {
+		return nil, errors.New("表达式不能为空")
+	}
+	if err := d.requirePaused(); err != nil {
+		return nil, err
+	}
+	return el.Evaluate(expression, d.execCtx.ELEnv())
+}
+
+// Snapshot 获取会话状态和执行上下文
+func (d *DebugSession) Snapshot() map[string]any {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	return map[string]any{
+		"session":   d.copyInfo(),
+		"execution": d.execCtx,
+	}
+}
+
+// copyInfo 复制会话的公开信息
+func (d *DebugSession) copyInfo() *DebugSession {
+	return &DebugSession{
+		ID:           d.ID,
+		FlowID:       d.FlowID,
+		Status:       d.Status,
+		Breakpoints:  append([]string{}, d.Breakpoints...),
+		PausedNode:   d.PausedNode,
+		PausedAt:     d.PausedAt,
+		PauseTimeout: d.PauseTimeout,
+		Error:        d.Error,
+		StartTime:    d.StartTime,
+		EndTime:      d.EndTime,
+	}
+}
+
+// requirePaused 检查会话是否处于暂停状态
+func (d *DebugSession) requirePaused() error {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	if d.Status != DebugPaused {
+		return fmt.Errorf("调试会话未处于暂停状态，当前状态: %s", d.Status)
+	}
+	return nil
+}
+
+// setStatus 设置会话状态
+func (d *DebugSession) setStatus(status DebugStatus, errMsg string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.Status = status
+	if errMsg != "" {
+		d.Error = errMsg
+	}
+}
+
+// finish 流程执行结束后更新会话状态
+func (d *DebugSession) finish(err error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.EndTime = time.Now()
+	d.PausedNode = ""
+	if err != nil {
+		d.Error = err.Error()
+		if d.Status != DebugAborted {
+			d.Status = DebugFailed
+		}
+		return
+	}
+	d.Status = DebugCompleted
+}
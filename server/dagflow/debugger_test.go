@@ -0,0 +1,201 @@
+package dagflow
+
+import (
+	"context"
+	"server/dagflow/engine"
+	"server/dagflow/handler"
+	"server/dagflow/model"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// debugNodeType 调试测试节点的类型
+const debugNodeType = "debug-test"
+
+// debugHandler 记录执行顺序的测试处理器，节点返回执行时看到的x数据
+type debugHandler struct {
+	mu  sync.Mutex
+	ran []string
+}
+
+// Handle 记录执行的节点
+func (h *debugHandler) Handle(ctx context.Context, node model.TaskNode, execCtx *model.ExecutionContext) (any, error) {
+	h.mu.Lock()
+	h.ran = append(h.ran, node.ID)
+	h.mu.Unlock()
+	x, _ := execCtx.GetData("x")
+	return x, nil
+}
+
+// GetType 获取处理器类型
+func (h *debugHandler) GetType() string {
+	return debugNodeType
+}
+
+// Validate 测试节点不校验配置
+func (h *debugHandler) Validate(node model.TaskNode) error {
+	return nil
+}
+
+// executed 获取已执行的节点
+func (h *debugHandler) executed() []string {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return append([]string(nil), h.ran...)
+}
+
+// startTestDebug 在start -> a -> b -> end的流程上启动调试会话，返回会话、处理器和执行结束的通道
+func startTestDebug(t *testing.T, breakpoints []string, pauseTimeout time.Duration) (*DebugSession, *debugHandler, chan struct{}) {
+	t.Helper()
+	h := &debugHandler{}
+	registry := handler.NewHandlerRegistry()
+	registry.Register(h)
+	e := engine.NewEngine(registry, nil)
+
+	flow := model.Flow{
+		ID:          1,
+		Name:        "debug",
+		Nodes:       make(map[string]model.TaskNode),
+		Edges:       make(map[string]model.Edge),
+		StartNodeID: "start",
+		EndNodeID:   "end",
+	}
+	for _, id := range []string{"start", "a", "b", "end"} {
+		flow.Nodes[id] = model.TaskNode{ID: id, Name: id, Type: debugNodeType}
+	}
+	for _, pair := range [][2]string{{"start", "a"}, {"a", "b"}, {"b", "end"}} {
+		id := pair[0] + "->" + pair[1]
+		flow.Edges[id] = model.Edge{ID: id, Name: id, Source: pair[0], Target: pair[1]}
+	}
+
+	execCtx := model.NewExecutionContext(flow.ID, nil, nil)
+	execCtx.Debug = true
+	session := &DebugSession{
+		ID:           execCtx.ExecutionID,
+		FlowID:       flow.ID,
+		Status:       DebugRunning,
+		PauseTimeout: int(pauseTimeout / time.Second),
+		pauseTimeout: pauseTimeout,
+		commands:     make(chan DebugCommand),
+		execCtx:      execCtx,
+	}
+	session.SetBreakpoints(breakpoints)
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		_, err := e.Run(context.Background(), flow, execCtx, engine.WithBeforeNode(session.beforeNode))
+		session.finish(err)
+	}()
+	return session, h, done
+}
+
+// waitPaused 等待会话在指定节点前暂停
+func waitPaused(t *testing.T, session *DebugSession, nodeID string) {
+	t.Helper()
+	require.Eventually(t, func() bool {
+		info := session.Snapshot()["session"].(*DebugSession)
+		return info.Status == DebugPaused && info.PausedNode == nodeID
+	}, time.Second, 5*time.Millisecond, "会话没有在节点 %s 前暂停", nodeID)
+}
+
+// waitDone 等待流程执行结束
+func waitDone(t *testing.T, done chan struct{}) {
+	t.Helper()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("流程没有执行结束")
+	}
+}
+
+// TestDebugBreakpointContinue 测试在断点前暂停，暂停期间修改数据和计算表达式，继续后执行完成
+func TestDebugBreakpointContinue(t *testing.T) {
+	session, h, done := startTestDebug(t, []string{"b"}, time.Minute)
+	waitPaused(t, session, "b")
+	assert.Equal(t, []string{"start", "a"}, h.executed())
+
+	require.NoError(t, session.SetData("x", 5))
+	value, err := session.Evaluate("x + 1")
+	require.NoError(t, err)
+	assert.Equal(t, 6, value)
+	require.NoError(t, session.SetData("y", 1))
+	require.NoError(t, session.SetData("y", nil))
+	_, ok := session.execCtx.GetData("y")
+	assert.False(t, ok)
+
+	require.NoError(t, session.Command(DebugContinue))
+	waitDone(t, done)
+	assert.Equal(t, DebugCompleted, session.Status)
+	assert.Empty(t, session.PausedNode)
+	assert.Equal(t, []string{"start", "a", "b"}, h.executed())
+	// 修改的数据在继续执行后生效
+	result, _ := session.execCtx.GetData("b")
+	assert.Equal(t, 5, result)
+}
+
+// TestDebugStep 测试单步执行在下一个节点前再次暂停
+func TestDebugStep(t *testing.T) {
+	session, h, done := startTestDebug(t, []string{"a"}, time.Minute)
+	waitPaused(t, session, "a")
+
+	require.NoError(t, session.Command(DebugStep))
+	waitPaused(t, session, "b")
+	assert.Equal(t, []string{"start", "a"}, h.executed())
+
+	require.NoError(t, session.Command(DebugContinue))
+	waitDone(t, done)
+	assert.Equal(t, DebugCompleted, session.Status)
+	assert.Equal(t, []string{"start", "a", "b"}, h.executed())
+}
+
+// TestDebugAbort 测试中止后不再执行后续节点
+func TestDebugAbort(t *testing.T) {
+	session, h, done := startTestDebug(t, []string{"b"}, time.Minute)
+	waitPaused(t, session, "b")
+
+	require.NoError(t, session.Command(DebugAbort))
+	waitDone(t, done)
+	assert.Equal(t, DebugAborted, session.Status)
+	assert.Contains(t, session.Error, ErrDebugAborted.Error())
+	assert.Equal(t, []string{"start", "a"}, h.executed())
+}
+
+// TestDebugPauseTimeout 测试暂停超时后自动中止
+func TestDebugPauseTimeout(t *testing.T) {
+	session, h, done := startTestDebug(t, []string{"a"}, 50*time.Millisecond)
+
+	waitDone(t, done)
+	assert.Equal(t, DebugAborted, session.Status)
+	assert.Contains(t, session.Error, ErrDebugPauseTimeout.Error())
+	assert.Equal(t, []string{"start"}, h.executed())
+}
+
+// TestDebugRequiresPaused 测试会话未暂停时不能发送命令、修改数据和计算表达式
+func TestDebugRequiresPaused(t *testing.T) {
+	session, _, done := startTestDebug(t, nil, time.Minute)
+	waitDone(t, done)
+	require.Equal(t, DebugCompleted, session.Status)
+
+	assert.Error(t, session.Command(DebugContinue))
+	assert.Error(t, session.SetData("x", 1))
+	_, err := session.Evaluate("1 + 1")
+	assert.Error(t, err)
+
+	assert.EqualError(t, session.Command("jump"), "未知的调试命令: jump")
+	assert.EqualError(t, session.SetData("", 1), "数据键不能为空")
+	_, err = session.Evaluate("")
+	assert.EqualError(t, err, "表达式不能为空")
+}
+
+// TestDebugSessionJSON 测试会话信息中的暂停超时时间以秒为单位
+func TestDebugSessionJSON(t *testing.T) {
+	session, _, done := startTestDebug(t, nil, 90*time.Second)
+	waitDone(t, done)
+	info := session.Snapshot()["session"].(*DebugSession)
+	assert.Equal(t, 90, info.PauseTimeout)
+}
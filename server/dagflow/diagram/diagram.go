@@ -0,0 +1,136 @@
+// Package diagram 将流程导出为Graphviz DOT和Mermaid文本，用于设计评审和文档
+// 可叠加一次执行的节点状态和耗时
+package diagram
+
+import (
+	"fmt"
+	"server/dagflow/model"
+	"sort"
+	"strings"
+)
+
+// 导出格式
+const (
+	FormatDOT     = "dot"     // Graphviz DOT
+	FormatMermaid = "mermaid" // Mermaid flowchart
+)
+
+// NodeState 节点的执行状态
+type NodeState struct {
+	Status   model.NodeExecutionStatus `json:"status"`   // 执行状态
+	Duration int64                     `json:"duration"` // 耗时(毫秒)，未执行完成时为0
+}
+
+// Overlay 叠加到图上的执行状态，键为节点ID
+type Overlay map[string]NodeState
+
+// OverlayOf 从执行上下文中获取各节点的状态和耗时
+func OverlayOf(execCtx *model.ExecutionContext) Overlay {
+	overlay := make(Overlay, len(execCtx.NodeStatus))
+	for nodeID, status := range execCtx.NodeStatus {
+		state := NodeState{Status: status}
+		start, ok1 := execCtx.NodeStartTimes[nodeID]
+		end, ok2 := execCtx.NodeEndTimes[nodeID]
+		if ok1 && ok2 && !end.Before(start) {
+			state.Duration = end.Sub(start).Milliseconds()
+		}
+		overlay[nodeID] = state
+	}
+	return overlay
+}
+
+// Export 按格式导出流程，overlay为nil时不显示执行状态
+func Export(flow model.Flow, format string, overlay Overlay) (string, error) {
+	switch strings.ToLower(format) {
+	case "", FormatDOT:
+		return DOT(flow, overlay), nil
+	case FormatMermaid:
+		return Mermaid(flow, overlay), nil
+	}
+	return "", fmt.Errorf("不支持的导出格式: %s", format)
+}
+
+// statusColors 各执行状态的填充色和边框色
+var statusColors = map[model.NodeExecutionStatus][2]string{
+	model.Completed: {"#d9f7be", "#389e0d"},
+	model.Failed:    {"#ffccc7", "#cf1322"},
+	model.TimedOut:  {"#ffe7ba", "#d46b08"},
+	model.Running:   {"#bae7ff", "#096dd9"},
+	model.Skipped:   {"#f0f0f0", "#8c8c8c"},
+	model.Pending:   {"#ffffff", "#8c8c8c"},
+}
+
+// nodeLabel 节点标签的各行：名称、类型，有执行状态时追加状态和耗时
+func nodeLabel(node model.TaskNode, overlay Overlay) []string {
+	name := node.Name
+	if name == "" {
+		name = node.ID
+	}
+	lines := []string{name, "(" + node.Type + ")"}
+	if node.Disabled {
+		lines[1] = "(" + node.Type + ", 已禁用)"
+	}
+	if state, ok := overlay[node.ID]; ok {
+		line := string(state.Status)
+		if state.Duration > 0 {
+			line += fmt.Sprintf(" %dms", state.Duration)
+		}
+		lines = append(lines, line)
+	}
+	return lines
+}
+
+// edgeLabel 连线标签的各行：名称或表达式、连接点
+func edgeLabel(edge model.Edge) []string {
+	lines := make([]string, 0, 2)
+	if edge.Expression != "" {
+		lines = append(lines, edge.Expression)
+	} else if edge.Name != "" {
+		lines = append(lines, edge.Name)
+	}
+	if edge.Kind == model.EdgeError {
+		lines = append(lines, "[error]")
+	}
+	if edge.SourceAnchor != "" || edge.TargetAnchor != "" {
+		lines = append(lines, edge.SourceAnchor+" → "+edge.TargetAnchor)
+	}
+	return lines
+}
+
+// sortedNodes 按开始节点、普通节点、finally节点、结束节点的顺序排列节点，同类按ID排序，保证输出稳定
+func sortedNodes(flow model.Flow) []model.TaskNode {
+	rank := func(id string) int {
+		switch id {
+		case flow.StartNodeID:
+			return 0
+		case flow.FinallyID:
+			return 2
+		case flow.EndNodeID:
+			return 3
+		}
+		return 1
+	}
+	nodes := make([]model.TaskNode, 0, len(flow.Nodes))
+	for _, node := range flow.Nodes {
+		nodes = append(nodes, node)
+	}
+	sort.Slice(nodes, func(i, j int) bool {
+		if ri, rj := rank(nodes[i].ID), rank(nodes[j].ID); ri != rj {
+			return ri < rj
+		}
+		return nodes[i].ID < nodes[j].ID
+	})
+	return nodes
+}
+
+// sortedEdges 按ID排列连线，保证输出稳定
+func sortedEdges(flow model.Flow) []model.Edge {
+	edges := make([]model.Edge, 0, len(flow.Edges))
+	for _, edge := range flow.Edges {
+		edges = append(edges, edge)
+	}
+	sort.Slice(edges, func(i, j int) bool {
+		return edges[i].ID < edges[j].ID
+	})
+	return edges
+}
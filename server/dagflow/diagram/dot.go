@@ -0,0 +1,73 @@
+package diagram
+
+import (
+	"fmt"
+	"server/dagflow/model"
+	"strings"
+)
+
+// DOT 导出为Graphviz DOT文本
+// 开始和结束节点为椭圆，禁用的节点为灰色虚线框，错误连线为红色虚线
+func DOT(flow model.Flow, overlay Overlay) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "digraph %s {\n", dotQuote(flow.Name))
+	b.WriteString("  rankdir=TB;\n")
+	b.WriteString("  node [shape=box, style=\"rounded\", fontname=\"sans-serif\"];\n")
+	b.WriteString("  edge [fontname=\"sans-serif\", fontsize=10];\n")
+
+	for _, node := range sortedNodes(flow) {
+		attrs := []string{"label=" + dotQuote(strings.Join(nodeLabel(node, overlay), "\n"))}
+		styles := []string{"rounded"}
+		switch node.ID {
+		case flow.StartNodeID, flow.EndNodeID:
+			attrs = append(attrs, "shape=ellipse")
+			styles = styles[:0]
+		case flow.FinallyID:
+			attrs = append(attrs, "shape=hexagon")
+			styles = styles[:0]
+		}
+		color := ""
+		if state, ok := overlay[node.ID]; ok {
+			if colors, ok := statusColors[state.Status]; ok {
+				styles = append(styles, "filled")
+				attrs = append(attrs, "fillcolor="+dotQuote(colors[0]))
+				color = colors[1]
+			}
+		}
+		if node.Disabled {
+			styles = append(styles, "dashed")
+			attrs = append(attrs, "fontcolor=\"#8c8c8c\"")
+			color = "#8c8c8c"
+		}
+		if color != "" {
+			attrs = append(attrs, "color="+dotQuote(color))
+		}
+		if len(styles) > 0 {
+			attrs = append(attrs, "style="+dotQuote(strings.Join(styles, ",")))
+		}
+		fmt.Fprintf(&b, "  %s [%s];\n", dotQuote(node.ID), strings.Join(attrs, ", "))
+	}
+
+	for _, edge := range sortedEdges(flow) {
+		attrs := make([]string, 0, 3)
+		if label := edgeLabel(edge); len(label) > 0 {
+			attrs = append(attrs, "label="+dotQuote(strings.Join(label, "\n")))
+		}
+		if edge.Kind == model.EdgeError {
+			attrs = append(attrs, "style=dashed", "color=\"#cf1322\"")
+		}
+		fmt.Fprintf(&b, "  %s -> %s", dotQuote(edge.Source), dotQuote(edge.Target))
+		if len(attrs) > 0 {
+			fmt.Fprintf(&b, " [%s]", strings.Join(attrs, ", "))
+		}
+		b.WriteString(";\n")
+	}
+	b.WriteString("}\n")
+	return b.String()
+}
+
+// dotQuote 转为DOT的双引号字符串
+func dotQuote(text string) string {
+	replacer := strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\r", "", "\n", `\n`)
+	return `"` + replacer.Replace(text) + `"`
+}
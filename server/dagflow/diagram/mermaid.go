@@ -0,0 +1,95 @@
+package diagram
+
+import (
+	"fmt"
+	"server/dagflow/model"
+	"sort"
+	"strings"
+)
+
+// Mermaid 导出为Mermaid flowchart文本
+// 节点ID可能包含Mermaid不支持的字符，按顺序映射为n1、n2等；禁用的节点为灰色虚线框，错误连线为虚线
+func Mermaid(flow model.Flow, overlay Overlay) string {
+	var b strings.Builder
+	b.WriteString("flowchart TD\n")
+	if flow.Name != "" {
+		fmt.Fprintf(&b, "  %%%% %s\n", strings.ReplaceAll(flow.Name, "\n", " "))
+	}
+
+	ids := make(map[string]string, len(flow.Nodes))
+	classes := make(map[string][]string)
+	for i, node := range sortedNodes(flow) {
+		id := fmt.Sprintf("n%d", i+1)
+		ids[node.ID] = id
+		label := mermaidQuote(strings.Join(nodeLabel(node, overlay), "<br/>"))
+		switch node.ID {
+		case flow.StartNodeID, flow.EndNodeID:
+			fmt.Fprintf(&b, "  %s([%s])\n", id, label)
+		case flow.FinallyID:
+			fmt.Fprintf(&b, "  %s{{%s}}\n", id, label)
+		default:
+			fmt.Fprintf(&b, "  %s[%s]\n", id, label)
+		}
+		if state, ok := overlay[node.ID]; ok {
+			if _, ok := statusColors[state.Status]; ok {
+				classes[string(state.Status)] = append(classes[string(state.Status)], id)
+			}
+		}
+		if node.Disabled {
+			classes["disabled"] = append(classes["disabled"], id)
+		}
+	}
+
+	for _, edge := range sortedEdges(flow) {
+		source, target := mermaidID(ids, edge.Source), mermaidID(ids, edge.Target)
+		arrow := "-->"
+		if edge.Kind == model.EdgeError {
+			arrow = "-.->"
+		}
+		if label := edgeLabel(edge); len(label) > 0 {
+			fmt.Fprintf(&b, "  %s %s|%s| %s\n", source, arrow, mermaidQuote(strings.Join(label, "<br/>")), target)
+		} else {
+			fmt.Fprintf(&b, "  %s %s %s\n", source, arrow, target)
+		}
+	}
+
+	// 样式类，状态样式在前，禁用样式在后以覆盖边框
+	names := make([]string, 0, len(classes))
+	for name := range classes {
+		if name != "disabled" {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		colors := statusColors[model.NodeExecutionStatus(name)]
+		fmt.Fprintf(&b, "  classDef %s fill:%s,stroke:%s\n", name, colors[0], colors[1])
+	}
+	if _, ok := classes["disabled"]; ok {
+		b.WriteString("  classDef disabled stroke:#8c8c8c,stroke-dasharray:5 5,color:#8c8c8c\n")
+		names = append(names, "disabled")
+	}
+	for _, name := range names {
+		fmt.Fprintf(&b, "  class %s %s\n", strings.Join(classes[name], ","), name)
+	}
+	return b.String()
+}
+
+// mermaidID 获取节点在Mermaid中的ID，连线指向不存在的节点时使用转义后的原ID
+func mermaidID(ids map[string]string, nodeID string) string {
+	if id, ok := ids[nodeID]; ok {
+		return id
+	}
+	return "x_" + strings.Map(func(r rune) rune {
+		if r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' {
+			return r
+		}
+		return '_'
+	}, nodeID)
+}
+
+// mermaidQuote 转为Mermaid的双引号标签，引号使用实体转义
+func mermaidQuote(text string) string {
+	replacer := strings.NewReplacer(`"`, "#quot;", "\r", "", "\n", "<br/>")
+	return `"` + replacer.Replace(text) + `"`
+}
@@ -0,0 +1,116 @@
+package engine
+
+import (
+	"context"
+	"errors"
+	"server/dagflow/handler"
+	"server/dagflow/model"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// testNodeType 测试节点的类型
+const testNodeType = "test"
+
+// nodeFunc 测试节点的执行函数
+type nodeFunc func(ctx context.Context, execCtx *model.ExecutionContext) (any, error)
+
+// testHandler 按节点ID分派执行函数的测试处理器，未设置执行函数的节点返回节点ID
+type testHandler struct {
+	funcs map[string]nodeFunc
+	mu    sync.Mutex
+	runs  map[string]int // 节点的执行次数
+}
+
+// Handle 执行节点对应的函数并记录执行次数
+func (h *testHandler) Handle(ctx context.Context, node model.TaskNode, execCtx *model.ExecutionContext) (any, error) {
+	h.mu.Lock()
+	h.runs[node.ID]++
+	h.mu.Unlock()
+	if fn, ok := h.funcs[node.ID]; ok {
+		return fn(ctx, execCtx)
+	}
+	return node.ID, nil
+}
+
+// GetType 获取处理器类型
+func (h *testHandler) GetType() string {
+	return testNodeType
+}
+
+// Validate 测试节点不校验配置
+func (h *testHandler) Validate(node model.TaskNode) error {
+	return nil
+}
+
+// count 获取节点的执行次数
+func (h *testHandler) count(nodeID string) int {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.runs[nodeID]
+}
+
+// newTestEngine 创建使用测试处理器的引擎
+func newTestEngine(funcs map[string]nodeFunc) (*Engine, *testHandler) {
+	h := &testHandler{funcs: funcs, runs: make(map[string]int)}
+	registry := handler.NewHandlerRegistry()
+	registry.Register(h)
+	return NewEngine(registry, nil), h
+}
+
+// newTestFlow 创建从start开始、到end结束的测试流程
+func newTestFlow(nodeIDs []string, edges ...model.Edge) model.Flow {
+	flow := model.Flow{
+		ID:          1,
+		Name:        "test",
+		Nodes:       make(map[string]model.TaskNode),
+		Edges:       make(map[string]model.Edge),
+		StartNodeID: "start",
+		EndNodeID:   "end",
+	}
+	for _, id := range append([]string{"start", "end"}, nodeIDs...) {
+		flow.Nodes[id] = model.TaskNode{ID: id, Name: id, Type: testNodeType}
+	}
+	for _, edge := range edges {
+		if edge.ID == "" {
+			edge.ID = edge.Source + "->" + edge.Target
+		}
+		edge.Name = edge.ID
+		flow.Edges[edge.ID] = edge
+	}
+	return flow
+}
+
+// edge 创建正常连线
+func edge(source, target string) model.Edge {
+	return model.Edge{Source: source, Target: target}
+}
+
+// errorEdge 创建错误分支连线
+func errorEdge(source, target string) model.Edge {
+	return model.Edge{Source: source, Target: target, Kind: model.EdgeError}
+}
+
+// fail 返回执行失败的节点函数
+func fail(message string) nodeFunc {
+	return func(ctx context.Context, execCtx *model.ExecutionContext) (any, error) {
+		return nil, errors.New(message)
+	}
+}
+
+// runTestFlow 执行测试流程
+func runTestFlow(t *testing.T, e *Engine, flow model.Flow, opts ...RunOption) (*model.ExecutionContext, error) {
+	t.Helper()
+	execCtx := model.NewExecutionContext(flow.ID, nil, nil)
+	return e.Run(context.Background(), flow, execCtx, opts...)
+}
+
+// assertNodeStatus 校验各节点的执行状态
+func assertNodeStatus(t *testing.T, execCtx *model.ExecutionContext, want map[string]model.NodeExecutionStatus) {
+	t.Helper()
+	for nodeID, status := range want {
+		assert.Equal(t, status, execCtx.GetNodeStatus(nodeID), "节点 %s", nodeID)
+	}
+}
@@ -0,0 +1,122 @@
+package engine
+
+import (
+	"server/dagflow/model"
+	"sync"
+)
+
+const (
+	historyEvents     = 512 // 每次执行保留的最近事件数
+	historyExecutions = 64  // 保留事件的执行数，超过时丢弃最早开始的执行
+)
+
+// EventBus 流程执行事件总线
+// 引擎在执行过程中将事件发布到总线，订阅者按过滤条件接收事件
+// 总线保留最近执行的事件，订阅指定执行时先重放已发布的事件，避免执行很快结束时订阅者错过结束事件
+type EventBus struct {
+	mu          sync.Mutex
+	nextID      int
+	subscribers map[int]*subscriber
+	history     map[string][]model.Event // 按执行ID保留的最近事件
+	executions  []string                 // 保留事件的执行ID，按首个事件的先后排序
+}
+
+// subscriber 事件订阅者
+type subscriber struct {
+	ch     chan model.Event
+	filter func(model.Event) bool
+}
+
+// NewEventBus 创建事件总线
+func NewEventBus() *EventBus {
+	return &EventBus{
+		subscribers: make(map[int]*subscriber),
+		history:     make(map[string][]model.Event),
+	}
+}
+
+// Emit 发布事件
+// 订阅者的缓冲区已满时丢弃该事件，避免慢速订阅者阻塞流程执行
+func (b *EventBus) Emit(event model.Event) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.record(event)
+	for _, sub := range b.subscribers {
+		if sub.filter != nil && !sub.filter(event) {
+			continue
+		}
+		select {
+		case sub.ch <- event:
+		default:
+		}
+	}
+}
+
+// Subscribe 订阅事件
+// filter 为空时接收全部事件，返回事件通道和取消订阅函数
+func (b *EventBus) Subscribe(filter func(model.Event) bool, buffer int) (<-chan model.Event, func()) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.subscribe(filter, buffer, nil)
+}
+
+// SubscribeExecution 订阅指定执行的事件
+// 先重放该执行已发布且满足过滤条件的事件，再接收后续事件，重放与订阅之间不会遗漏事件
+// 返回的replayed表示总线中是否保留有该执行的事件，为false时执行可能尚未开始或已结束较久
+func (b *EventBus) SubscribeExecution(executionID string, filter func(model.Event) bool, buffer int) (events <-chan model.Event, cancel func(), replayed bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	history, replayed := b.history[executionID]
+	var past []model.Event
+	for _, event := range history {
+		if filter == nil || filter(event) {
+			past = append(past, event)
+		}
+	}
+	events, cancel = b.subscribe(filter, buffer, past)
+	return events, cancel, replayed
+}
+
+// subscribe 注册订阅者并写入需要重放的事件，调用方需持有锁
+func (b *EventBus) subscribe(filter func(model.Event) bool, buffer int, past []model.Event) (<-chan model.Event, func()) {
+	if buffer <= 0 {
+		buffer = 256
+	}
+	b.nextID++
+	id := b.nextID
+	sub := &subscriber{ch: make(chan model.Event, buffer+len(past)), filter: filter}
+	for _, event := range past {
+		sub.ch <- event
+	}
+	b.subscribers[id] = sub
+
+	var once sync.Once
+	cancel := func() {
+		once.Do(func() {
+			b.mu.Lock()
+			defer b.mu.Unlock()
+			delete(b.subscribers, id)
+			close(sub.ch)
+		})
+	}
+	return sub.ch, cancel
+}
+
+// record 保留事件用于重放，每次执行只保留最近的事件，调用方需持有锁
+func (b *EventBus) record(event model.Event) {
+	if event.ExecutionID == "" {
+		return
+	}
+	history, ok := b.history[event.ExecutionID]
+	if !ok {
+		b.executions = append(b.executions, event.ExecutionID)
+		if len(b.executions) > historyExecutions {
+			delete(b.history, b.executions[0])
+			b.executions = b.executions[1:]
+		}
+	}
+	if len(history) >= historyEvents {
+		history = history[1:]
+	}
+	b.history[event.ExecutionID] = append(history, event)
+}
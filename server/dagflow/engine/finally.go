@@ -0,0 +1,73 @@
+package engine
+
+import (
+	"context"
+	"fmt"
+	"server/dagflow/model"
+	"time"
+)
+
+// finallyTimeout finally分支的最长执行时间
+// finally分支不受流程超时和取消的影响，需单独限制执行时间
+const finallyTimeout = 5 * time.Minute
+
+// runFinally 主流程结束后执行finally分支，流程成功、失败或被取消时都会执行
+// 主流程的执行结果保存在OutcomeDataKey数据中，供finally分支的节点使用
+func (x *execution) runFinally(ctx context.Context, flowErr error) error {
+	flow, execCtx := x.flow, x.execCtx
+	if flow.FinallyID == "" {
+		return nil
+	}
+
+	outcome := map[string]any{"status": model.Completed, "error": ""}
+	if flowErr != nil {
+		outcome["status"] = failureStatus(flowErr)
+		outcome["error"] = execCtx.Mask(flowErr.Error())
+	}
+	execCtx.SetData(model.OutcomeDataKey, outcome)
+
+	finallyCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), finallyTimeout)
+	defer cancel()
+
+	execCtx.Log("info", "开始执行finally分支")
+	err := x.runFrom(finallyCtx, flow.FinallyID)
+	if err != nil {
+		execCtx.FinallyStatus = failureStatus(err)
+		execCtx.FinallyError = execCtx.Mask(err.Error())
+		execCtx.Log("error", "finally分支执行失败: %v", err)
+		return fmt.Errorf("finally分支执行失败: %w", err)
+	}
+	execCtx.FinallyStatus = model.Completed
+	execCtx.Log("info", "finally分支执行完成")
+	return nil
+}
+
+// finishStatus 汇总主流程和finally分支的执行结果
+// 主流程失败时返回主流程的错误，finally分支的结果记录在执行上下文中；
+// 主流程成功但finally分支失败时流程按失败处理
+func (x *execution) finishStatus(flowErr, finallyErr error) (model.NodeExecutionStatus, error) {
+	if flowErr != nil {
+		return failureStatus(flowErr), flowErr
+	}
+	if finallyErr != nil {
+		return model.Failed, finallyErr
+	}
+	if x.execCtx.HandledFailures() > 0 {
+		return model.Recovered, nil
+	}
+	return model.Completed, nil
+}
+
+// checkFinally 检查finally节点不能从开始节点到达，避免被主流程执行
+func checkFinally(flow model.Flow) error {
+	if flow.FinallyID == "" {
+		return nil
+	}
+	if _, ok := flow.Nodes[flow.FinallyID]; !ok {
+		return fmt.Errorf("未找到finally节点: %s", flow.FinallyID)
+	}
+	if reachableNodes(flow, flow.StartNodeID)[flow.FinallyID] {
+		return fmt.Errorf("finally节点不能从开始节点到达")
+	}
+	return nil
+}
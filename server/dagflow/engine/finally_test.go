@@ -0,0 +1,122 @@
+package engine
+
+import (
+	"context"
+	"testing"
+
+	"server/dagflow/model"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newFinallyFlow 创建start -> a -> end的主流程，finally分支为f -> g
+func newFinallyFlow() model.Flow {
+	flow := newTestFlow([]string{"a", "f", "g"}, edge("start", "a"), edge("a", "end"), edge("f", "g"))
+	flow.FinallyID = "f"
+	return flow
+}
+
+// TestFinally 测试主流程成功或失败后都执行finally分支，并汇总主流程和finally分支的结果
+func TestFinally(t *testing.T) {
+	tests := []struct {
+		name        string
+		funcs       map[string]nodeFunc
+		wantErr     string
+		wantStatus  model.NodeExecutionStatus
+		wantOutcome map[string]any
+		wantFinally model.NodeExecutionStatus
+	}{
+		{
+			name:        "主流程成功",
+			wantStatus:  model.Completed,
+			wantOutcome: map[string]any{"status": model.Completed, "error": ""},
+			wantFinally: model.Completed,
+		},
+		{
+			name:        "主流程失败后仍执行finally",
+			funcs:       map[string]nodeFunc{"a": fail("a失败")},
+			wantErr:     "a失败",
+			wantStatus:  model.Failed,
+			wantOutcome: map[string]any{"status": model.Failed, "error": "a失败"},
+			wantFinally: model.Completed,
+		},
+		{
+			name:        "主流程成功但finally失败",
+			funcs:       map[string]nodeFunc{"g": fail("g失败")},
+			wantErr:     "finally分支执行失败: g失败",
+			wantStatus:  model.Failed,
+			wantOutcome: map[string]any{"status": model.Completed, "error": ""},
+			wantFinally: model.Failed,
+		},
+		{
+			name:        "主流程和finally都失败时返回主流程的错误",
+			funcs:       map[string]nodeFunc{"a": fail("a失败"), "g": fail("g失败")},
+			wantErr:     "a失败",
+			wantStatus:  model.Failed,
+			wantOutcome: map[string]any{"status": model.Failed, "error": "a失败"},
+			wantFinally: model.Failed,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// finally分支的节点在执行时读取主流程的结果
+			var outcome any
+			funcs := map[string]nodeFunc{
+				"f": func(ctx context.Context, execCtx *model.ExecutionContext) (any, error) {
+					outcome, _ = execCtx.GetData(model.OutcomeDataKey)
+					return "f", nil
+				},
+			}
+			for id, fn := range tt.funcs {
+				funcs[id] = fn
+			}
+			e, h := newTestEngine(funcs)
+
+			execCtx, err := runTestFlow(t, e, newFinallyFlow())
+			if tt.wantErr != "" {
+				assert.EqualError(t, err, tt.wantErr)
+			} else {
+				assert.NoError(t, err)
+			}
+			assert.Equal(t, tt.wantStatus, execCtx.Status)
+			assert.Equal(t, tt.wantOutcome, outcome)
+			assert.Equal(t, tt.wantFinally, execCtx.FinallyStatus)
+			assert.Equal(t, 1, h.count("f"))
+			assert.Equal(t, 1, h.count("g"))
+		})
+	}
+}
+
+// TestFinallyAfterCancel 测试流程被取消后finally分支不受取消影响继续执行
+func TestFinallyAfterCancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	e, h := newTestEngine(map[string]nodeFunc{
+		"a": func(nodeCtx context.Context, execCtx *model.ExecutionContext) (any, error) {
+			cancel()
+			<-nodeCtx.Done()
+			return nil, nodeCtx.Err()
+		},
+	})
+	flow := newFinallyFlow()
+	execCtx := model.NewExecutionContext(flow.ID, nil, nil)
+
+	execCtx, err := e.Run(ctx, flow, execCtx)
+	require.ErrorIs(t, err, context.Canceled)
+	assert.Equal(t, model.Failed, execCtx.Status)
+	assert.Equal(t, model.Completed, execCtx.FinallyStatus)
+	assert.Equal(t, 1, h.count("g"))
+}
+
+// TestCheckFinally 测试finally节点必须存在且不能从开始节点到达
+func TestCheckFinally(t *testing.T) {
+	flow := newFinallyFlow()
+	assert.NoError(t, checkFinally(flow))
+
+	flow.FinallyID = "missing"
+	assert.EqualError(t, checkFinally(flow), "未找到finally节点: missing")
+
+	flow = newTestFlow([]string{"a"}, edge("start", "a"), edge("a", "end"))
+	flow.FinallyID = "a"
+	assert.EqualError(t, checkFinally(flow), "finally节点不能从开始节点到达")
+}
@@ -0,0 +1,102 @@
+package engine
+
+import (
+	"context"
+	"server/dagflow/handler"
+	"server/dagflow/model"
+)
+
+// NodeInterceptor 节点执行前的拦截函数
+// 返回错误时节点不再执行，流程终止，不转入节点的错误分支
+type NodeInterceptor func(ctx context.Context, node model.TaskNode, execCtx *model.ExecutionContext) error
+
+// NodeObserver 节点执行结束后的回调，节点的状态和结果已保存到执行上下文
+type NodeObserver func(node model.TaskNode, execCtx *model.ExecutionContext)
+
+// HandlerOverride 替换节点处理器的函数
+// 返回nil时使用注册表中的处理器，用于在不修改注册表的情况下注入模拟处理器
+type HandlerOverride func(node model.TaskNode) handler.TaskHandler
+
+// RunOption 单次流程执行的可选配置
+type RunOption func(*runOptions)
+
+// runOptions 单次流程执行的配置
+type runOptions struct {
+	beforeNode NodeInterceptor // 节点执行前的拦截函数
+	afterNode  NodeObserver    // 节点执行结束后的回调
+	workers    int             // 并发节点数，为0时使用流程配置
+	restore    bool            // 是否从执行上下文中已保存的节点状态恢复执行
+	override   HandlerOverride // 替换节点处理器的函数
+}
+
+// WithBeforeNode 设置节点执行前的拦截函数，用于断点调试等场景
+func WithBeforeNode(interceptor NodeInterceptor) RunOption {
+	return func(o *runOptions) {
+		o.beforeNode = interceptor
+	}
+}
+
+// WithAfterNode 设置节点执行结束后的回调，用于在节点完成后保存执行状态等场景
+func WithAfterNode(observer NodeObserver) RunOption {
+	return func(o *runOptions) {
+		o.afterNode = observer
+	}
+}
+
+// WithWorkers 设置单次执行的并发节点数，覆盖流程配置
+func WithWorkers(workers int) RunOption {
+	return func(o *runOptions) {
+		o.workers = workers
+	}
+}
+
+// WithRestore 从执行上下文中已保存的节点状态恢复执行
+// 已完成的节点不再执行，直接使用保存的数据继续调度；已失败的节点按原错误重新转入错误分支
+func WithRestore() RunOption {
+	return func(o *runOptions) {
+		o.restore = true
+	}
+}
+
+// WithHandlerOverride 设置替换节点处理器的函数，用于流程测试中模拟节点结果
+func WithHandlerOverride(override HandlerOverride) RunOption {
+	return func(o *runOptions) {
+		o.override = override
+	}
+}
+
+// execution 单次流程执行的运行状态
+type execution struct {
+	engine   *Engine
+	flow     model.Flow
+	execCtx  *model.ExecutionContext
+	options  runOptions
+	restored map[string]model.NodeExecutionStatus // 恢复执行时保存的节点状态
+}
+
+// newExecution 创建单次流程执行
+func (e *Engine) newExecution(flow model.Flow, execCtx *model.ExecutionContext, opts []RunOption) *execution {
+	x := &execution{engine: e, flow: flow, execCtx: execCtx}
+	for _, opt := range opts {
+		opt(&x.options)
+	}
+	if x.options.restore {
+		x.restored = make(map[string]model.NodeExecutionStatus)
+		for nodeID := range flow.Nodes {
+			if status := execCtx.GetNodeStatus(nodeID); status == model.Completed || status == model.Failed {
+				x.restored[nodeID] = status
+			}
+		}
+	}
+	return x
+}
+
+// handler 获取节点的处理器，优先使用替换的处理器
+func (x *execution) handler(node model.TaskNode) (handler.TaskHandler, error) {
+	if x.options.override != nil {
+		if taskHandler := x.options.override(node); taskHandler != nil {
+			return taskHandler, nil
+		}
+	}
+	return x.engine.handlerRegistry.Get(node.Type)
+}
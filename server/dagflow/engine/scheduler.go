@@ -0,0 +1,292 @@
+package engine
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"server/dagflow/model"
+	"sync"
+)
+
+// DefaultWorkers 单次执行默认的并发节点数
+const DefaultWorkers = 4
+
+// scheduler 基于就绪队列的节点调度器
+// 节点的所有入边都确定后进入就绪队列，由固定数量的工作协程执行；
+// 入边条件均不成立的节点标记为跳过，并继续向后续节点传播（死路径消除）
+type scheduler struct {
+	x       *execution
+	ctx     context.Context
+	start   string          // 调度的起始节点
+	ready   chan string     // 就绪队列
+	pending sync.WaitGroup  // 已入队但尚未处理完的节点
+	mu      sync.Mutex      // 保护以下字段
+	waiting map[string]int  // 节点尚未确定的入边数量
+	taken   map[string]bool // 节点是否存在条件成立的入边
+	err     error           // 导致流程终止的首个错误
+}
+
+// run 从开始节点调度执行整个流程
+func (x *execution) run(ctx context.Context) error {
+	return x.runFrom(ctx, x.flow.StartNodeID)
+}
+
+// runFrom 从指定节点调度执行其可达的所有节点
+func (x *execution) runFrom(ctx context.Context, start string) error {
+	flow, execCtx := x.flow, x.execCtx
+	if _, ok := flow.Nodes[start]; !ok {
+		return fmt.Errorf("未找到节点: %s", start)
+	}
+
+	s := &scheduler{
+		x:       x,
+		ctx:     ctx,
+		start:   start,
+		ready:   make(chan string, len(flow.Nodes)+len(flow.Edges)+1),
+		waiting: make(map[string]int),
+		taken:   make(map[string]bool),
+	}
+
+	// 只统计从起始节点可达的入边，不可达的分支不会阻塞汇合节点
+	reachable := reachableNodes(flow, start)
+	for _, edge := range flow.Edges {
+		if reachable[edge.Source] && edge.Target != start {
+			s.waiting[edge.Target]++
+		}
+	}
+
+	s.enqueue(start)
+	go func() {
+		s.pending.Wait()
+		close(s.ready)
+	}()
+
+	workers := x.workers()
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for nodeID := range s.ready {
+				s.process(nodeID)
+				s.pending.Done()
+			}
+		}()
+	}
+	wg.Wait()
+
+	if s.err != nil {
+		return s.err
+	}
+	for nodeID, count := range s.waiting {
+		if count > 0 {
+			execCtx.Log("warn", "节点 %s 的前置节点未全部完成，未被执行（流程中可能存在环）", nodeID)
+		}
+	}
+	return nil
+}
+
+// workers 获取单次执行的并发节点数，优先使用执行参数，其次使用流程配置
+func (x *execution) workers() int {
+	if x.options.workers > 0 {
+		return x.options.workers
+	}
+	if x.flow.Workers > 0 {
+		return x.flow.Workers
+	}
+	return DefaultWorkers
+}
+
+// process 处理就绪队列中的节点
+func (s *scheduler) process(nodeID string) {
+	x := s.x
+	flow, execCtx := x.flow, x.execCtx
+
+	// 流程已失败时不再执行新的节点
+	if s.failed() {
+		return
+	}
+	// 流程已超时或被取消时不再执行新的节点
+	if s.ctx.Err() != nil {
+		s.fail(contextError(s.ctx))
+		return
+	}
+
+	node, ok := flow.Nodes[nodeID]
+	if !ok {
+		s.fail(fmt.Errorf("未找到节点: %s", nodeID))
+		return
+	}
+
+	switch {
+	case nodeID != s.start && !s.isTaken(nodeID):
+		// 所有入边条件均不成立，跳过该节点及其后续分支
+		execCtx.SetNodeStatus(nodeID, model.Skipped)
+		execCtx.Log("info", "节点 %s 被跳过(入边条件均不成立)", node.Name)
+		for _, edge := range findOutgoingEdges(flow, nodeID) {
+			s.resolve(edge.Target, false)
+		}
+		return
+	case node.IsDisabled():
+		execCtx.SetNodeStatus(nodeID, model.Skipped)
+		execCtx.Log("info", "节点 %s 已被禁用，跳过执行", node.Name)
+	case x.restored[nodeID] == model.Completed:
+		// 恢复执行时已完成的节点不再执行
+		execCtx.Log("info", "节点 %s 已在恢复前完成", node.Name)
+	case x.restored[nodeID] == model.Failed:
+		// 恢复执行时已失败的节点按原错误转入错误分支
+		message, _ := execCtx.NodeErrorMessage(nodeID)
+		if !s.handleFailure(node, errors.New(message), false) {
+			s.fail(errors.New(message))
+		}
+		return
+	default:
+		if err := x.executeNode(s.ctx, node); err != nil {
+			// 记录失败信息后再回调，回调中保存的执行状态包含错误分支使用的数据
+			handled := s.handleFailure(node, err, true)
+			s.afterNode(node)
+			if !handled {
+				s.fail(err)
+			}
+			return
+		}
+		s.afterNode(node)
+	}
+
+	// 结束节点之后不再调度
+	if nodeID == flow.EndNodeID {
+		return
+	}
+
+	// 计算后续连线条件
+	nextEdges := findOutgoingEdges(flow, nodeID)
+	if len(nextEdges) == 0 {
+		execCtx.Log("warn", "节点 %s 没有后续节点", nodeID)
+		return
+	}
+	for _, edge := range nextEdges {
+		// 节点执行成功，错误分支不执行
+		if edge.Kind == model.EdgeError {
+			s.resolve(edge.Target, false)
+			continue
+		}
+		taken, err := x.evaluateEdge(edge)
+		if err != nil {
+			s.fail(err)
+			return
+		}
+		s.resolve(edge.Target, taken)
+	}
+}
+
+// handleFailure 节点失败时转入错误分支，节点没有错误分支时返回false由流程终止
+// 失败信息保存在ErrorDataKey数据中，正常连线的后续分支被跳过；record为false时不重复记录失败
+func (s *scheduler) handleFailure(node model.TaskNode, err error, record bool) bool {
+	x := s.x
+	execCtx := x.execCtx
+	edges := findOutgoingEdges(x.flow, node.ID)
+	hasErrorEdge := false
+	for _, edge := range edges {
+		if edge.Kind == model.EdgeError {
+			hasErrorEdge = true
+			break
+		}
+	}
+	// 节点被中断或流程已超时、被取消时不再转入错误分支
+	var interrupted interruptError
+	handled := hasErrorEdge && s.ctx.Err() == nil && !errors.As(err, &interrupted)
+	if record {
+		execCtx.AddFailure(model.NodeFailure{NodeID: node.ID, NodeName: node.Name, Message: err.Error(), Handled: handled})
+	}
+	if !handled {
+		return false
+	}
+
+	execCtx.SetData(model.ErrorDataKey, map[string]any{
+		"nodeId":   node.ID,
+		"nodeName": node.Name,
+		"message":  execCtx.Mask(err.Error()),
+	})
+	execCtx.Log("warn", "节点 %s 执行失败，转入错误分支", node.Name)
+	for _, edge := range edges {
+		if edge.Kind != model.EdgeError {
+			s.resolve(edge.Target, false)
+			continue
+		}
+		taken, evalErr := x.evaluateEdge(edge)
+		if evalErr != nil {
+			s.fail(evalErr)
+			return true
+		}
+		s.resolve(edge.Target, taken)
+	}
+	return true
+}
+
+// afterNode 节点执行结束后调用回调
+func (s *scheduler) afterNode(node model.TaskNode) {
+	if s.x.options.afterNode != nil {
+		s.x.options.afterNode(node, s.x.execCtx)
+	}
+}
+
+// resolve 确定一条入边的条件结果，节点的入边全部确定后进入就绪队列
+func (s *scheduler) resolve(nodeID string, taken bool) {
+	s.mu.Lock()
+	if taken {
+		s.taken[nodeID] = true
+	}
+	s.waiting[nodeID]--
+	ready := s.waiting[nodeID] == 0
+	s.mu.Unlock()
+
+	if ready {
+		s.enqueue(nodeID)
+	}
+}
+
+// enqueue 将节点加入就绪队列
+func (s *scheduler) enqueue(nodeID string) {
+	s.pending.Add(1)
+	s.ready <- nodeID
+}
+
+// isTaken 检查节点是否存在条件成立的入边
+func (s *scheduler) isTaken(nodeID string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.taken[nodeID]
+}
+
+// fail 记录导致流程终止的错误，只保留第一个错误
+func (s *scheduler) fail(err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.err == nil {
+		s.err = err
+	}
+}
+
+// failed 检查流程是否已失败
+func (s *scheduler) failed() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.err != nil
+}
+
+// reachableNodes 获取从起始节点可达的所有节点
+func reachableNodes(flow model.Flow, start string) map[string]bool {
+	reachable := map[string]bool{start: true}
+	queue := []string{start}
+	for len(queue) > 0 {
+		nodeID := queue[0]
+		queue = queue[1:]
+		for _, edge := range findOutgoingEdges(flow, nodeID) {
+			if !reachable[edge.Target] {
+				reachable[edge.Target] = true
+				queue = append(queue, edge.Target)
+			}
+		}
+	}
+	return reachable
+}
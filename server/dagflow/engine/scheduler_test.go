@@ -0,0 +1,169 @@
+package engine
+
+import (
+	"context"
+	"errors"
+	"server/dagflow/model"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestParallelFanOutJoin 测试并行分支同时执行，汇合节点在所有分支完成后只执行一次
+func TestParallelFanOutJoin(t *testing.T) {
+	branches := []string{"a", "b", "c"}
+	var started sync.WaitGroup
+	started.Add(len(branches))
+	allStarted := make(chan struct{})
+	go func() {
+		started.Wait()
+		close(allStarted)
+	}()
+	var running, maxRunning atomic.Int32
+	branch := func(ctx context.Context, execCtx *model.ExecutionContext) (any, error) {
+		n := running.Add(1)
+		defer running.Add(-1)
+		for {
+			old := maxRunning.Load()
+			if n <= old || maxRunning.CompareAndSwap(old, n) {
+				break
+			}
+		}
+		started.Done()
+		// 等待所有分支都已开始，分支串行执行时会超时
+		select {
+		case <-allStarted:
+			return "ok", nil
+		case <-time.After(2 * time.Second):
+			return nil, errors.New("分支没有并行执行")
+		}
+	}
+	var joinSaw []model.NodeExecutionStatus
+	funcs := map[string]nodeFunc{
+		"a": branch, "b": branch, "c": branch,
+		"join": func(ctx context.Context, execCtx *model.ExecutionContext) (any, error) {
+			for _, id := range branches {
+				joinSaw = append(joinSaw, execCtx.GetNodeStatus(id))
+			}
+			return "joined", nil
+		},
+	}
+	e, h := newTestEngine(funcs)
+	flow := newTestFlow([]string{"a", "b", "c", "join"},
+		edge("start", "a"), edge("start", "b"), edge("start", "c"),
+		edge("a", "join"), edge("b", "join"), edge("c", "join"),
+		edge("join", "end"))
+
+	execCtx, err := runTestFlow(t, e, flow, WithWorkers(4))
+	require.NoError(t, err)
+	assert.Equal(t, model.Completed, execCtx.Status)
+	assert.Equal(t, int32(3), maxRunning.Load())
+	assert.Equal(t, 1, h.count("join"))
+	assert.Equal(t, []model.NodeExecutionStatus{model.Completed, model.Completed, model.Completed}, joinSaw)
+	result, _ := execCtx.GetData("join")
+	assert.Equal(t, "joined", result)
+}
+
+// TestConditionalJoin 测试条件分支不成立时跳过该分支，汇合节点仍在其余分支完成后执行
+func TestConditionalJoin(t *testing.T) {
+	e, h := newTestEngine(nil)
+	skipped := edge("start", "b")
+	skipped.Expression = "false"
+	flow := newTestFlow([]string{"a", "b", "b2", "join"},
+		edge("start", "a"), skipped,
+		edge("a", "join"), edge("b", "b2"), edge("b2", "join"),
+		edge("join", "end"))
+
+	execCtx, err := runTestFlow(t, e, flow, WithWorkers(1))
+	require.NoError(t, err)
+	assert.Equal(t, model.Completed, execCtx.Status)
+	assertNodeStatus(t, execCtx, map[string]model.NodeExecutionStatus{
+		"a": model.Completed, "b": model.Skipped, "b2": model.Skipped, "join": model.Completed, "end": model.Completed,
+	})
+	assert.Equal(t, 0, h.count("b"))
+	assert.Equal(t, 1, h.count("join"))
+}
+
+// TestErrorEdgeRouting 测试节点失败时转入错误分支，没有错误分支时流程失败
+func TestErrorEdgeRouting(t *testing.T) {
+	tests := []struct {
+		name       string
+		edges      []model.Edge
+		wantErr    bool
+		wantStatus model.NodeExecutionStatus
+		wantNodes  map[string]model.NodeExecutionStatus
+		wantError  bool // 是否保存了错误分支使用的失败信息
+	}{
+		{
+			name:       "转入错误分支",
+			edges:      []model.Edge{edge("start", "b"), edge("b", "c"), errorEdge("b", "h"), edge("c", "end"), edge("h", "end")},
+			wantStatus: model.Recovered,
+			wantNodes: map[string]model.NodeExecutionStatus{
+				"b": model.Failed, "c": model.Skipped, "h": model.Completed, "end": model.Completed,
+			},
+			wantError: true,
+		},
+		{
+			name:       "错误分支条件成立",
+			edges:      []model.Edge{edge("start", "b"), edge("b", "c"), {Source: "b", Target: "h", Kind: model.EdgeError, Expression: `error.nodeId == "b"`}, edge("c", "end"), edge("h", "end")},
+			wantStatus: model.Recovered,
+			wantNodes: map[string]model.NodeExecutionStatus{
+				"b": model.Failed, "c": model.Skipped, "h": model.Completed, "end": model.Completed,
+			},
+			wantError: true,
+		},
+		{
+			name:       "没有错误分支",
+			edges:      []model.Edge{edge("start", "b"), edge("b", "c"), edge("c", "end")},
+			wantErr:    true,
+			wantStatus: model.Failed,
+			wantNodes: map[string]model.NodeExecutionStatus{
+				"b": model.Failed, "c": model.Pending,
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e, h := newTestEngine(map[string]nodeFunc{"b": fail("b失败")})
+			flow := newTestFlow([]string{"b", "c", "h"}, tt.edges...)
+
+			execCtx, err := runTestFlow(t, e, flow)
+			if tt.wantErr {
+				assert.EqualError(t, err, "b失败")
+			} else {
+				assert.NoError(t, err)
+			}
+			assert.Equal(t, tt.wantStatus, execCtx.Status)
+			assertNodeStatus(t, execCtx, tt.wantNodes)
+			assert.Equal(t, 0, h.count("c"))
+
+			require.Len(t, execCtx.Failures, 1)
+			assert.Equal(t, "b", execCtx.Failures[0].NodeID)
+			assert.Equal(t, tt.wantError, execCtx.Failures[0].Handled)
+			data, ok := execCtx.GetData(model.ErrorDataKey)
+			assert.Equal(t, tt.wantError, ok)
+			if tt.wantError {
+				assert.Equal(t, map[string]any{"nodeId": "b", "nodeName": "b", "message": "b失败"}, data)
+			}
+		})
+	}
+}
+
+// TestErrorEdgeNotTakenOnSuccess 测试节点成功时不执行错误分支
+func TestErrorEdgeNotTakenOnSuccess(t *testing.T) {
+	e, h := newTestEngine(nil)
+	flow := newTestFlow([]string{"b", "c", "h"},
+		edge("start", "b"), edge("b", "c"), errorEdge("b", "h"), edge("c", "end"), edge("h", "end"))
+
+	execCtx, err := runTestFlow(t, e, flow)
+	require.NoError(t, err)
+	assert.Equal(t, model.Completed, execCtx.Status)
+	assertNodeStatus(t, execCtx, map[string]model.NodeExecutionStatus{
+		"b": model.Completed, "c": model.Completed, "h": model.Skipped, "end": model.Completed,
+	})
+	assert.Equal(t, 0, h.count("h"))
+}
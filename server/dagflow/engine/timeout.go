@@ -0,0 +1,148 @@
+package engine
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"server/dagflow/handler"
+	"server/dagflow/model"
+	"sync"
+	"time"
+)
+
+// ErrTimeout 执行超时错误，节点超时和流程超时均包装该错误
+var ErrTimeout = errors.New("执行超时")
+
+// leakGracePeriod 超时后等待处理器退出的时间，超过该时间仍未退出视为协程泄漏
+var leakGracePeriod = 10 * time.Second
+
+// LeakedHandler 超时后未响应上下文取消的节点处理器
+type LeakedHandler struct {
+	FlowID      uint      `json:"flowId"`      // 流程ID
+	ExecutionID string    `json:"executionId"` // 执行ID
+	NodeID      string    `json:"nodeId"`      // 节点ID
+	NodeName    string    `json:"nodeName"`    // 节点名称
+	NodeType    string    `json:"nodeType"`    // 节点类型
+	TimeoutAt   time.Time `json:"timeoutAt"`   // 超时时间
+}
+
+// leakRegistry 泄漏的节点处理器记录，处理器最终退出后移除
+type leakRegistry struct {
+	handlers sync.Map
+}
+
+// list 获取当前泄漏的节点处理器
+func (r *leakRegistry) list() []LeakedHandler {
+	handlers := make([]LeakedHandler, 0)
+	r.handlers.Range(func(_, value any) bool {
+		handlers = append(handlers, value.(LeakedHandler))
+		return true
+	})
+	return handlers
+}
+
+// LeakedHandlers 获取超时后仍未退出的节点处理器
+func (e *Engine) LeakedHandlers() []LeakedHandler {
+	return e.leaks.list()
+}
+
+// handlerResult 节点处理器的执行结果
+type handlerResult struct {
+	result any
+	err    error
+}
+
+// runHandler 在独立协程中执行节点处理器，按节点超时时间和上层上下文控制执行时长
+// 处理器未在超时后及时退出时记录为泄漏
+func (x *execution) runHandler(ctx context.Context, taskHandler handler.TaskHandler, node model.TaskNode) (any, error) {
+	var nodeCtx context.Context
+	var cancel context.CancelFunc
+	if node.Timeout > 0 {
+		nodeCtx, cancel = context.WithTimeout(ctx, time.Duration(node.Timeout)*time.Second)
+	} else {
+		nodeCtx, cancel = context.WithCancel(ctx)
+	}
+	defer cancel()
+
+	done := make(chan handlerResult, 1)
+	go func() {
+		defer func() {
+			if r := recover(); r != nil {
+				done <- handlerResult{err: fmt.Errorf("节点处理器异常: %v", r)}
+			}
+		}()
+		result, err := taskHandler.Handle(nodeCtx, node, x.execCtx)
+		done <- handlerResult{result: result, err: err}
+	}()
+
+	select {
+	case r := <-done:
+		return handlerOutcome(ctx, nodeCtx, node, r)
+	case <-nodeCtx.Done():
+	}
+
+	// 处理器与超时同时结束时以处理器结果为准
+	select {
+	case r := <-done:
+		return handlerOutcome(ctx, nodeCtx, node, r)
+	default:
+	}
+
+	go x.watchLeak(node, done)
+	return nil, timeoutError(ctx, node)
+}
+
+// handlerOutcome 获取已退出的处理器的结果
+// 处理器响应超时或取消后返回错误时，按节点超时或流程中断处理，而不是普通的执行失败
+func handlerOutcome(ctx, nodeCtx context.Context, node model.TaskNode, r handlerResult) (any, error) {
+	if r.err != nil && nodeCtx.Err() != nil {
+		return nil, timeoutError(ctx, node)
+	}
+	return r.result, r.err
+}
+
+// timeoutError 根据上下文状态生成节点中断的错误
+func timeoutError(ctx context.Context, node model.TaskNode) error {
+	switch {
+	case errors.Is(ctx.Err(), context.DeadlineExceeded):
+		return fmt.Errorf("%w: 流程执行超时，节点 %s 被终止", ErrTimeout, node.Name)
+	case ctx.Err() != nil:
+		return context.Cause(ctx)
+	default:
+		return fmt.Errorf("%w: 节点 %s 超过 %d 秒未完成", ErrTimeout, node.Name, node.Timeout)
+	}
+}
+
+// contextError 流程上下文结束后返回对应的错误
+func contextError(ctx context.Context) error {
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return fmt.Errorf("%w: 流程执行超时", ErrTimeout)
+	}
+	return context.Cause(ctx)
+}
+
+// watchLeak 等待超时的处理器退出，超过等待时间仍未退出时记录为泄漏
+func (x *execution) watchLeak(node model.TaskNode, done <-chan handlerResult) {
+	execCtx := x.execCtx
+	select {
+	case <-done:
+		return
+	case <-time.After(leakGracePeriod):
+	}
+
+	key := execCtx.ExecutionID + "/" + node.ID
+	x.engine.leaks.handlers.Store(key, LeakedHandler{
+		FlowID:      execCtx.FlowID,
+		ExecutionID: execCtx.ExecutionID,
+		NodeID:      node.ID,
+		NodeName:    node.Name,
+		NodeType:    node.Type,
+		TimeoutAt:   time.Now().Add(-leakGracePeriod),
+	})
+	execCtx.Log("warn", "节点 %s (%s) 的处理器超时后未响应取消，可能存在协程泄漏", node.Name, node.Type)
+	x.engine.logger.Warn("节点处理器协程泄漏: flow=%d, execution=%s, node=%s, type=%s", execCtx.FlowID, execCtx.ExecutionID, node.ID, node.Type)
+
+	<-done
+	x.engine.leaks.handlers.Delete(key)
+	x.engine.logger.Info("泄漏的节点处理器已退出: execution=%s, node=%s", execCtx.ExecutionID, node.ID)
+}
@@ -0,0 +1,35 @@
+package engine
+
+import (
+	"context"
+	"server/dagflow/model"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestNodeTimeout 测试节点超过超时时间后标记为超时，流程按超时失败
+func TestNodeTimeout(t *testing.T) {
+	e, _ := newTestEngine(map[string]nodeFunc{
+		"slow": func(ctx context.Context, execCtx *model.ExecutionContext) (any, error) {
+			<-ctx.Done()
+			return nil, ctx.Err()
+		},
+	})
+	flow := newTestFlow([]string{"slow", "next"}, edge("start", "slow"), edge("slow", "next"), edge("next", "end"))
+	node := flow.Nodes["slow"]
+	node.Timeout = 1
+	flow.Nodes["slow"] = node
+
+	begin := time.Now()
+	execCtx, err := runTestFlow(t, e, flow)
+	assert.Less(t, time.Since(begin), 3*time.Second)
+	require.Error(t, err)
+	assert.ErrorIs(t, err, ErrTimeout)
+	assert.Equal(t, model.TimedOut, execCtx.Status)
+	assertNodeStatus(t, execCtx, map[string]model.NodeExecutionStatus{"slow": model.TimedOut, "next": model.Pending})
+	message, _ := execCtx.NodeErrorMessage("slow")
+	assert.Contains(t, message, "超过 1 秒未完成")
+}
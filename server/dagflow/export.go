@@ -0,0 +1,47 @@
+package dagflow
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"server/dagflow/diagram"
+	"server/dagflow/model"
+	"server/service/sflow"
+)
+
+// ExportFlow 将流程导出为Graphviz DOT或Mermaid文本
+// executionID不为空时使用该次执行的流程版本，并叠加节点的执行状态和耗时
+func (s *Service) ExportFlow(flowID, format, executionID string) (string, error) {
+	if executionID == "" {
+		flow, err := s.loadFlow(flowID)
+		if err != nil {
+			return "", err
+		}
+		return diagram.Export(flow, format, nil)
+	}
+
+	record, err := sflow.SFlowLog{}.FindByExecution(executionID)
+	if err != nil {
+		return "", fmt.Errorf("查询执行记录失败: %v", err)
+	}
+	if flowID != "" && fmt.Sprint(record.SFlowId) != flowID {
+		return "", fmt.Errorf("执行 %s 不属于流程 %s", executionID, flowID)
+	}
+	if record.NodeStates == "" {
+		return "", errors.New("执行尚未结束或没有记录节点状态")
+	}
+	var overlay diagram.Overlay
+	if err := json.Unmarshal([]byte(record.NodeStates), &overlay); err != nil {
+		return "", fmt.Errorf("解析节点状态失败: %v", err)
+	}
+	var flow model.Flow
+	if record.Version != "" {
+		flow, err = s.replayFlow(record, false)
+	} else {
+		flow, err = s.loadFlow(fmt.Sprint(record.SFlowId))
+	}
+	if err != nil {
+		return "", err
+	}
+	return diagram.Export(flow, format, overlay)
+}
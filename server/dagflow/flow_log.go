@@ -0,0 +1,144 @@
+package dagflow
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"server/dagflow/diagram"
+	"server/dagflow/model"
+	"server/service/notify"
+	"server/service/sflow"
+	"server/utils/global"
+	"server/utils/logger"
+	"strings"
+	"time"
+)
+
+// startFlowLog 记录流程开始执行，同时保存流程版本和输入参数用于回放，数据库未初始化时返回nil
+func startFlowLog(flow model.Flow, execCtx *model.ExecutionContext) *sflow.SFlowLog {
+	if global.DB == nil {
+		return nil
+	}
+	flowLog := &sflow.SFlowLog{
+		ExecutionID: execCtx.ExecutionID,
+		ReplayOf:    execCtx.ReplayOf,
+		Dry:         execCtx.Dry,
+	}
+	if content, err := json.Marshal(flow); err == nil {
+		flowLog.Version, err = sflow.SFlowVersion{}.Ensure(flow.ID, string(content))
+		if err != nil {
+			flowLog.Version = ""
+		}
+	}
+	if params, err := json.Marshal(execCtx.Params); err == nil {
+		flowLog.Params = string(params)
+	}
+	_, logPath, err := flowLog.StartLogFile(logSFlow(flow))
+	if err != nil {
+		return nil
+	}
+	// 执行日志实时写入日志文件，用于查看执行中的日志
+	if file, err := os.OpenFile(logPath, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0666); err == nil {
+		execCtx.SetLogWriter(file)
+	}
+	return flowLog
+}
+
+// finishFlowLog 记录流程执行结果，日志内容已在执行上下文中脱敏
+func finishFlowLog(flowLog *sflow.SFlowLog, flow model.Flow, execCtx *model.ExecutionContext, err error) {
+	if flowLog == nil || execCtx == nil {
+		return
+	}
+	saveNodes(flowLog, execCtx)
+	execCtx.CloseLogWriter()
+	defer func() { go pruneFlowLogs(flow.ID) }()
+	// 执行日志已写入日志文件，日志记录中只保存执行结果
+	logs := []string{}
+	if err != nil {
+		flowLog.Error(logSFlow(flow), append(logs, "ERROR: "+execCtx.Mask(err.Error())))
+		publishFlowFailed(flow, execCtx, err)
+		return
+	}
+	flowLog.Success(logSFlow(flow), append(logs, "SUCCESS!"))
+}
+
+// finishedEvent 按执行日志构造已结束执行的结束事件，执行中、未找到或不属于该流程时返回false
+func finishedEvent(flowID uint, executionID string) (model.Event, bool) {
+	if global.DB == nil {
+		return model.Event{}, false
+	}
+	record, err := sflow.SFlowLog{}.FindByExecution(executionID)
+	if err != nil || record.SFlowId != flowID || record.Status == 0 {
+		return model.Event{}, false
+	}
+	event := model.Event{
+		Type:        model.EventFlowFinished,
+		FlowID:      flowID,
+		ExecutionID: executionID,
+		Status:      model.Completed,
+		Time:        record.EndTime.Time,
+	}
+	if record.Status < 0 {
+		event.Status = model.Failed
+		event.Message = strings.TrimPrefix(record.LogText, "ERROR: ")
+	}
+	return event, true
+}
+
+// pruneFlowLogs 清理流程超出保留数量的执行日志
+func pruneFlowLogs(flowID uint) {
+	sFlow, err := sflow.SFlow{}.Load(flowID)
+	if err != nil || sFlow.LogKeepNum <= 0 {
+		return
+	}
+	if _, err := (sflow.SFlowLog{}).Prune(flowID, sFlow.LogKeepNum); err != nil {
+		logger.LOG.Errorf("清理流程 %d 的执行日志失败: %v", flowID, err)
+	}
+}
+
+// saveNodes 保存节点的结果和错误，演练回放时作为有副作用节点的结果；同时保存节点状态和耗时
+func saveNodes(flowLog *sflow.SFlowLog, execCtx *model.ExecutionContext) {
+	results := make(map[string]TestMock)
+	for nodeID, status := range execCtx.NodeStatus {
+		switch status {
+		case model.Completed:
+			results[nodeID] = TestMock{Result: execCtx.NodeResults[nodeID]}
+		case model.Failed, model.TimedOut:
+			results[nodeID] = TestMock{Error: execCtx.NodeErrors[nodeID]}
+		}
+	}
+	content, err := json.Marshal(results)
+	if err != nil {
+		return
+	}
+	states, err := json.Marshal(diagram.OverlayOf(execCtx))
+	if err != nil {
+		return
+	}
+	flowLog.SaveNodes(execCtx.Mask(string(content)), string(states))
+}
+
+// publishFlowFailed 发布流程执行失败事件
+func publishFlowFailed(flow model.Flow, execCtx *model.ExecutionContext, err error) {
+	message := execCtx.Mask(err.Error())
+	notify.Publish(flow.ID, notify.Message{
+		Event:   notify.EventSFlowFailed,
+		Level:   "error",
+		Title:   fmt.Sprintf("作业流程执行失败: %s", flow.Name),
+		Content: fmt.Sprintf("流程: %s(%d)\n执行ID: %s\n错误: %s", flow.Name, flow.ID, execCtx.ExecutionID, message),
+		Data: map[string]any{
+			"flowId":      flow.ID,
+			"flowName":    flow.Name,
+			"executionId": execCtx.ExecutionID,
+			"error":       message,
+		},
+		Time: time.Now(),
+	})
+}
+
+// logSFlow 构造写入流程日志所需的SFlow
+func logSFlow(flow model.Flow) sflow.SFlow {
+	sFlow := sflow.SFlow{Name: flow.Name}
+	sFlow.ID = flow.ID
+	return sFlow
+}
@@ -19,6 +19,22 @@ type TaskHandler interface {
 	Validate(node model.TaskNode) error
 }
 
+// SideEffecter 有副作用的处理器可实现该接口，演练回放时该类型的节点使用原执行记录的结果代替执行
+type SideEffecter interface {
+	SideEffect() bool
+}
+
+// HasSideEffect 判断节点是否有副作用，节点标记或处理器声明有副作用时返回true
+func HasSideEffect(handler TaskHandler, node model.TaskNode) bool {
+	if node.SideEffect {
+		return true
+	}
+	if s, ok := handler.(SideEffecter); ok {
+		return s.SideEffect()
+	}
+	return false
+}
+
 // HandlerRegistry 任务处理器注册表
 type HandlerRegistry struct {
 	handlers map[string]TaskHandler
@@ -0,0 +1,103 @@
+// Package notify 提供发送通知的流程节点，消息通过通知中心的渠道发送
+package notify
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"server/dagflow/model"
+	"server/dagflow/utils"
+	notifier "server/service/notify"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// TypeNotify 发送通知节点类型
+const TypeNotify = "notify"
+
+// NotifyHandler 发送通知节点处理器
+// 节点属性：channels 通知渠道ID、title 标题、content 内容、level 级别，标题和内容支持EL表达式
+type NotifyHandler struct{}
+
+// GetType 获取处理器类型
+func (h *NotifyHandler) GetType() string {
+	return TypeNotify
+}
+
+// SideEffect 发送通知有副作用，演练回放时不发送
+func (h *NotifyHandler) SideEffect() bool {
+	return true
+}
+
+// Handle 发送通知，所有渠道都发送失败时节点失败
+func (h *NotifyHandler) Handle(ctx context.Context, node model.TaskNode, execCtx *model.ExecutionContext) (any, error) {
+	channels := parseChannels(node.Properties["channels"])
+	env := execCtx.ELEnv()
+	msg := notifier.Message{
+		Event:   "sflow_notify",
+		Level:   utils.GetStr(node, "level", "info", nil),
+		Title:   execCtx.Mask(utils.GetStr(node, "title", node.Name, env)),
+		Content: execCtx.Mask(utils.GetStr(node, "content", "", env)),
+		Data: map[string]any{
+			"flowId":      execCtx.FlowID,
+			"executionId": execCtx.ExecutionID,
+			"nodeId":      node.ID,
+		},
+		Time: time.Now(),
+	}
+	list, err := notifier.Send(ctx, channels, msg)
+	if err != nil {
+		return nil, err
+	}
+	result := make([]map[string]any, 0, len(list))
+	for _, delivery := range list {
+		result = append(result, map[string]any{
+			"deliveryId": delivery.ID,
+			"channelId":  delivery.ChannelID,
+			"status":     delivery.Status,
+			"error":      delivery.LastError,
+		})
+		if delivery.Status != notifier.StatusSuccess {
+			execCtx.Log("warn", "[%s] 通知渠道 %s 发送失败，稍后重试: %s", node.Name, delivery.ChannelName, delivery.LastError)
+		}
+	}
+	execCtx.Log("info", "[%s] 通知已发送: %s", node.Name, msg.Title)
+	return map[string]any{"title": msg.Title, "deliveries": result}, nil
+}
+
+// Validate 验证节点配置
+func (h *NotifyHandler) Validate(node model.TaskNode) error {
+	if len(parseChannels(node.Properties["channels"])) == 0 {
+		return errors.New("通知节点配置错误：缺少通知渠道channels")
+	}
+	if title, _ := node.Properties["title"].(string); title == "" {
+		if content, _ := node.Properties["content"].(string); content == "" {
+			return errors.New("通知节点配置错误：标题和内容不能同时为空")
+		}
+	}
+	return nil
+}
+
+// parseChannels 解析通知渠道ID，支持逗号分隔的字符串和数组
+func parseChannels(value any) []uint {
+	var items []string
+	switch v := value.(type) {
+	case string:
+		items = strings.Split(v, ",")
+	case []any:
+		for _, item := range v {
+			items = append(items, fmt.Sprint(item))
+		}
+	case float64:
+		items = []string{fmt.Sprint(v)}
+	}
+	ids := make([]uint, 0, len(items))
+	for _, item := range items {
+		id, err := strconv.ParseUint(strings.TrimSpace(item), 10, 64)
+		if err == nil && id > 0 {
+			ids = append(ids, uint(id))
+		}
+	}
+	return ids
+}
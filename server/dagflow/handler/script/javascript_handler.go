@@ -4,8 +4,11 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"server/dagflow/artifact"
+	"server/dagflow/core/el"
 	"server/dagflow/model"
 	"server/dagflow/utils"
+	"strings"
 	"time"
 
 	"github.com/dop251/goja"
@@ -46,9 +49,9 @@ func (h *JavaScriptHandler) Handle(ctx context.Context, node model.TaskNode, exe
 	if !ok || scriptText == "" {
 		return nil, errors.New("JavaScript节点配置错误：缺少或为空的scriptText配置")
 	}
-	var data = execCtx.Data
+	var data = execCtx.DataSnapshot()
 	// 获取可选的变量配置
-	scriptVars, err := utils.GetMap(node, "scriptVars", data)
+	scriptVars, err := utils.GetMap(node, "scriptVars", execCtx.ELEnv())
 	if err != nil {
 		return nil, fmt.Errorf("获取脚本变量失败：%v", err)
 	}
@@ -63,7 +66,7 @@ func (h *JavaScriptHandler) Handle(ctx context.Context, node model.TaskNode, exe
 	// 注入上下文数据
 	dataObj := vm.NewObject()
 	// 将执行上下文数据转换为JS对象
-	for k, v := range execCtx.Data {
+	for k, v := range data {
 		if err := dataObj.Set(k, v); err != nil {
 			return nil, fmt.Errorf("设置JS环境变量失败：%v", err)
 		}
@@ -93,6 +96,20 @@ func (h *JavaScriptHandler) Handle(ctx context.Context, node model.TaskNode, exe
 		return nil, fmt.Errorf("设置JS日志函数失败：%v", err)
 	}
 
+	// 注入密钥函数，按流程所属项目目录解析密钥
+	if err := vm.Set("secret", func(name string) (any, error) {
+		return el.CallScopedFunction("secret", execCtx.Scope(), name)
+	}); err != nil {
+		return nil, fmt.Errorf("设置JS密钥函数失败：%v", err)
+	}
+
+	// 注入制品函数，将内容保存为制品并返回制品引用
+	if err := vm.Set("artifact", func(name string, content string) (model.ArtifactRef, error) {
+		return artifact.Save(execCtx, node.ID, name, strings.NewReader(content))
+	}); err != nil {
+		return nil, fmt.Errorf("设置JS制品函数失败：%v", err)
+	}
+
 	// 设置执行超时(默认5秒)
 	var timeoutMS int64 = 5000
 	if timeout, ok := node.Properties["timeout"].(int64); ok && timeout > 0 {
@@ -8,8 +8,9 @@ import (
 
 // 节点类型常量
 const (
-	TypeStart = "start" // 开始节点
-	TypeEnd   = "end"   // 结束节点
+	TypeStart   = "start"   // 开始节点
+	TypeEnd     = "end"     // 结束节点
+	TypeFinally = "finally" // finally节点
 )
 
 // StartNodeHandler 开始节点处理器
@@ -20,9 +21,9 @@ func (h *StartNodeHandler) GetType() string {
 	return TypeStart
 }
 
-// Handle 处理开始节点
+// Handle 处理开始节点，将输入参数作为节点结果供后续节点使用
 func (h *StartNodeHandler) Handle(ctx context.Context, node model.TaskNode, execCtx *model.ExecutionContext) (any, error) {
-	return nil, nil
+	return execCtx.Params, nil
 }
 
 // Validate 验证节点配置
@@ -31,6 +32,26 @@ func (h *StartNodeHandler) Validate(node model.TaskNode) error {
 	return nil
 }
 
+// FinallyNodeHandler finally节点处理器
+// finally节点是finally分支的入口，主流程结束后执行，返回主流程的执行结果
+type FinallyNodeHandler struct{}
+
+// GetType 获取处理器类型
+func (h *FinallyNodeHandler) GetType() string {
+	return TypeFinally
+}
+
+// Handle 处理finally节点
+func (h *FinallyNodeHandler) Handle(ctx context.Context, node model.TaskNode, execCtx *model.ExecutionContext) (any, error) {
+	outcome, _ := execCtx.GetData(model.OutcomeDataKey)
+	return outcome, nil
+}
+
+// Validate 验证节点配置
+func (h *FinallyNodeHandler) Validate(node model.TaskNode) error {
+	return nil
+}
+
 // EndNodeHandler 结束节点处理器
 type EndNodeHandler struct{}
 
@@ -54,7 +75,7 @@ func (h *EndNodeHandler) Handle(ctx context.Context, node model.TaskNode, execCt
 	switch resultType {
 	case "all":
 		// 返回所有数据
-		return execCtx.Data, nil
+		return execCtx.DataSnapshot(), nil
 	case "specified":
 		// 返回指定数据
 		specifiedKeys, ok := node.Properties["resultKeys"].([]string)
@@ -71,7 +92,7 @@ func (h *EndNodeHandler) Handle(ctx context.Context, node model.TaskNode, execCt
 		return result, nil
 	default:
 		// 默认返回所有数据
-		return execCtx.Data, nil
+		return execCtx.DataSnapshot(), nil
 	}
 }
 
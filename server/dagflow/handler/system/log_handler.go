@@ -32,7 +32,7 @@ func (h *LogNodeHandler) Handle(ctx context.Context, node model.TaskNode, execCt
 	}
 
 	// 计算日志内容
-	content := utils.GetStr(node, "logInfo", "", execCtx.Data)
+	content := utils.GetStr(node, "logInfo", "", execCtx.ELEnv())
 
 	// 记录日志
 	execCtx.Log(logLevel, "[%s] %s", node.Name, content)
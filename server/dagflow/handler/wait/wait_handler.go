@@ -0,0 +1,358 @@
+// Package wait 提供暂停流程执行的节点：人工审批和等待外部信号
+// 节点暂停时将执行上下文保存到等待记录中，等待期间其他节点执行结束后更新保存的执行上下文，服务重启后由服务层恢复执行
+package wait
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"server/dagflow/model"
+	"server/service/sflow"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// 节点类型常量
+const (
+	TypeApproval = "approval"   // 人工审批节点
+	TypeSignal   = "waitSignal" // 等待信号节点
+)
+
+// 等待超时后的默认动作
+const (
+	ActionApprove  = "approve"  // 审批节点：视为批准
+	ActionReject   = "reject"   // 审批节点：视为拒绝（默认）
+	ActionContinue = "continue" // 信号节点：以空数据继续执行
+	ActionFail     = "fail"     // 信号节点：节点失败（默认）
+)
+
+// ErrRejected 审批被拒绝
+var ErrRejected = errors.New("审批未通过")
+
+// ErrWaitTimeout 等待超时且默认动作为失败
+var ErrWaitTimeout = errors.New("等待超时")
+
+// waiters 等待中的节点，处理等待记录后通过Notify唤醒
+var waiters = struct {
+	sync.Mutex
+	m map[uint]chan struct{}
+}{m: make(map[uint]chan struct{})}
+
+// waiting 执行中等待中的节点数量，save保证同一执行的执行状态按顺序保存
+type waiting struct {
+	count int
+	save  sync.Mutex
+}
+
+// executions 有等待中节点的执行
+var executions = struct {
+	sync.Mutex
+	m map[string]*waiting
+}{m: make(map[string]*waiting)}
+
+// Notify 唤醒等待指定记录的节点，节点不在本进程中等待时忽略
+func Notify(waitID uint) {
+	waiters.Lock()
+	defer waiters.Unlock()
+	if ch, ok := waiters.m[waitID]; ok {
+		close(ch)
+		delete(waiters.m, waitID)
+	}
+}
+
+// register 注册等待
+func register(waitID uint) chan struct{} {
+	waiters.Lock()
+	defer waiters.Unlock()
+	ch := make(chan struct{})
+	waiters.m[waitID] = ch
+	return ch
+}
+
+// unregister 取消等待
+func unregister(waitID uint) {
+	waiters.Lock()
+	defer waiters.Unlock()
+	delete(waiters.m, waitID)
+}
+
+// track 登记执行中有节点开始等待，返回该执行的等待情况和节点结束等待时调用的函数
+func track(executionID string) (*waiting, func()) {
+	executions.Lock()
+	defer executions.Unlock()
+	w, ok := executions.m[executionID]
+	if !ok {
+		w = &waiting{}
+		executions.m[executionID] = w
+	}
+	w.count++
+	return w, func() {
+		executions.Lock()
+		defer executions.Unlock()
+		if w.count--; w.count == 0 {
+			delete(executions.m, executionID)
+		}
+	}
+}
+
+// SaveState 执行中有等待中的节点时，将最新的执行状态保存到该执行所有等待中的记录
+// 在节点执行结束后调用，服务重启后按最新的状态恢复，等待期间已执行结束的节点不会重复执行
+func SaveState(node model.TaskNode, execCtx *model.ExecutionContext) {
+	executions.Lock()
+	w := executions.m[execCtx.ExecutionID]
+	executions.Unlock()
+	if w == nil {
+		return
+	}
+	// 在锁内序列化，保证后保存的状态不会被先生成的状态覆盖
+	w.save.Lock()
+	defer w.save.Unlock()
+	state, err := execCtx.ToJSON()
+	if err == nil {
+		err = sflow.SFlowWait{}.UpdateState(execCtx.ExecutionID, state)
+	}
+	if err != nil {
+		execCtx.Log("warn", "节点 %s 执行结束后保存执行状态失败: %v", node.Name, err)
+	}
+}
+
+// ApprovalHandler 人工审批节点处理器
+// 节点属性：approvers 审批人ID、message 提示信息、waitTimeout 等待超时(秒)、defaultAction 超时默认动作
+type ApprovalHandler struct{}
+
+// GetType 获取处理器类型
+func (h *ApprovalHandler) GetType() string {
+	return TypeApproval
+}
+
+// Handle 暂停执行直到审批通过或拒绝，拒绝时节点失败，可通过错误分支处理
+func (h *ApprovalHandler) Handle(ctx context.Context, node model.TaskNode, execCtx *model.ExecutionContext) (any, error) {
+	record, err := waitFor(ctx, node, execCtx, func(record *sflow.SFlowWait) {
+		record.Kind = sflow.WaitApproval
+		record.Approvers = parseApprovers(node.Properties["approvers"])
+		record.DefaultAction = stringProperty(node, "defaultAction", ActionReject)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	result := map[string]any{
+		"status":    record.Status,
+		"decidedBy": record.DecidedBy,
+		"comment":   record.Comment,
+	}
+	switch record.Status {
+	case sflow.WaitApproved:
+		result["approved"] = true
+		return result, nil
+	case sflow.WaitTimedOut:
+		if record.DefaultAction == ActionApprove {
+			result["approved"] = true
+			return result, nil
+		}
+		return nil, fmt.Errorf("%w: 审批超时", ErrRejected)
+	default:
+		if record.Comment != "" {
+			return nil, fmt.Errorf("%w: %s", ErrRejected, record.Comment)
+		}
+		return nil, ErrRejected
+	}
+}
+
+// Validate 验证节点配置
+func (h *ApprovalHandler) Validate(node model.TaskNode) error {
+	return validateAction(node, ActionApprove, ActionReject)
+}
+
+// SignalHandler 等待信号节点处理器
+// 节点属性：signal 信号名称、message 提示信息、waitTimeout 等待超时(秒)、defaultAction 超时默认动作
+type SignalHandler struct{}
+
+// GetType 获取处理器类型
+func (h *SignalHandler) GetType() string {
+	return TypeSignal
+}
+
+// Handle 暂停执行直到收到指定名称的信号，返回信号携带的数据
+func (h *SignalHandler) Handle(ctx context.Context, node model.TaskNode, execCtx *model.ExecutionContext) (any, error) {
+	signal := stringProperty(node, "signal", "")
+	if signal == "" {
+		return nil, errors.New("等待信号节点配置错误：缺少signal配置")
+	}
+	record, err := waitFor(ctx, node, execCtx, func(record *sflow.SFlowWait) {
+		record.Kind = sflow.WaitSignal
+		record.SignalName = signal
+		record.DefaultAction = stringProperty(node, "defaultAction", ActionFail)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if record.Status == sflow.WaitTimedOut {
+		if record.DefaultAction == ActionContinue {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("%w: 未收到信号 %s", ErrWaitTimeout, signal)
+	}
+	if record.Payload == "" {
+		return nil, nil
+	}
+	var payload any
+	if err := json.Unmarshal([]byte(record.Payload), &payload); err != nil {
+		return record.Payload, nil
+	}
+	return payload, nil
+}
+
+// Validate 验证节点配置
+func (h *SignalHandler) Validate(node model.TaskNode) error {
+	if stringProperty(node, "signal", "") == "" {
+		return errors.New("等待信号节点配置错误：缺少signal配置")
+	}
+	return validateAction(node, ActionContinue, ActionFail)
+}
+
+// waitFor 创建或恢复节点的等待记录，并等待记录被处理、超时或流程被取消
+// 恢复执行时节点已有等待记录，直接使用该记录，已处理的记录立即返回
+func waitFor(ctx context.Context, node model.TaskNode, execCtx *model.ExecutionContext, init func(*sflow.SFlowWait)) (sflow.SFlowWait, error) {
+	// 创建记录前登记，创建期间其他节点执行结束时也会保存执行状态
+	w, untrack := track(execCtx.ExecutionID)
+	defer untrack()
+
+	record, err := sflow.SFlowWait{}.FindByNode(execCtx.ExecutionID, node.ID)
+	if err != nil {
+		return record, fmt.Errorf("查询等待记录失败: %v", err)
+	}
+	if record.ID == 0 {
+		if record, err = createRecord(w, node, execCtx, init); err != nil {
+			return record, err
+		}
+	}
+	if record.Status != sflow.WaitPending {
+		return record, nil
+	}
+
+	notified := register(record.ID)
+	defer unregister(record.ID)
+	// 注册后重新读取，避免遗漏注册前已处理的记录
+	if record, err = record.Load(record.ID); err != nil || record.Status != sflow.WaitPending {
+		return record, err
+	}
+
+	execCtx.Log("info", "节点 %s 等待%s (等待记录ID: %d)", node.Name, kindName(record.Kind), record.ID)
+	execCtx.Emit(model.Event{Type: model.EventNodeWaiting, NodeID: node.ID, Message: record.Message})
+
+	var deadline <-chan time.Time
+	if record.HasDeadline() {
+		timer := time.NewTimer(max(record.Remaining(), 0))
+		defer timer.Stop()
+		deadline = timer.C
+	}
+
+	select {
+	case <-notified:
+	case <-deadline:
+		// 超时与处理同时发生时以先写入的结果为准
+		if err := record.Resolve(record.ID, sflow.WaitTimedOut, 0, "等待超时", ""); err == nil {
+			execCtx.Log("warn", "节点 %s 等待超时，按默认动作 %s 处理", node.Name, record.DefaultAction)
+		}
+	case <-ctx.Done():
+		record.Resolve(record.ID, sflow.WaitCancelled, 0, "流程已结束", "")
+		return record, context.Cause(ctx)
+	}
+	return record.Load(record.ID)
+}
+
+// createRecord 创建节点的等待记录并保存当前的执行状态
+// 与SaveState互斥，避免创建前生成的执行状态覆盖其他节点执行结束后保存的状态
+func createRecord(w *waiting, node model.TaskNode, execCtx *model.ExecutionContext, init func(*sflow.SFlowWait)) (sflow.SFlowWait, error) {
+	w.save.Lock()
+	defer w.save.Unlock()
+	state, err := execCtx.ToJSON()
+	if err != nil {
+		return sflow.SFlowWait{}, fmt.Errorf("保存执行状态失败: %v", err)
+	}
+	record := sflow.SFlowWait{
+		SFlowId:     execCtx.FlowID,
+		ExecutionID: execCtx.ExecutionID,
+		NodeID:      node.ID,
+		NodeName:    node.Name,
+		Message:     stringProperty(node, "message", ""),
+		State:       state,
+	}
+	if timeout := intProperty(node, "waitTimeout"); timeout > 0 {
+		record.DeadlineAt.Time = time.Now().Add(time.Duration(timeout) * time.Second)
+	}
+	init(&record)
+	if err := record.Create(); err != nil {
+		return record, fmt.Errorf("创建等待记录失败: %v", err)
+	}
+	return record, nil
+}
+
+// kindName 等待类型的显示名称
+func kindName(kind string) string {
+	if kind == sflow.WaitApproval {
+		return "审批"
+	}
+	return "信号"
+}
+
+// parseApprovers 解析审批人ID，支持逗号分隔的字符串和数组
+func parseApprovers(value any) string {
+	var items []string
+	switch v := value.(type) {
+	case string:
+		items = strings.Split(v, ",")
+	case []any:
+		for _, item := range v {
+			items = append(items, fmt.Sprint(item))
+		}
+	}
+	ids := make([]string, 0, len(items))
+	for _, item := range items {
+		if item = strings.TrimSpace(item); item != "" {
+			ids = append(ids, item)
+		}
+	}
+	return strings.Join(ids, ",")
+}
+
+// stringProperty 获取字符串类型的节点属性
+func stringProperty(node model.TaskNode, key, defval string) string {
+	if v, ok := node.Properties[key].(string); ok && v != "" {
+		return v
+	}
+	return defval
+}
+
+// intProperty 获取整数类型的节点属性，支持数字和数字字符串
+func intProperty(node model.TaskNode, key string) int {
+	switch v := node.Properties[key].(type) {
+	case float64:
+		return int(v)
+	case int:
+		return v
+	case string:
+		i, _ := strconv.Atoi(strings.TrimSpace(v))
+		return i
+	}
+	return 0
+}
+
+// validateAction 验证超时默认动作
+func validateAction(node model.TaskNode, actions ...string) error {
+	action := stringProperty(node, "defaultAction", "")
+	if action == "" {
+		return nil
+	}
+	for _, item := range actions {
+		if action == item {
+			return nil
+		}
+	}
+	return fmt.Errorf("超时默认动作应为 %s 之一", strings.Join(actions, "、"))
+}
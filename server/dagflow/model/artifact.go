@@ -0,0 +1,32 @@
+package model
+
+import "strings"
+
+// ArtifactKind 制品引用的类型标识
+const ArtifactKind = "artifact"
+
+// ArtifactScheme 制品URI的协议前缀
+const ArtifactScheme = "artifact://"
+
+// ArtifactRef 制品引用
+// 大文件或大结果保存到制品存储后，流程数据中只保存引用，通过URI下载或读取内容
+type ArtifactRef struct {
+	Kind     string `json:"kind" expr:"kind"`         // 类型标识，固定为artifact
+	URI      string `json:"uri" expr:"uri"`           // 制品URI：artifact://执行ID/节点ID/名称
+	Name     string `json:"name" expr:"name"`         // 制品名称
+	Size     int64  `json:"size" expr:"size"`         // 大小(字节)
+	MimeType string `json:"mimeType" expr:"mimeType"` // MIME类型
+	Checksum string `json:"checksum" expr:"checksum"` // 校验和：sha256:十六进制摘要
+}
+
+// IsArtifactRef 判断结果是否为制品引用，兼容恢复执行后反序列化得到的map
+func IsArtifactRef(result any) bool {
+	switch v := result.(type) {
+	case ArtifactRef, *ArtifactRef:
+		return true
+	case map[string]any:
+		uri, _ := v["uri"].(string)
+		return v["kind"] == ArtifactKind && strings.HasPrefix(uri, ArtifactScheme)
+	}
+	return false
+}
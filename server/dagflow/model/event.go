@@ -0,0 +1,48 @@
+package model
+
+import "time"
+
+// EventType 流程执行事件类型
+type EventType string
+
+const (
+	EventFlowStarted   EventType = "flow_started"   // 流程开始执行
+	EventFlowFinished  EventType = "flow_finished"  // 流程执行结束
+	EventNodeStarted   EventType = "node_started"   // 节点开始执行
+	EventNodeCompleted EventType = "node_completed" // 节点执行完成
+	EventNodeFailed    EventType = "node_failed"    // 节点执行失败
+	EventNodeSkipped   EventType = "node_skipped"   // 节点被跳过
+	EventNodePaused    EventType = "node_paused"    // 节点在调试断点处暂停
+	EventNodeWaiting   EventType = "node_waiting"   // 节点等待审批或外部信号
+	EventEdgeEvaluated EventType = "edge_evaluated" // 连线表达式已计算
+	EventLog           EventType = "log"            // 日志输出
+)
+
+// Event 流程执行事件
+// 用于在流程执行过程中实时通知订阅者节点状态、连线计算结果和日志
+type Event struct {
+	Type        EventType           `json:"type"`              // 事件类型
+	FlowID      uint                `json:"flowId"`            // 流程ID
+	ExecutionID string              `json:"executionId"`       // 执行ID
+	NodeID      string              `json:"nodeId,omitempty"`  // 节点ID
+	EdgeID      string              `json:"edgeId,omitempty"`  // 连线ID
+	Status      NodeExecutionStatus `json:"status,omitempty"`  // 节点或流程状态
+	Result      *bool               `json:"result,omitempty"`  // 连线表达式计算结果
+	Level       string              `json:"level,omitempty"`   // 日志级别
+	Message     string              `json:"message,omitempty"` // 日志内容或错误信息
+	Time        time.Time           `json:"time"`              // 事件时间
+}
+
+// EventEmitter 事件发送接口
+type EventEmitter interface {
+	Emit(event Event)
+}
+
+// nodeStatusEvents 节点状态与事件类型的对应关系
+var nodeStatusEvents = map[NodeExecutionStatus]EventType{
+	Running:   EventNodeStarted,
+	Completed: EventNodeCompleted,
+	Failed:    EventNodeFailed,
+	Skipped:   EventNodeSkipped,
+	TimedOut:  EventNodeFailed,
+}
@@ -3,6 +3,10 @@ package model
 
 import (
 	"encoding/json"
+	"fmt"
+	"io"
+	"server/dagflow/core/el"
+	"sync"
 	"time"
 )
 
@@ -23,6 +27,8 @@ type TaskNode struct {
 	ExceptionHandle string         `json:"exceptionHandle"` // 异常处理方式，根据el表达式判断是否继续运行，为空则发生异常时终止运行
 	Disabled        bool           `json:"disabled"`        // 是否禁用，默认启用
 	LogLevel        string         `json:"logLevel"`        // 日志级别，默认无
+	Timeout         int            `json:"timeout"`         // 执行超时时间(秒)，0表示不限制
+	SideEffect      bool           `json:"sideEffect"`      // 是否有副作用(如发送通知、写入外部系统)，演练回放时使用记录的结果代替执行
 	Properties      map[string]any `json:"properties"`      // 节点属性，根据节点类型不同包含不同的属性
 }
 
@@ -58,8 +64,21 @@ type Edge struct {
 	Target       string `json:"target"`       // 目标节点ID
 	SourceAnchor string `json:"sourceAnchor"` // 源连接点
 	TargetAnchor string `json:"targetAnchor"` // 目标连接点
+	Kind         string `json:"kind"`         // 连线类型，为空表示正常连线，error表示仅在源节点失败时执行
 }
 
+// 连线类型
+const (
+	EdgeNormal = ""      // 源节点成功时执行
+	EdgeError  = "error" // 源节点失败时执行，失败信息保存在ErrorDataKey数据中
+)
+
+// 错误分支和finally分支使用的数据键
+const (
+	ErrorDataKey   = "error"       // 错误分支中最近一次节点失败的信息
+	OutcomeDataKey = "flowOutcome" // finally分支中主流程的执行结果
+)
+
 // GetID 获取连线ID
 func (e Edge) GetID() string {
 	return e.ID
@@ -88,6 +107,13 @@ type Flow struct {
 	EndNodeID    string              `json:"endNodeId"`    // 结束节点ID
 	ReturnResult bool                `json:"returnResult"` // 是否返回结果
 	ResultType   string              `json:"resultType"`   // 返回结果类型
+	Timeout      int                 `json:"timeout"`      // 流程执行超时时间(秒)，0表示不限制
+	Workers      int                 `json:"workers"`      // 单次执行的并发节点数，0表示使用默认值
+	Concurrency  string              `json:"concurrency"`  // 并发策略：parallel、skip、queue、replace
+	MaxQueue     int                 `json:"maxQueue"`     // 排队策略下的最大等待数量
+	Params       []ParamSchema       `json:"params"`       // 输入参数定义，由开始节点声明
+	FinallyID    string              `json:"finallyId"`    // finally节点ID，其后续分支在流程结束后总会执行
+	ProjectDirID string              `json:"projectDirId"` // 所属项目目录ID，限定可访问的密钥
 }
 
 // NodeExecutionStatus 节点执行状态
@@ -99,8 +125,18 @@ const (
 	Completed NodeExecutionStatus = "completed" // 已完成
 	Failed    NodeExecutionStatus = "failed"    // 执行失败
 	Skipped   NodeExecutionStatus = "skipped"   // 已跳过
+	TimedOut  NodeExecutionStatus = "timeout"   // 执行超时
+	Recovered NodeExecutionStatus = "recovered" // 流程完成，但有节点失败并由错误分支处理
 )
 
+// NodeFailure 节点失败记录
+type NodeFailure struct {
+	NodeID   string `json:"nodeId"`   // 节点ID
+	NodeName string `json:"nodeName"` // 节点名称
+	Message  string `json:"message"`  // 错误信息
+	Handled  bool   `json:"handled"`  // 是否由错误分支处理
+}
+
 // ExecutionContext 执行上下文，保存流程执行过程中的数据
 type ExecutionContext struct {
 	FlowID         uint                           `json:"flowId"`         // 流程ID
@@ -116,9 +152,20 @@ type ExecutionContext struct {
 	NodeResults    map[string]any                 `json:"nodeResults"`    // 节点执行结果
 	NodeErrors     map[string]string              `json:"nodeErrors"`     // 节点执行错误
 	Debug          bool                           `json:"debug"`          // 是否为调试模式
+	ReplayOf       string                         `json:"replayOf"`       // 回放的原执行ID，非回放执行时为空
+	Dry            bool                           `json:"dry"`            // 是否为演练回放，有副作用的节点使用原执行记录的结果
+	Failures       []NodeFailure                  `json:"failures"`       // 节点失败记录，包含已由错误分支处理的失败
+	FinallyStatus  NodeExecutionStatus            `json:"finallyStatus"`  // finally分支执行状态，未配置时为空
+	FinallyError   string                         `json:"finallyError"`   // finally分支执行错误
 	ParentContext  *ExecutionContext              `json:"-"`              // 父执行上下文(用于子流程)
 	SubContexts    map[string][]*ExecutionContext `json:"-"`              // 子执行上下文(用于迭代节点)
 	logger         LoggerInterface                `json:"-"`              // 日志记录器
+	emitter        EventEmitter                   `json:"-"`              // 事件发送器
+	scope          any                            `json:"-"`              // 执行范围，供表达式中的作用域函数(如secret)使用
+	masker         Masker                         `json:"-"`              // 脱敏器，日志和结果输出时替换密钥值
+	logs           []string                       `json:"-"`              // 已脱敏的执行日志
+	logWriter      io.WriteCloser                 `json:"-"`              // 执行日志文件，日志实时写入用于查看执行中的日志
+	mu             sync.RWMutex                   `json:"-"`              // 保护数据和节点状态的读写锁
 }
 
 // LoggerInterface 日志接口
@@ -129,6 +176,11 @@ type LoggerInterface interface {
 	Error(msg string, args ...any)
 }
 
+// Masker 脱敏接口
+type Masker interface {
+	Mask(text string) string
+}
+
 // NewExecutionContext 创建新的执行上下文
 func NewExecutionContext(flowID uint, params map[string]any, logger LoggerInterface) *ExecutionContext {
 	now := time.Now()
@@ -152,71 +204,266 @@ func NewExecutionContext(flowID uint, params map[string]any, logger LoggerInterf
 
 // SetData 设置数据
 func (ctx *ExecutionContext) SetData(key string, value any) {
+	ctx.mu.Lock()
+	defer ctx.mu.Unlock()
 	ctx.Data[key] = value
 }
 
 // GetData 获取数据
 func (ctx *ExecutionContext) GetData(key string) (any, bool) {
+	ctx.mu.RLock()
+	defer ctx.mu.RUnlock()
 	v, ok := ctx.Data[key]
 	return v, ok
 }
 
+// DeleteData 删除数据
+func (ctx *ExecutionContext) DeleteData(key string) {
+	ctx.mu.Lock()
+	defer ctx.mu.Unlock()
+	delete(ctx.Data, key)
+}
+
+// DataSnapshot 获取数据的浅拷贝，用于在执行过程中安全地读取数据
+func (ctx *ExecutionContext) DataSnapshot() map[string]any {
+	ctx.mu.RLock()
+	defer ctx.mu.RUnlock()
+	snapshot := make(map[string]any, len(ctx.Data))
+	for k, v := range ctx.Data {
+		snapshot[k] = v
+	}
+	return snapshot
+}
+
+// SetScope 设置执行范围
+func (ctx *ExecutionContext) SetScope(scope any) {
+	ctx.scope = scope
+}
+
+// Scope 获取执行范围
+func (ctx *ExecutionContext) Scope() any {
+	return ctx.scope
+}
+
+// SetMasker 设置脱敏器
+func (ctx *ExecutionContext) SetMasker(masker Masker) {
+	ctx.masker = masker
+}
+
+// Mask 替换文本中的密钥值，未设置脱敏器时原样返回
+func (ctx *ExecutionContext) Mask(text string) string {
+	if ctx.masker == nil {
+		return text
+	}
+	return ctx.masker.Mask(text)
+}
+
+// ELEnv 获取计算EL表达式使用的环境，包含数据快照和执行范围
+func (ctx *ExecutionContext) ELEnv() map[string]any {
+	env := ctx.DataSnapshot()
+	if ctx.scope != nil {
+		env[el.ScopeKey] = ctx.scope
+	}
+	return env
+}
+
+// Logs 获取已脱敏的执行日志
+func (ctx *ExecutionContext) Logs() []string {
+	ctx.mu.RLock()
+	defer ctx.mu.RUnlock()
+	return append([]string(nil), ctx.logs...)
+}
+
+// SetLogWriter 设置执行日志文件，先写入已有的日志，之后的日志实时写入
+func (ctx *ExecutionContext) SetLogWriter(writer io.WriteCloser) {
+	ctx.mu.Lock()
+	defer ctx.mu.Unlock()
+	for _, line := range ctx.logs {
+		io.WriteString(writer, line+"\n")
+	}
+	ctx.logWriter = writer
+}
+
+// CloseLogWriter 关闭执行日志文件
+func (ctx *ExecutionContext) CloseLogWriter() {
+	ctx.mu.Lock()
+	defer ctx.mu.Unlock()
+	if ctx.logWriter != nil {
+		ctx.logWriter.Close()
+		ctx.logWriter = nil
+	}
+}
+
 // SetNodeResult 设置节点执行结果
 func (ctx *ExecutionContext) SetNodeResult(nodeID string, result any) {
+	ctx.mu.Lock()
+	defer ctx.mu.Unlock()
 	ctx.NodeResults[nodeID] = result
 }
 
+// AddFailure 记录节点失败
+func (ctx *ExecutionContext) AddFailure(failure NodeFailure) {
+	failure.Message = ctx.Mask(failure.Message)
+	ctx.mu.Lock()
+	defer ctx.mu.Unlock()
+	ctx.Failures = append(ctx.Failures, failure)
+}
+
+// HandledFailures 获取已由错误分支处理的节点失败数量
+func (ctx *ExecutionContext) HandledFailures() int {
+	ctx.mu.RLock()
+	defer ctx.mu.RUnlock()
+	count := 0
+	for _, failure := range ctx.Failures {
+		if failure.Handled {
+			count++
+		}
+	}
+	return count
+}
+
 // GetNodeResult 获取节点执行结果
 func (ctx *ExecutionContext) GetNodeResult(nodeID string) (any, bool) {
+	ctx.mu.RLock()
+	defer ctx.mu.RUnlock()
 	v, ok := ctx.NodeResults[nodeID]
 	return v, ok
 }
 
+// SetNodeError 设置节点执行错误
+func (ctx *ExecutionContext) SetNodeError(nodeID string, message string) {
+	ctx.mu.Lock()
+	defer ctx.mu.Unlock()
+	ctx.NodeErrors[nodeID] = ctx.Mask(message)
+}
+
+// NodeErrorMessage 获取节点执行错误
+func (ctx *ExecutionContext) NodeErrorMessage(nodeID string) (string, bool) {
+	ctx.mu.RLock()
+	defer ctx.mu.RUnlock()
+	message, ok := ctx.NodeErrors[nodeID]
+	return message, ok
+}
+
 // SetNodeStatus 设置节点执行状态
+// 状态发生变化时发送对应的节点事件，失败事件携带节点错误信息
 func (ctx *ExecutionContext) SetNodeStatus(nodeID string, status NodeExecutionStatus) {
+	ctx.mu.Lock()
+	old, exists := ctx.NodeStatus[nodeID]
 	ctx.NodeStatus[nodeID] = status
+	message := ctx.NodeErrors[nodeID]
+	ctx.mu.Unlock()
+	if exists && old == status {
+		return
+	}
+	if eventType, ok := nodeStatusEvents[status]; ok {
+		ctx.Emit(Event{Type: eventType, NodeID: nodeID, Status: status, Message: message})
+	}
 }
 
 // GetNodeStatus 获取节点执行状态
 func (ctx *ExecutionContext) GetNodeStatus(nodeID string) NodeExecutionStatus {
+	ctx.mu.RLock()
+	defer ctx.mu.RUnlock()
 	if status, ok := ctx.NodeStatus[nodeID]; ok {
 		return status
 	}
 	return Pending
 }
 
+// SetNodeStartTime 记录节点开始时间
+func (ctx *ExecutionContext) SetNodeStartTime(nodeID string, t time.Time) {
+	ctx.mu.Lock()
+	defer ctx.mu.Unlock()
+	ctx.NodeStartTimes[nodeID] = t
+}
+
+// SetNodeEndTime 记录节点结束时间
+func (ctx *ExecutionContext) SetNodeEndTime(nodeID string, t time.Time) {
+	ctx.mu.Lock()
+	defer ctx.mu.Unlock()
+	ctx.NodeEndTimes[nodeID] = t
+}
+
+// SetEventEmitter 设置事件发送器
+func (ctx *ExecutionContext) SetEventEmitter(emitter EventEmitter) {
+	ctx.emitter = emitter
+}
+
+// HasEventEmitter 是否已设置事件发送器
+func (ctx *ExecutionContext) HasEventEmitter() bool {
+	return ctx.emitter != nil
+}
+
+// Emit 发送执行事件，自动填充流程ID、执行ID和事件时间
+func (ctx *ExecutionContext) Emit(event Event) {
+	if ctx.emitter == nil {
+		return
+	}
+	event.FlowID = ctx.FlowID
+	event.ExecutionID = ctx.ExecutionID
+	if event.Time.IsZero() {
+		event.Time = time.Now()
+	}
+	ctx.emitter.Emit(event)
+}
+
 // Log 记录日志
+// 日志内容在输出前脱敏，并保留一份用于写入执行日志
 func (ctx *ExecutionContext) Log(level string, message string, args ...any) {
+	text := ctx.Mask(fmt.Sprintf(message, args...))
+	line := fmt.Sprintf("[%s] %s %s", time.Now().Format("2006-01-02 15:04:05"), level, text)
+	ctx.mu.Lock()
+	ctx.logs = append(ctx.logs, line)
+	if ctx.logWriter != nil {
+		io.WriteString(ctx.logWriter, line+"\n")
+	}
+	ctx.mu.Unlock()
+	if ctx.emitter != nil {
+		ctx.Emit(Event{Type: EventLog, Level: level, Message: text})
+	}
 	if ctx.logger == nil {
 		return
 	}
 
 	switch level {
 	case "debug":
-		ctx.logger.Debug(message, args...)
+		ctx.logger.Debug("%s", text)
 	case "info":
-		ctx.logger.Info(message, args...)
+		ctx.logger.Info("%s", text)
 	case "warn":
-		ctx.logger.Warn(message, args...)
+		ctx.logger.Warn("%s", text)
 	case "error":
-		ctx.logger.Error(message, args...)
+		ctx.logger.Error("%s", text)
 	default:
-		ctx.logger.Info(message, args...)
+		ctx.logger.Info("%s", text)
 	}
 }
 
 // Clone 克隆执行上下文(用于迭代节点)
 func (ctx *ExecutionContext) Clone() *ExecutionContext {
-	dataCopy := make(map[string]any, len(ctx.Data))
-	for k, v := range ctx.Data {
-		dataCopy[k] = v
-	}
+	dataCopy := ctx.DataSnapshot()
 
 	clone := NewExecutionContext(ctx.FlowID, dataCopy, ctx.logger)
 	clone.ParentContext = ctx
+	clone.emitter = ctx.emitter
+	clone.scope = ctx.scope
+	clone.masker = ctx.masker
 	return clone
 }
 
+// MarshalJSON 在读锁保护下序列化执行上下文，输出中的密钥值会被脱敏
+func (ctx *ExecutionContext) MarshalJSON() ([]byte, error) {
+	type alias ExecutionContext
+	ctx.mu.RLock()
+	bytes, err := json.Marshal((*alias)(ctx))
+	ctx.mu.RUnlock()
+	if err != nil || ctx.masker == nil {
+		return bytes, err
+	}
+	return []byte(ctx.masker.Mask(string(bytes))), nil
+}
+
 // ToJSON 将ExecutionContext转为JSON字符串
 func (ctx *ExecutionContext) ToJSON() (string, error) {
 	bytes, err := json.Marshal(ctx)
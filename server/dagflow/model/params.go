@@ -0,0 +1,249 @@
+package model
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// ParamType 输入参数类型
+type ParamType string
+
+const (
+	ParamString ParamType = "string" // 字符串
+	ParamInt    ParamType = "int"    // 整数
+	ParamFloat  ParamType = "float"  // 浮点数
+	ParamBool   ParamType = "bool"   // 布尔值
+	ParamArray  ParamType = "array"  // 数组
+	ParamObject ParamType = "object" // 对象
+)
+
+// ParamSchema 流程输入参数定义，由开始节点声明
+type ParamSchema struct {
+	Name        string    `json:"name"`        // 参数名称
+	Label       string    `json:"label"`       // 显示名称
+	Type        ParamType `json:"type"`        // 参数类型，为空时不做类型转换
+	Required    bool      `json:"required"`    // 是否必填
+	Default     any       `json:"default"`     // 默认值
+	Enum        []any     `json:"enum"`        // 可选值
+	Pattern     string    `json:"pattern"`     // 正则表达式，仅校验字符串参数
+	Min         *float64  `json:"min"`         // 最小值，仅校验数值参数
+	Max         *float64  `json:"max"`         // 最大值，仅校验数值参数
+	Description string    `json:"description"` // 参数说明
+}
+
+// ParamError 单个参数的校验错误
+type ParamError struct {
+	Field   string `json:"field"`   // 参数名称
+	Message string `json:"message"` // 错误信息
+}
+
+// ParamErrors 参数校验错误集合
+type ParamErrors []ParamError
+
+// Error 实现error接口
+func (e ParamErrors) Error() string {
+	messages := make([]string, 0, len(e))
+	for _, item := range e {
+		messages = append(messages, item.Field+": "+item.Message)
+	}
+	return "参数校验失败: " + strings.Join(messages, "; ")
+}
+
+// ValidateParams 按参数定义校验并转换输入参数
+// 未声明的参数原样保留，校验失败时返回ParamErrors
+func ValidateParams(schema []ParamSchema, params map[string]any) (map[string]any, error) {
+	result := make(map[string]any, len(params))
+	for k, v := range params {
+		result[k] = v
+	}
+
+	var errs ParamErrors
+	for _, item := range schema {
+		if item.Name == "" {
+			continue
+		}
+		value, ok := result[item.Name]
+		if !ok || isEmptyParam(value) {
+			if item.Default == nil {
+				if item.Required {
+					errs = append(errs, ParamError{Field: item.Name, Message: "参数不能为空"})
+				}
+				delete(result, item.Name)
+				continue
+			}
+			value = item.Default
+		}
+
+		converted, err := convertParam(item.Type, value)
+		if err != nil {
+			errs = append(errs, ParamError{Field: item.Name, Message: err.Error()})
+			continue
+		}
+		if msg := checkParamRule(item, converted); msg != "" {
+			errs = append(errs, ParamError{Field: item.Name, Message: msg})
+			continue
+		}
+		result[item.Name] = converted
+	}
+
+	if len(errs) > 0 {
+		return nil, errs
+	}
+	return result, nil
+}
+
+// isEmptyParam 检查参数值是否为空
+func isEmptyParam(value any) bool {
+	if value == nil {
+		return true
+	}
+	s, ok := value.(string)
+	return ok && s == ""
+}
+
+// convertParam 将参数值转换为声明的类型
+func convertParam(paramType ParamType, value any) (any, error) {
+	switch paramType {
+	case "":
+		return value, nil
+	case ParamString:
+		switch v := value.(type) {
+		case string:
+			return v, nil
+		case map[string]any, []any:
+			return nil, fmt.Errorf("应为字符串")
+		default:
+			return fmt.Sprint(v), nil
+		}
+	case ParamInt:
+		switch v := value.(type) {
+		case int:
+			return int64(v), nil
+		case int64:
+			return v, nil
+		case float64:
+			if v != float64(int64(v)) {
+				return nil, fmt.Errorf("应为整数")
+			}
+			return int64(v), nil
+		case json.Number:
+			return v.Int64()
+		case string:
+			i, err := strconv.ParseInt(strings.TrimSpace(v), 10, 64)
+			if err != nil {
+				return nil, fmt.Errorf("应为整数")
+			}
+			return i, nil
+		}
+		return nil, fmt.Errorf("应为整数")
+	case ParamFloat:
+		switch v := value.(type) {
+		case int:
+			return float64(v), nil
+		case int64:
+			return float64(v), nil
+		case float64:
+			return v, nil
+		case json.Number:
+			return v.Float64()
+		case string:
+			f, err := strconv.ParseFloat(strings.TrimSpace(v), 64)
+			if err != nil {
+				return nil, fmt.Errorf("应为数字")
+			}
+			return f, nil
+		}
+		return nil, fmt.Errorf("应为数字")
+	case ParamBool:
+		switch v := value.(type) {
+		case bool:
+			return v, nil
+		case string:
+			b, err := strconv.ParseBool(strings.TrimSpace(v))
+			if err != nil {
+				return nil, fmt.Errorf("应为布尔值")
+			}
+			return b, nil
+		case float64:
+			return v != 0, nil
+		}
+		return nil, fmt.Errorf("应为布尔值")
+	case ParamArray:
+		switch v := value.(type) {
+		case []any:
+			return v, nil
+		case string:
+			var arr []any
+			if err := json.Unmarshal([]byte(v), &arr); err != nil {
+				return nil, fmt.Errorf("应为数组")
+			}
+			return arr, nil
+		}
+		return nil, fmt.Errorf("应为数组")
+	case ParamObject:
+		switch v := value.(type) {
+		case map[string]any:
+			return v, nil
+		case string:
+			var obj map[string]any
+			if err := json.Unmarshal([]byte(v), &obj); err != nil {
+				return nil, fmt.Errorf("应为对象")
+			}
+			return obj, nil
+		}
+		return nil, fmt.Errorf("应为对象")
+	}
+	return nil, fmt.Errorf("不支持的参数类型: %s", paramType)
+}
+
+// checkParamRule 校验参数的可选值、取值范围和正则规则，返回错误信息
+func checkParamRule(item ParamSchema, value any) string {
+	if len(item.Enum) > 0 {
+		matched := false
+		for _, option := range item.Enum {
+			if fmt.Sprint(option) == fmt.Sprint(value) {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return fmt.Sprintf("取值应为 %v 之一", item.Enum)
+		}
+	}
+	if number, ok := paramNumber(value); ok {
+		if item.Min != nil && number < *item.Min {
+			return fmt.Sprintf("不能小于 %v", *item.Min)
+		}
+		if item.Max != nil && number > *item.Max {
+			return fmt.Sprintf("不能大于 %v", *item.Max)
+		}
+	}
+	if item.Pattern != "" {
+		s, ok := value.(string)
+		if !ok {
+			return ""
+		}
+		re, err := regexp.Compile(item.Pattern)
+		if err != nil {
+			return fmt.Sprintf("正则表达式配置错误: %v", err)
+		}
+		if !re.MatchString(s) {
+			return fmt.Sprintf("格式不正确，应匹配 %s", item.Pattern)
+		}
+	}
+	return ""
+}
+
+// paramNumber 获取转换后的数值参数，非数值参数返回false
+func paramNumber(value any) (float64, bool) {
+	switch v := value.(type) {
+	case int64:
+		return float64(v), true
+	case float64:
+		return v, true
+	}
+	return 0, false
+}
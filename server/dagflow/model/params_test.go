@@ -0,0 +1,176 @@
+package model
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// floatPtr 返回浮点数指针，用于设置取值范围
+func floatPtr(v float64) *float64 {
+	return &v
+}
+
+// TestConvertParam 测试参数按声明的类型转换
+func TestConvertParam(t *testing.T) {
+	tests := []struct {
+		name      string
+		paramType ParamType
+		value     any
+		want      any
+		wantErr   bool
+	}{
+		{"未声明类型原样返回", "", 12, 12, false},
+		{"字符串", ParamString, "abc", "abc", false},
+		{"数字转字符串", ParamString, 12.5, "12.5", false},
+		{"布尔值转字符串", ParamString, true, "true", false},
+		{"对象不能转字符串", ParamString, map[string]any{"a": 1}, nil, true},
+		{"数组不能转字符串", ParamString, []any{1}, nil, true},
+		{"int转整数", ParamInt, 3, int64(3), false},
+		{"JSON数字转整数", ParamInt, float64(42), int64(42), false},
+		{"小数不能转整数", ParamInt, 1.5, nil, true},
+		{"json.Number转整数", ParamInt, json.Number("7"), int64(7), false},
+		{"字符串转整数", ParamInt, " 15 ", int64(15), false},
+		{"非数字字符串不能转整数", ParamInt, "abc", nil, true},
+		{"布尔值不能转整数", ParamInt, true, nil, true},
+		{"整数转浮点数", ParamFloat, 3, float64(3), false},
+		{"int64转浮点数", ParamFloat, int64(4), float64(4), false},
+		{"字符串转浮点数", ParamFloat, "2.5", 2.5, false},
+		{"json.Number转浮点数", ParamFloat, json.Number("1.25"), 1.25, false},
+		{"非数字字符串不能转浮点数", ParamFloat, "x", nil, true},
+		{"布尔值", ParamBool, false, false, false},
+		{"字符串转布尔值", ParamBool, "true", true, false},
+		{"数字1转布尔值", ParamBool, float64(1), true, false},
+		{"数字0转布尔值", ParamBool, float64(0), false, false},
+		{"无效字符串不能转布尔值", ParamBool, "yes", nil, true},
+		{"数组", ParamArray, []any{1, "a"}, []any{1, "a"}, false},
+		{"JSON字符串转数组", ParamArray, `[1,"a"]`, []any{float64(1), "a"}, false},
+		{"无效JSON不能转数组", ParamArray, `{"a":1}`, nil, true},
+		{"对象", ParamObject, map[string]any{"a": 1}, map[string]any{"a": 1}, false},
+		{"JSON字符串转对象", ParamObject, `{"a":1}`, map[string]any{"a": float64(1)}, false},
+		{"数组不能转对象", ParamObject, []any{1}, nil, true},
+		{"不支持的类型", ParamType("date"), "2024-01-01", nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := convertParam(tt.paramType, tt.value)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+// TestCheckParamRule 测试可选值、取值范围和正则规则
+func TestCheckParamRule(t *testing.T) {
+	tests := []struct {
+		name   string
+		item   ParamSchema
+		value  any
+		wantOK bool
+	}{
+		{"无规则", ParamSchema{Name: "a"}, "x", true},
+		{"在可选值中", ParamSchema{Name: "env", Enum: []any{"dev", "prod"}}, "prod", true},
+		{"不在可选值中", ParamSchema{Name: "env", Enum: []any{"dev", "prod"}}, "test", false},
+		{"JSON数字可选值匹配整数", ParamSchema{Name: "n", Enum: []any{float64(1), float64(2)}}, int64(2), true},
+		{"整数不小于最小值", ParamSchema{Name: "n", Min: floatPtr(1)}, int64(1), true},
+		{"整数小于最小值", ParamSchema{Name: "n", Min: floatPtr(1)}, int64(0), false},
+		{"浮点数不大于最大值", ParamSchema{Name: "n", Max: floatPtr(1.5)}, 1.5, true},
+		{"浮点数大于最大值", ParamSchema{Name: "n", Max: floatPtr(1.5)}, 1.6, false},
+		{"在取值范围内", ParamSchema{Name: "n", Min: floatPtr(1), Max: floatPtr(10)}, int64(5), true},
+		{"取值范围不校验字符串", ParamSchema{Name: "s", Min: floatPtr(10)}, "1", true},
+		{"匹配正则", ParamSchema{Name: "v", Pattern: `^v\d+$`}, "v12", true},
+		{"不匹配正则", ParamSchema{Name: "v", Pattern: `^v\d+$`}, "12", false},
+		{"正则不校验非字符串", ParamSchema{Name: "v", Pattern: `^v\d+$`}, int64(12), true},
+		{"正则配置错误", ParamSchema{Name: "v", Pattern: `(`}, "a", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			msg := checkParamRule(tt.item, tt.value)
+			assert.Equal(t, tt.wantOK, msg == "", msg)
+		})
+	}
+}
+
+// TestValidateParams 测试必填、默认值、类型转换和字段级错误
+func TestValidateParams(t *testing.T) {
+	schema := []ParamSchema{
+		{Name: "host", Type: ParamString, Required: true},
+		{Name: "port", Type: ParamInt, Default: float64(22), Min: floatPtr(1), Max: floatPtr(65535)},
+		{Name: "env", Type: ParamString, Default: "dev", Enum: []any{"dev", "prod"}},
+		{Name: "tags", Type: ParamArray},
+		{Name: "", Type: ParamInt, Required: true},
+	}
+	tests := []struct {
+		name       string
+		params     map[string]any
+		want       map[string]any
+		wantFields []string
+	}{
+		{
+			name:   "使用默认值并保留未声明的参数",
+			params: map[string]any{"host": "a", "extra": 1},
+			want:   map[string]any{"host": "a", "port": int64(22), "env": "dev", "extra": 1},
+		},
+		{
+			name:   "转换类型",
+			params: map[string]any{"host": "a", "port": "2222", "env": "prod", "tags": `["x"]`},
+			want:   map[string]any{"host": "a", "port": int64(2222), "env": "prod", "tags": []any{"x"}},
+		},
+		{
+			name:   "空字符串按未填写处理",
+			params: map[string]any{"host": "a", "port": "", "tags": ""},
+			want:   map[string]any{"host": "a", "port": int64(22), "env": "dev"},
+		},
+		{
+			name:       "缺少必填参数",
+			params:     map[string]any{},
+			wantFields: []string{"host"},
+		},
+		{
+			name:       "必填参数为nil",
+			params:     map[string]any{"host": nil},
+			wantFields: []string{"host"},
+		},
+		{
+			name:       "返回每个字段的错误",
+			params:     map[string]any{"host": "a", "port": "abc", "env": "test", "tags": 1},
+			wantFields: []string{"port", "env", "tags"},
+		},
+		{
+			name:       "超出取值范围",
+			params:     map[string]any{"host": "a", "port": float64(70000)},
+			wantFields: []string{"port"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ValidateParams(schema, tt.params)
+			if len(tt.wantFields) == 0 {
+				assert.NoError(t, err)
+				assert.Equal(t, tt.want, got)
+				return
+			}
+			assert.Nil(t, got)
+			var errs ParamErrors
+			assert.ErrorAs(t, err, &errs)
+			fields := make([]string, 0, len(errs))
+			for _, item := range errs {
+				fields = append(fields, item.Field)
+			}
+			assert.Equal(t, tt.wantFields, fields)
+		})
+	}
+}
+
+// TestValidateParamsNoSchema 测试未声明参数时原样返回输入参数
+func TestValidateParamsNoSchema(t *testing.T) {
+	params := map[string]any{"a": "1"}
+	got, err := ValidateParams(nil, params)
+	assert.NoError(t, err)
+	assert.Equal(t, params, got)
+}
@@ -0,0 +1,96 @@
+// 示例插件，演示使用sdk编写DAGFlow插件
+//
+// 编译后放入插件目录（默认 data/workdir/plugins）即可在流程中使用：
+//
+//	go build -o data/workdir/plugins/example ./dagflow/plugin/example
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"server/dagflow/plugin/protocol"
+	"server/dagflow/plugin/sdk"
+	"strings"
+	"time"
+)
+
+func main() {
+	err := sdk.Serve(sdk.Plugin{
+		Name:    "example",
+		Version: "1.0.0",
+		NodeTypes: []sdk.NodeType{
+			{
+				NodeType: protocol.NodeType{
+					Type:        "example.text",
+					Name:        "文本转换",
+					Description: "对文本进行大小写转换或反转",
+					Properties: []protocol.Property{
+						{Name: "text", Label: "文本", Type: "string", Required: true},
+						{Name: "mode", Label: "方式", Type: "string", Default: "upper", Description: "upper、lower、reverse"},
+					},
+				},
+				Handle:   handleText,
+				Validate: validateText,
+			},
+			{
+				NodeType: protocol.NodeType{
+					Type:        "example.wait",
+					Name:        "等待",
+					Description: "等待指定秒数，每秒输出一次日志，可被取消",
+					Properties: []protocol.Property{
+						{Name: "seconds", Label: "秒数", Type: "number", Default: 3},
+					},
+				},
+				Handle: handleWait,
+			},
+		},
+	})
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+// handleText 文本转换
+func handleText(ctx *sdk.Context) (any, error) {
+	text := ctx.String("text", "")
+	switch ctx.String("mode", "upper") {
+	case "lower":
+		return strings.ToLower(text), nil
+	case "reverse":
+		runes := []rune(text)
+		for i, j := 0, len(runes)-1; i < j; i, j = i+1, j-1 {
+			runes[i], runes[j] = runes[j], runes[i]
+		}
+		return string(runes), nil
+	default:
+		return strings.ToUpper(text), nil
+	}
+}
+
+// validateText 校验转换方式
+func validateText(node protocol.Node) error {
+	switch mode, _ := node.Properties["mode"].(string); mode {
+	case "", "upper", "lower", "reverse":
+		return nil
+	default:
+		return errors.New("不支持的转换方式: " + mode)
+	}
+}
+
+// handleWait 等待指定秒数
+func handleWait(ctx *sdk.Context) (any, error) {
+	seconds := ctx.Int("seconds", 3)
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+	for i := 1; i <= seconds; i++ {
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-ticker.C:
+			ctx.Log("info", "已等待 %d/%d 秒", i, seconds)
+		}
+	}
+	return map[string]any{"seconds": seconds}, nil
+}
@@ -0,0 +1,85 @@
+package plugin
+
+import (
+	"context"
+	"fmt"
+	"server/dagflow/model"
+	"server/dagflow/plugin/protocol"
+	"time"
+)
+
+var (
+	// handleTimeout 节点执行的最长时间，节点设置了超时时间时以节点设置为准
+	handleTimeout = 30 * time.Minute
+	// validateTimeout 校验节点配置的超时时间
+	validateTimeout = 10 * time.Second
+)
+
+// ProxyHandler 插件节点处理器，将节点的执行和校验转发给插件进程
+type ProxyHandler struct {
+	plugin   *Plugin
+	nodeType protocol.NodeType
+}
+
+// GetType 获取处理器类型
+func (h *ProxyHandler) GetType() string {
+	return h.nodeType.Type
+}
+
+// SideEffect 节点类型是否有副作用
+func (h *ProxyHandler) SideEffect() bool {
+	return h.nodeType.SideEffect
+}
+
+// Handle 调用插件执行节点，上下文取消时通知插件取消执行
+func (h *ProxyHandler) Handle(ctx context.Context, node model.TaskNode, execCtx *model.ExecutionContext) (any, error) {
+	if node.Timeout <= 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, handleTimeout)
+		defer cancel()
+	}
+	params := protocol.HandleParams{
+		Node:        toNode(node),
+		FlowID:      execCtx.FlowID,
+		ExecutionID: execCtx.ExecutionID,
+		Params:      execCtx.Params,
+		Data:        execCtx.DataSnapshot(),
+	}
+	var result protocol.HandleResult
+	err := h.plugin.call(ctx, protocol.MethodHandle, params, &result, func(level, message string) {
+		execCtx.Log(level, "[%s] %s", node.Name, message)
+	})
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil, context.Cause(ctx)
+		}
+		return nil, err
+	}
+	return result.Result, nil
+}
+
+// Validate 校验必填属性后调用插件校验节点配置
+func (h *ProxyHandler) Validate(node model.TaskNode) error {
+	for _, property := range h.nodeType.Properties {
+		if !property.Required {
+			continue
+		}
+		if value, ok := node.Properties[property.Name]; !ok || value == nil || value == "" {
+			return fmt.Errorf("%s节点配置错误：缺少属性 %s", h.nodeType.Name, property.Name)
+		}
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), validateTimeout)
+	defer cancel()
+	return h.plugin.call(ctx, protocol.MethodValidate, protocol.ValidateParams{Node: toNode(node)}, nil, nil)
+}
+
+// toNode 转换为协议中的节点配置
+func toNode(node model.TaskNode) protocol.Node {
+	return protocol.Node{
+		ID:         node.ID,
+		Name:       node.Name,
+		Type:       node.Type,
+		Timeout:    node.Timeout,
+		Properties: node.Properties,
+	}
+}
@@ -0,0 +1,106 @@
+package plugin
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"server/dagflow/handler"
+	"server/dagflow/model"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Manager 插件管理器，加载插件目录中的插件并注册插件提供的节点类型
+type Manager struct {
+	dir     string
+	logger  model.LoggerInterface
+	mu      sync.Mutex
+	plugins []*Plugin
+}
+
+// NewManager 创建插件管理器
+func NewManager(dir string, logger model.LoggerInterface) *Manager {
+	return &Manager{dir: dir, logger: logger}
+}
+
+// Load 启动插件目录中的所有插件，将插件的节点类型注册为代理处理器
+// 插件启动失败时跳过该插件，与已注册处理器同名的节点类型不会覆盖原处理器
+func (m *Manager) Load(registry *handler.HandlerRegistry) {
+	entries, err := os.ReadDir(m.dir)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			m.logger.Error("读取插件目录失败: %v", err)
+		}
+		return
+	}
+	for _, entry := range entries {
+		path := filepath.Join(m.dir, entry.Name())
+		if !isExecutable(entry) {
+			continue
+		}
+		p := newPlugin(path, m.logger)
+		if err := p.start(); err != nil {
+			m.logger.Error("加载插件 %s 失败: %v", entry.Name(), err)
+			continue
+		}
+		registered := make([]string, 0, len(p.info.NodeTypes))
+		for _, nodeType := range p.info.NodeTypes {
+			if _, err := registry.Get(nodeType.Type); err == nil {
+				m.logger.Warn("插件 %s 的节点类型 %s 已存在，忽略", p.name(), nodeType.Type)
+				continue
+			}
+			registry.Register(&ProxyHandler{plugin: p, nodeType: nodeType})
+			registered = append(registered, nodeType.Type)
+		}
+		m.mu.Lock()
+		m.plugins = append(m.plugins, p)
+		m.mu.Unlock()
+		m.logger.Info("插件 %s (%s) 已加载，节点类型: %s", p.name(), p.info.Version, strings.Join(registered, ", "))
+	}
+}
+
+// List 获取已加载的插件
+func (m *Manager) List() []Info {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	list := make([]Info, 0, len(m.plugins))
+	for _, p := range m.plugins {
+		list = append(list, p.Info())
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].Name < list[j].Name
+	})
+	return list
+}
+
+// Stop 停止所有插件
+func (m *Manager) Stop() {
+	m.mu.Lock()
+	plugins := m.plugins
+	m.mu.Unlock()
+	var wg sync.WaitGroup
+	for _, p := range plugins {
+		wg.Add(1)
+		go func(p *Plugin) {
+			defer wg.Done()
+			p.stop()
+		}(p)
+	}
+	wg.Wait()
+}
+
+// isExecutable 判断目录项是否为可执行文件
+func isExecutable(entry os.DirEntry) bool {
+	if entry.IsDir() || strings.HasPrefix(entry.Name(), ".") {
+		return false
+	}
+	if runtime.GOOS == "windows" {
+		return strings.EqualFold(filepath.Ext(entry.Name()), ".exe")
+	}
+	info, err := entry.Info()
+	if err != nil {
+		return false
+	}
+	return info.Mode().IsRegular() && info.Mode().Perm()&0111 != 0
+}
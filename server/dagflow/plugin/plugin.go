@@ -0,0 +1,216 @@
+// Package plugin 实现DAGFlow的进程外插件
+// 插件目录中的可执行文件作为子进程启动，握手时声明提供的节点类型，
+// 之后通过标准输入输出上的JSON-RPC执行节点，协议见protocol包，插件可使用sdk包编写
+package plugin
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"server/dagflow/model"
+	"server/dagflow/plugin/protocol"
+	"sync"
+	"time"
+)
+
+// 插件状态
+const (
+	StateRunning    = "running"    // 运行中
+	StateRestarting = "restarting" // 进程退出，等待重启
+	StateStopped    = "stopped"    // 已停止
+)
+
+var (
+	// describeTimeout 握手超时时间
+	describeTimeout = 10 * time.Second
+	// shutdownTimeout 停止插件时等待进程退出的时间
+	shutdownTimeout = 3 * time.Second
+	// maxRestartDelay 插件进程崩溃后的最大重启间隔
+	maxRestartDelay = 30 * time.Second
+	// stableDuration 进程运行超过该时间后重置重启间隔
+	stableDuration = time.Minute
+)
+
+// Info 插件信息
+type Info struct {
+	Path      string              `json:"path"`      // 可执行文件路径
+	Name      string              `json:"name"`      // 插件名称
+	Version   string              `json:"version"`   // 插件版本
+	State     string              `json:"state"`     // 状态
+	Pid       int                 `json:"pid"`       // 进程ID
+	Restarts  int                 `json:"restarts"`  // 重启次数
+	LastError string              `json:"lastError"` // 最近一次退出或启动失败的原因
+	NodeTypes []protocol.NodeType `json:"nodeTypes"` // 提供的节点类型
+}
+
+// Plugin 插件，管理插件进程的启动、握手和崩溃重启
+type Plugin struct {
+	path      string
+	logger    model.LoggerInterface
+	mu        sync.Mutex
+	proc      *process
+	info      protocol.DescribeResult
+	state     string
+	restarts  int
+	lastError string
+	startedAt time.Time
+	stopped   bool
+}
+
+// newPlugin 创建插件
+func newPlugin(path string, logger model.LoggerInterface) *Plugin {
+	return &Plugin{path: path, logger: logger, state: StateStopped}
+}
+
+// name 插件名称，握手前使用文件名
+func (p *Plugin) name() string {
+	if p.info.Name != "" {
+		return p.info.Name
+	}
+	return filepath.Base(p.path)
+}
+
+// start 启动插件进程并握手
+func (p *Plugin) start() error {
+	proc, err := startProcess(p.path, func(line string) {
+		p.logger.Info("[插件 %s] %s", p.name(), line)
+	})
+	if err != nil {
+		return fmt.Errorf("启动插件失败: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), describeTimeout)
+	defer cancel()
+	var info protocol.DescribeResult
+	if err := proc.call(ctx, protocol.MethodDescribe, struct{}{}, &info, nil); err != nil {
+		proc.kill()
+		return fmt.Errorf("插件握手失败: %v", err)
+	}
+	if info.Name == "" {
+		info.Name = filepath.Base(p.path)
+	}
+
+	p.mu.Lock()
+	if p.stopped {
+		p.mu.Unlock()
+		proc.kill()
+		return errors.New("插件已停止")
+	}
+	p.proc = proc
+	p.info = info
+	p.state = StateRunning
+	p.startedAt = time.Now()
+	p.mu.Unlock()
+
+	go p.watch(proc)
+	return nil
+}
+
+// watch 等待插件进程退出，非主动停止时按退避间隔重启
+func (p *Plugin) watch(proc *process) {
+	<-proc.done
+	err := proc.exitError()
+
+	p.mu.Lock()
+	if p.stopped || p.proc != proc {
+		p.mu.Unlock()
+		return
+	}
+	p.proc = nil
+	p.state = StateRestarting
+	p.lastError = err.Error()
+	if time.Since(p.startedAt) > stableDuration {
+		p.restarts = 0
+	}
+	p.mu.Unlock()
+
+	p.logger.Warn("插件 %s 进程退出: %v", p.name(), err)
+	p.restart()
+}
+
+// restart 按退避间隔重启插件，直到启动成功或插件被停止
+func (p *Plugin) restart() {
+	for {
+		p.mu.Lock()
+		if p.stopped {
+			p.mu.Unlock()
+			return
+		}
+		p.restarts++
+		delay := restartDelay(p.restarts)
+		p.mu.Unlock()
+
+		time.Sleep(delay)
+		err := p.start()
+		if err == nil {
+			p.logger.Info("插件 %s 已重启", p.name())
+			return
+		}
+		p.mu.Lock()
+		p.lastError = err.Error()
+		p.mu.Unlock()
+		p.logger.Error("插件 %s 重启失败: %v", p.name(), err)
+	}
+}
+
+// restartDelay 计算第n次重启前的等待时间
+func restartDelay(n int) time.Duration {
+	delay := time.Second
+	for i := 1; i < n && delay < maxRestartDelay; i++ {
+		delay *= 2
+	}
+	return min(delay, maxRestartDelay)
+}
+
+// call 调用插件方法，插件未运行时返回错误
+func (p *Plugin) call(ctx context.Context, method string, params, result any, log logSink) error {
+	p.mu.Lock()
+	proc := p.proc
+	p.mu.Unlock()
+	if proc == nil {
+		return fmt.Errorf("插件 %s 未运行", p.name())
+	}
+	return proc.call(ctx, method, params, result, log)
+}
+
+// stop 停止插件，通知插件退出，超时后结束进程
+func (p *Plugin) stop() {
+	p.mu.Lock()
+	p.stopped = true
+	p.state = StateStopped
+	proc := p.proc
+	p.proc = nil
+	p.mu.Unlock()
+	if proc == nil {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	proc.call(ctx, protocol.MethodShutdown, struct{}{}, nil, nil)
+	select {
+	case <-proc.done:
+	case <-ctx.Done():
+	}
+	proc.kill()
+}
+
+// Info 获取插件信息
+func (p *Plugin) Info() Info {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	info := Info{
+		Path:      p.path,
+		Name:      p.name(),
+		Version:   p.info.Version,
+		State:     p.state,
+		Restarts:  p.restarts,
+		LastError: p.lastError,
+		NodeTypes: p.info.NodeTypes,
+	}
+	if p.proc != nil {
+		info.Pid = p.proc.pid()
+	}
+	return info
+}
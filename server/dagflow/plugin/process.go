@@ -0,0 +1,223 @@
+package plugin
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os/exec"
+	"server/dagflow/plugin/protocol"
+	"sync"
+	"sync/atomic"
+)
+
+// maxMessageSize 单条消息的最大长度
+const maxMessageSize = 64 * 1024 * 1024
+
+// ErrExited 插件进程已退出
+var ErrExited = errors.New("插件进程已退出")
+
+// logSink 接收调用期间插件输出的日志
+type logSink func(level, message string)
+
+// pendingCall 等待响应的调用
+type pendingCall struct {
+	response chan *protocol.Message
+	log      logSink
+}
+
+// process 运行中的插件进程
+// 负责消息的收发，一个进程退出后由Plugin重新启动新的进程
+type process struct {
+	cmd     *exec.Cmd
+	stdin   io.WriteCloser
+	writeMu sync.Mutex
+	nextID  atomic.Int64
+	mu      sync.Mutex
+	pending map[int64]*pendingCall
+	stderr  sync.WaitGroup // 标准错误读取完成
+	done    chan struct{}  // 进程输出关闭后关闭
+	err     error          // 进程退出的原因
+}
+
+// startProcess 启动插件进程并开始读取消息
+func startProcess(path string, stderr func(line string)) (*process, error) {
+	cmd := exec.Command(path)
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, err
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	errPipe, err := cmd.StderrPipe()
+	if err != nil {
+		return nil, err
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, err
+	}
+	p := &process{
+		cmd:     cmd,
+		stdin:   stdin,
+		pending: make(map[int64]*pendingCall),
+		done:    make(chan struct{}),
+	}
+	p.stderr.Add(1)
+	go func() {
+		defer p.stderr.Done()
+		scanner := bufio.NewScanner(errPipe)
+		for scanner.Scan() {
+			stderr(scanner.Text())
+		}
+	}()
+	go p.read(stdout)
+	return p, nil
+}
+
+// read 读取插件输出的消息，输出关闭后结束所有等待中的调用
+func (p *process) read(stdout io.Reader) {
+	scanner := bufio.NewScanner(stdout)
+	scanner.Buffer(make([]byte, 64*1024), maxMessageSize)
+	for scanner.Scan() {
+		var msg protocol.Message
+		if err := json.Unmarshal(scanner.Bytes(), &msg); err != nil {
+			continue
+		}
+		p.dispatch(&msg)
+	}
+	err := scanner.Err()
+	// Wait会关闭标准错误管道，需要等标准错误读取完成后再调用
+	p.stderr.Wait()
+	if waitErr := p.cmd.Wait(); err == nil {
+		err = waitErr
+	}
+	if err == nil {
+		err = ErrExited
+	} else {
+		err = fmt.Errorf("%w: %v", ErrExited, err)
+	}
+	p.mu.Lock()
+	p.err = err
+	p.mu.Unlock()
+	close(p.done)
+}
+
+// dispatch 分发插件消息：日志通知转给对应调用，响应交给等待的调用
+func (p *process) dispatch(msg *protocol.Message) {
+	if msg.Method == protocol.MethodLog {
+		var params protocol.LogParams
+		if json.Unmarshal(msg.Params, &params) != nil {
+			return
+		}
+		p.mu.Lock()
+		call := p.pending[params.ID]
+		p.mu.Unlock()
+		if call != nil && call.log != nil {
+			call.log(params.Level, params.Message)
+		}
+		return
+	}
+	if msg.ID == nil {
+		return
+	}
+	p.mu.Lock()
+	call := p.pending[*msg.ID]
+	delete(p.pending, *msg.ID)
+	p.mu.Unlock()
+	if call != nil {
+		call.response <- msg
+	}
+}
+
+// write 发送一条消息
+func (p *process) write(msg protocol.Message) error {
+	msg.JSONRPC = protocol.Version
+	bytes, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	p.writeMu.Lock()
+	defer p.writeMu.Unlock()
+	_, err = p.stdin.Write(append(bytes, '\n'))
+	return err
+}
+
+// notify 发送通知
+func (p *process) notify(method string, params any) error {
+	raw, err := json.Marshal(params)
+	if err != nil {
+		return err
+	}
+	return p.write(protocol.Message{Method: method, Params: raw})
+}
+
+// call 调用插件方法并等待响应
+// 上下文结束时向插件发送取消通知，不再等待响应
+func (p *process) call(ctx context.Context, method string, params, result any, log logSink) error {
+	raw, err := json.Marshal(params)
+	if err != nil {
+		return fmt.Errorf("参数序列化失败: %v", err)
+	}
+	id := p.nextID.Add(1)
+	call := &pendingCall{response: make(chan *protocol.Message, 1), log: log}
+	p.mu.Lock()
+	p.pending[id] = call
+	p.mu.Unlock()
+	defer func() {
+		p.mu.Lock()
+		delete(p.pending, id)
+		p.mu.Unlock()
+	}()
+
+	if err := p.write(protocol.Message{ID: &id, Method: method, Params: raw}); err != nil {
+		return fmt.Errorf("发送请求失败: %v", err)
+	}
+
+	select {
+	case msg := <-call.response:
+		if msg.Error != nil {
+			return msg.Error
+		}
+		if result != nil && len(msg.Result) > 0 {
+			if err := json.Unmarshal(msg.Result, result); err != nil {
+				return fmt.Errorf("解析插件响应失败: %v", err)
+			}
+		}
+		return nil
+	case <-ctx.Done():
+		p.notify(protocol.MethodCancel, protocol.CancelParams{ID: id})
+		return ctx.Err()
+	case <-p.done:
+		return p.exitError()
+	}
+}
+
+// exitError 获取进程退出的原因
+func (p *process) exitError() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.err == nil {
+		return ErrExited
+	}
+	return p.err
+}
+
+// pid 获取进程ID
+func (p *process) pid() int {
+	if p.cmd.Process == nil {
+		return 0
+	}
+	return p.cmd.Process.Pid
+}
+
+// kill 结束进程
+func (p *process) kill() {
+	p.stdin.Close()
+	if p.cmd.Process != nil {
+		p.cmd.Process.Kill()
+	}
+}
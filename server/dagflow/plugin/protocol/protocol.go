@@ -0,0 +1,127 @@
+// Package protocol 定义DAGFlow插件与主程序之间的JSON-RPC通信协议
+//
+// 插件是独立的可执行文件，由主程序作为子进程启动，通过标准输入输出交换消息，
+// 每行一条JSON-RPC 2.0消息，标准错误输出作为插件日志。
+//
+// 主程序调用的方法：
+//   - describe 握手，插件返回名称、版本和提供的节点类型
+//   - validate 校验节点配置
+//   - handle   执行节点
+//   - shutdown 通知插件退出
+//
+// 通知（不需要响应）：
+//   - cancel 主程序取消执行中的调用，参数为调用ID
+//   - log    插件输出执行日志，写入流程执行日志
+package protocol
+
+import "encoding/json"
+
+// Version JSON-RPC协议版本
+const Version = "2.0"
+
+// 方法名
+const (
+	MethodDescribe = "describe"
+	MethodValidate = "validate"
+	MethodHandle   = "handle"
+	MethodShutdown = "shutdown"
+	MethodCancel   = "cancel"
+	MethodLog      = "log"
+)
+
+// 错误码
+const (
+	CodeParseError     = -32700 // 消息格式错误
+	CodeMethodNotFound = -32601 // 方法不存在
+	CodeInvalidParams  = -32602 // 参数错误
+	CodeHandlerError   = 1      // 节点执行或校验失败
+	CodeCancelled      = 2      // 调用已取消
+)
+
+// Message JSON-RPC消息，请求、响应和通知共用
+// 请求包含ID和Method，通知只包含Method，响应包含ID和Result或Error
+type Message struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      *int64          `json:"id,omitempty"`
+	Method  string          `json:"method,omitempty"`
+	Params  json.RawMessage `json:"params,omitempty"`
+	Result  json.RawMessage `json:"result,omitempty"`
+	Error   *Error          `json:"error,omitempty"`
+}
+
+// Error JSON-RPC错误
+type Error struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+// Error 实现error接口
+func (e *Error) Error() string {
+	return e.Message
+}
+
+// Property 节点属性定义，用于流程设计器生成配置表单和校验必填项
+type Property struct {
+	Name        string `json:"name"`                  // 属性名
+	Label       string `json:"label"`                 // 显示名称
+	Type        string `json:"type"`                  // 类型：string、number、boolean、json
+	Required    bool   `json:"required"`              // 是否必填
+	Default     any    `json:"default,omitempty"`     // 默认值
+	Description string `json:"description,omitempty"` // 说明
+}
+
+// NodeType 插件提供的节点类型
+type NodeType struct {
+	Type        string     `json:"type"`        // 节点类型，在流程中唯一
+	Name        string     `json:"name"`        // 显示名称
+	Description string     `json:"description"` // 说明
+	Properties  []Property `json:"properties"`  // 属性定义
+	SideEffect  bool       `json:"sideEffect"`  // 是否有副作用，演练回放时不执行
+}
+
+// DescribeResult 握手结果
+type DescribeResult struct {
+	Name      string     `json:"name"`      // 插件名称
+	Version   string     `json:"version"`   // 插件版本
+	NodeTypes []NodeType `json:"nodeTypes"` // 提供的节点类型
+}
+
+// Node 节点配置
+type Node struct {
+	ID         string         `json:"id"`         // 节点ID
+	Name       string         `json:"name"`       // 节点名称
+	Type       string         `json:"type"`       // 节点类型
+	Timeout    int            `json:"timeout"`    // 执行超时时间(秒)
+	Properties map[string]any `json:"properties"` // 节点属性
+}
+
+// ValidateParams validate方法参数
+type ValidateParams struct {
+	Node Node `json:"node"`
+}
+
+// HandleParams handle方法参数
+type HandleParams struct {
+	Node        Node           `json:"node"`        // 节点配置
+	FlowID      uint           `json:"flowId"`      // 流程ID
+	ExecutionID string         `json:"executionId"` // 执行ID
+	Params      map[string]any `json:"params"`      // 流程输入参数
+	Data        map[string]any `json:"data"`        // 流程当前数据，包含已完成节点的结果
+}
+
+// HandleResult handle方法结果
+type HandleResult struct {
+	Result any `json:"result"` // 节点结果，保存到流程数据中
+}
+
+// CancelParams cancel通知参数
+type CancelParams struct {
+	ID int64 `json:"id"` // 要取消的调用ID
+}
+
+// LogParams log通知参数
+type LogParams struct {
+	ID      int64  `json:"id"`      // 产生日志的调用ID
+	Level   string `json:"level"`   // 日志级别：debug、info、warn、error
+	Message string `json:"message"` // 日志内容
+}
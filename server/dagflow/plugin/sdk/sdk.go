@@ -0,0 +1,295 @@
+// Package sdk 用于编写DAGFlow插件
+//
+// 插件是一个独立的可执行程序，在main函数中调用Serve即可：
+//
+//	func main() {
+//		sdk.Serve(sdk.Plugin{
+//			Name:    "demo",
+//			Version: "1.0.0",
+//			NodeTypes: []sdk.NodeType{{
+//				NodeType: protocol.NodeType{Type: "demo.hello", Name: "问候"},
+//				Handle: func(ctx *sdk.Context) (any, error) {
+//					return "hello " + ctx.String("name", "world"), nil
+//				},
+//			}},
+//		})
+//	}
+//
+// 编译后放入插件目录，主程序启动时自动加载。标准输出用于通信，插件不要向标准输出打印内容，
+// 调试信息请写入标准错误输出或使用Context.Log写入流程执行日志。
+package sdk
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"server/dagflow/plugin/protocol"
+	"sync"
+)
+
+// HandleFunc 执行节点，返回的结果保存到流程数据中
+type HandleFunc func(ctx *Context) (any, error)
+
+// ValidateFunc 校验节点配置
+type ValidateFunc func(node protocol.Node) error
+
+// NodeType 插件提供的节点类型及其实现
+type NodeType struct {
+	protocol.NodeType
+	Handle   HandleFunc   // 执行节点，必须设置
+	Validate ValidateFunc // 校验节点配置，可选
+}
+
+// Plugin 插件定义
+type Plugin struct {
+	Name      string     // 插件名称
+	Version   string     // 插件版本
+	NodeTypes []NodeType // 提供的节点类型
+}
+
+// Context 节点执行上下文
+// 主程序取消执行（节点超时、流程取消）时上下文随之取消
+type Context struct {
+	context.Context
+	Node        protocol.Node  // 节点配置
+	FlowID      uint           // 流程ID
+	ExecutionID string         // 执行ID
+	Params      map[string]any // 流程输入参数
+	Data        map[string]any // 流程当前数据
+	log         func(level, message string)
+}
+
+// Log 写入流程执行日志
+func (c *Context) Log(level, format string, args ...any) {
+	c.log(level, fmt.Sprintf(format, args...))
+}
+
+// Property 获取节点属性
+func (c *Context) Property(name string) (any, bool) {
+	value, ok := c.Node.Properties[name]
+	return value, ok
+}
+
+// String 获取字符串类型的节点属性
+func (c *Context) String(name, defval string) string {
+	if value, ok := c.Node.Properties[name]; ok && value != nil && value != "" {
+		return fmt.Sprint(value)
+	}
+	return defval
+}
+
+// Int 获取整数类型的节点属性
+func (c *Context) Int(name string, defval int) int {
+	switch v := c.Node.Properties[name].(type) {
+	case float64:
+		return int(v)
+	case string:
+		var i int
+		if _, err := fmt.Sscan(v, &i); err == nil {
+			return i
+		}
+	}
+	return defval
+}
+
+// server 插件服务端
+type server struct {
+	plugin  Plugin
+	types   map[string]NodeType
+	out     io.Writer
+	writeMu sync.Mutex
+	mu      sync.Mutex
+	calls   map[int64]context.CancelFunc
+	wg      sync.WaitGroup
+}
+
+// Serve 在标准输入输出上提供插件服务，主程序通知退出或关闭标准输入时返回
+func Serve(plugin Plugin) error {
+	return ServeIO(plugin, os.Stdin, os.Stdout)
+}
+
+// ServeIO 在指定的输入输出上提供插件服务，便于测试
+func ServeIO(plugin Plugin, in io.Reader, out io.Writer) error {
+	s := &server{
+		plugin: plugin,
+		types:  make(map[string]NodeType),
+		out:    out,
+		calls:  make(map[int64]context.CancelFunc),
+	}
+	for _, nodeType := range plugin.NodeTypes {
+		s.types[nodeType.Type] = nodeType
+	}
+	defer s.cancelAll()
+
+	scanner := bufio.NewScanner(in)
+	scanner.Buffer(make([]byte, 64*1024), 64*1024*1024)
+	for scanner.Scan() {
+		var msg protocol.Message
+		if err := json.Unmarshal(scanner.Bytes(), &msg); err != nil {
+			s.reply(nil, nil, &protocol.Error{Code: protocol.CodeParseError, Message: err.Error()})
+			continue
+		}
+		switch msg.Method {
+		case protocol.MethodShutdown:
+			s.cancelAll()
+			s.wg.Wait()
+			s.reply(msg.ID, struct{}{}, nil)
+			return nil
+		case protocol.MethodCancel:
+			var params protocol.CancelParams
+			if json.Unmarshal(msg.Params, &params) == nil {
+				s.cancel(params.ID)
+			}
+		default:
+			s.wg.Add(1)
+			go func(msg protocol.Message) {
+				defer s.wg.Done()
+				result, err := s.call(msg)
+				s.reply(msg.ID, result, err)
+			}(msg)
+		}
+	}
+	return scanner.Err()
+}
+
+// call 执行主程序的调用
+func (s *server) call(msg protocol.Message) (any, *protocol.Error) {
+	switch msg.Method {
+	case protocol.MethodDescribe:
+		types := make([]protocol.NodeType, 0, len(s.plugin.NodeTypes))
+		for _, nodeType := range s.plugin.NodeTypes {
+			types = append(types, nodeType.NodeType)
+		}
+		return protocol.DescribeResult{Name: s.plugin.Name, Version: s.plugin.Version, NodeTypes: types}, nil
+
+	case protocol.MethodValidate:
+		var params protocol.ValidateParams
+		if err := json.Unmarshal(msg.Params, &params); err != nil {
+			return nil, &protocol.Error{Code: protocol.CodeInvalidParams, Message: err.Error()}
+		}
+		nodeType, ok := s.types[params.Node.Type]
+		if !ok {
+			return nil, &protocol.Error{Code: protocol.CodeInvalidParams, Message: "不支持的节点类型: " + params.Node.Type}
+		}
+		if nodeType.Validate != nil {
+			if err := nodeType.Validate(params.Node); err != nil {
+				return nil, &protocol.Error{Code: protocol.CodeHandlerError, Message: err.Error()}
+			}
+		}
+		return nil, nil
+
+	case protocol.MethodHandle:
+		var params protocol.HandleParams
+		if err := json.Unmarshal(msg.Params, &params); err != nil {
+			return nil, &protocol.Error{Code: protocol.CodeInvalidParams, Message: err.Error()}
+		}
+		nodeType, ok := s.types[params.Node.Type]
+		if !ok || nodeType.Handle == nil {
+			return nil, &protocol.Error{Code: protocol.CodeInvalidParams, Message: "不支持的节点类型: " + params.Node.Type}
+		}
+		return s.handle(msg.ID, nodeType, params)
+	}
+	return nil, &protocol.Error{Code: protocol.CodeMethodNotFound, Message: "方法不存在: " + msg.Method}
+}
+
+// handle 执行节点，可被主程序的取消通知中断
+func (s *server) handle(id *int64, nodeType NodeType, params protocol.HandleParams) (any, *protocol.Error) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	var callID int64
+	if id != nil {
+		callID = *id
+		s.mu.Lock()
+		s.calls[callID] = cancel
+		s.mu.Unlock()
+		defer func() {
+			s.mu.Lock()
+			delete(s.calls, callID)
+			s.mu.Unlock()
+		}()
+	}
+
+	hctx := &Context{
+		Context:     ctx,
+		Node:        params.Node,
+		FlowID:      params.FlowID,
+		ExecutionID: params.ExecutionID,
+		Params:      params.Params,
+		Data:        params.Data,
+		log: func(level, message string) {
+			s.notify(protocol.MethodLog, protocol.LogParams{ID: callID, Level: level, Message: message})
+		},
+	}
+	result, err := func() (result any, err error) {
+		defer func() {
+			if r := recover(); r != nil {
+				err = fmt.Errorf("节点执行异常: %v", r)
+			}
+		}()
+		return nodeType.Handle(hctx)
+	}()
+	if err != nil {
+		code := protocol.CodeHandlerError
+		if ctx.Err() != nil {
+			code = protocol.CodeCancelled
+		}
+		return nil, &protocol.Error{Code: code, Message: err.Error()}
+	}
+	return protocol.HandleResult{Result: result}, nil
+}
+
+// cancel 取消执行中的调用
+func (s *server) cancel(id int64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if cancel, ok := s.calls[id]; ok {
+		cancel()
+	}
+}
+
+// cancelAll 取消所有执行中的调用
+func (s *server) cancelAll() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, cancel := range s.calls {
+		cancel()
+	}
+}
+
+// reply 发送响应
+func (s *server) reply(id *int64, result any, callErr *protocol.Error) {
+	msg := protocol.Message{ID: id, Error: callErr}
+	if callErr == nil {
+		raw, err := json.Marshal(result)
+		if err != nil {
+			msg.Error = &protocol.Error{Code: protocol.CodeHandlerError, Message: "结果序列化失败: " + err.Error()}
+		} else {
+			msg.Result = raw
+		}
+	}
+	s.write(msg)
+}
+
+// notify 发送通知
+func (s *server) notify(method string, params any) {
+	raw, err := json.Marshal(params)
+	if err != nil {
+		return
+	}
+	s.write(protocol.Message{Method: method, Params: raw})
+}
+
+// write 写入一条消息
+func (s *server) write(msg protocol.Message) {
+	msg.JSONRPC = protocol.Version
+	bytes, err := json.Marshal(msg)
+	if err != nil {
+		return
+	}
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+	s.out.Write(append(bytes, '\n'))
+}
@@ -0,0 +1,95 @@
+package dagflow
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"server/dagflow/engine"
+	"server/dagflow/handler"
+	"server/dagflow/model"
+	"server/service/sflow"
+)
+
+// ReplayOptions 回放选项
+type ReplayOptions struct {
+	Params     map[string]any `json:"params"`     // 覆盖原执行的输入参数，未设置的参数使用原值
+	UseCurrent bool           `json:"useCurrent"` // 使用流程的当前版本，默认使用原执行的版本
+	Dry        bool           `json:"dry"`        // 演练模式，有副作用的节点不执行，使用原执行记录的结果
+}
+
+// Replay 按原执行的输入参数和流程版本创建新的执行，返回新的执行ID
+// 新执行异步运行，记录中保存回放的原执行ID
+func (s *Service) Replay(executionID string, options ReplayOptions) (string, error) {
+	record, err := sflow.SFlowLog{}.FindByExecution(executionID)
+	if err != nil {
+		return "", fmt.Errorf("查询执行记录失败: %v", err)
+	}
+
+	flow, err := s.replayFlow(record, options.UseCurrent)
+	if err != nil {
+		return "", err
+	}
+
+	params := make(map[string]any)
+	if record.Params != "" && record.Params != "null" {
+		if err := json.Unmarshal([]byte(record.Params), &params); err != nil {
+			return "", fmt.Errorf("解析原执行的输入参数失败: %v", err)
+		}
+	}
+	for name, value := range options.Params {
+		params[name] = value
+	}
+	if _, err := model.ValidateParams(flow.Params, params); err != nil {
+		return "", err
+	}
+
+	var opts []engine.RunOption
+	if options.Dry {
+		results := make(map[string]TestMock)
+		if record.Results != "" {
+			if err := json.Unmarshal([]byte(record.Results), &results); err != nil {
+				return "", fmt.Errorf("解析原执行的节点结果失败: %v", err)
+			}
+		}
+		opts = append(opts, engine.WithHandlerOverride(s.dryOverride(results)))
+	}
+
+	execCtx := model.NewExecutionContext(flow.ID, params, s.logger)
+	execCtx.ReplayOf = executionID
+	execCtx.Dry = options.Dry
+	s.logger.Info("回放执行: %s -> %s (流程: %s)", executionID, execCtx.ExecutionID, flow.Name)
+	go s.runFlow(context.Background(), flow, execCtx, opts...)
+	return execCtx.ExecutionID, nil
+}
+
+// replayFlow 获取回放使用的流程，默认使用原执行保存的流程版本
+func (s *Service) replayFlow(record sflow.SFlowLog, useCurrent bool) (model.Flow, error) {
+	if useCurrent {
+		return s.loadFlow(fmt.Sprint(record.SFlowId))
+	}
+	if record.Version == "" {
+		return model.Flow{}, errors.New("原执行没有记录流程版本，请使用流程当前版本回放")
+	}
+	version, err := sflow.SFlowVersion{}.Find(record.SFlowId, record.Version)
+	if err != nil {
+		return model.Flow{}, fmt.Errorf("查询流程版本 %s 失败: %v", record.Version, err)
+	}
+	var flow model.Flow
+	if err := json.Unmarshal([]byte(version.Content), &flow); err != nil {
+		return model.Flow{}, fmt.Errorf("解析流程版本 %s 失败: %v", record.Version, err)
+	}
+	return flow, nil
+}
+
+// dryOverride 演练模式的处理器替换函数，有副作用的节点返回原执行记录的结果
+// 原执行中未执行的节点返回空结果
+func (s *Service) dryOverride(results map[string]TestMock) engine.HandlerOverride {
+	return func(node model.TaskNode) handler.TaskHandler {
+		taskHandler, err := s.handlerRegistry.Get(node.Type)
+		if err != nil || !handler.HasSideEffect(taskHandler, node) {
+			return nil
+		}
+		return &mockHandler{nodeType: node.Type, mock: results[node.ID], replay: true}
+	}
+}
@@ -0,0 +1,34 @@
+package dagflow
+
+import (
+	"errors"
+	"fmt"
+	"server/dagflow/core/el"
+	"server/service/secret"
+)
+
+// registerSecretFunction 注册EL表达式中的secret函数
+// 用法: secret("NAME")，按流程所属项目目录解析密钥，解析出的值会在日志和结果中脱敏
+func registerSecretFunction() {
+	el.RegisterScopedFunction("secret", func(scope any, args ...any) (any, error) {
+		if len(args) != 1 {
+			return nil, errors.New("secret函数需要一个参数: 密钥名称")
+		}
+		s, ok := scope.(*secret.Scope)
+		if !ok {
+			return nil, errors.New("当前执行环境不支持访问密钥")
+		}
+		return secret.Resolve(fmt.Sprint(args[0]), s)
+	})
+}
+
+// secretScope 创建流程执行时访问密钥的范围
+func secretScope(flowID uint, flowName, projectDirID string) *secret.Scope {
+	return &secret.Scope{
+		ProjectDirID: projectDirID,
+		EntityType:   secret.EntitySFlow,
+		EntityID:     flowID,
+		EntityName:   flowName,
+		Masker:       secret.NewMasker(),
+	}
+}
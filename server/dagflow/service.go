@@ -4,16 +4,26 @@ package dagflow
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
+	"path/filepath"
+	"server/dagflow/artifact"
 	"server/dagflow/engine"
 	"server/dagflow/handler"
+	"server/dagflow/handler/notify"
 	"server/dagflow/handler/script"
 	"server/dagflow/handler/system"
+	"server/dagflow/handler/wait"
 	"server/dagflow/model"
+	"server/dagflow/plugin"
 	"server/dagflow/utils"
+	"server/data"
+	"server/service/basic"
 	"server/service/sflow"
+	"server/utils/global"
 	"server/utils/logger"
+	"time"
 )
 
 // Service DAGFlow服务
@@ -29,6 +39,12 @@ type Service struct {
 
 	// 日志记录器
 	logger model.LoggerInterface
+
+	// 按流程并发策略控制执行
+	runs *runGate
+
+	// 进程外插件
+	plugins *plugin.Manager
 }
 
 // Logger 适配系统日志记录器
@@ -64,17 +80,28 @@ func NewService() *Service {
 
 	// 创建引擎
 	eng := engine.NewEngine(registry, logger)
+	// 超过大小阈值的节点结果转存为制品
+	eng.SetResultFilter(artifact.Spill)
+	// 注册EL表达式函数
+	registerSecretFunction()
+
 	// 注册基本处理器
 	registry.Register(&system.StartNodeHandler{})
 	registry.Register(&system.EndNodeHandler{})
+	registry.Register(&system.FinallyNodeHandler{})
 	registry.Register(&system.LogNodeHandler{})
 	registry.Register(&system.SleepNodeHandler{})
 
+	// 注册审批和等待信号处理器
+	registry.Register(&wait.ApprovalHandler{})
+	registry.Register(&wait.SignalHandler{})
+
+	// 注册发送通知处理器
+	registry.Register(&notify.NotifyHandler{})
+
 	// 注册JavaScript处理器
 	registry.Register(script.NewJavaScriptHandler())
 
-	// 注册休眠处理器
-
 	// 创建SFlow转换器
 	converter := &utils.FlowConverter{}
 
@@ -83,6 +110,7 @@ func NewService() *Service {
 		handlerRegistry: registry,
 		converter:       converter,
 		logger:          logger,
+		runs:            newRunGate(),
 	}
 }
 
@@ -91,24 +119,135 @@ func (s *Service) RegisterHandler(h handler.TaskHandler) {
 	s.handlerRegistry.Register(h)
 }
 
-// ExecuteFlow 执行流程
-func (s *Service) ExecuteFlow(ctx context.Context, flowID string, params map[string]any, debug bool) (*model.ExecutionContext, error) {
+// LoadPlugins 加载插件目录中的插件，注册插件提供的节点类型
+func (s *Service) LoadPlugins(dir string) {
+	if s.plugins != nil {
+		return
+	}
+	s.plugins = plugin.NewManager(dir, s.logger)
+	s.plugins.Load(s.handlerRegistry)
+}
+
+// StopPlugins 停止所有插件进程
+func (s *Service) StopPlugins() {
+	if s.plugins != nil {
+		s.plugins.Stop()
+	}
+}
+
+// GetPlugins 获取已加载的插件
+func (s *Service) GetPlugins() []plugin.Info {
+	if s.plugins == nil {
+		return []plugin.Info{}
+	}
+	return s.plugins.List()
+}
+
+// loadFlow 从SFlow加载并转换为Flow模型
+func (s *Service) loadFlow(flowID string) (model.Flow, error) {
 	// 从SFlow加载流程
 	sFlow := sflow.SFlow{}
 	sFlow, err := sFlow.Load(flowID)
 	if err != nil {
-		return nil, fmt.Errorf("加载流程失败: %v", err)
+		return model.Flow{}, fmt.Errorf("加载流程失败: %v", err)
 	}
 
 	// 转换为Flow模型
 	flow, err := s.converter.ConvertFromSFlow(&sFlow)
 	if err != nil {
-		return nil, fmt.Errorf("转换流程失败: %v", err)
+		return model.Flow{}, fmt.Errorf("转换流程失败: %v", err)
+	}
+	return flow, nil
+}
+
+// ErrFlowForbidden 用户无权访问流程
+var ErrFlowForbidden = errors.New("无权访问该流程")
+
+// CheckFlowAccess 检查用户是否可以访问流程
+// 登录用户拥有全部流程的权限，用户已删除或流程不存在时不能访问
+func (s *Service) CheckFlowAccess(flowID string, userID uint) error {
+	exists, err := (&basic.User{}).Exists(userID)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return ErrFlowForbidden
+	}
+	flow, err := sflow.SFlow{}.Load(flowID)
+	if err != nil {
+		return err
+	}
+	if flow.ID == 0 {
+		return fmt.Errorf("%w: 流程不存在", ErrFlowForbidden)
+	}
+	return nil
+}
+
+// ExecuteFlow 执行流程
+func (s *Service) ExecuteFlow(ctx context.Context, flowID string, params map[string]any, debug bool) (*model.ExecutionContext, error) {
+	flow, err := s.loadFlow(flowID)
+	if err != nil {
+		return nil, err
 	}
 
 	// 创建执行上下文
 	execCtx := model.NewExecutionContext(flow.ID, params, s.logger)
 	execCtx.Debug = debug // 设置debug模式
+	return s.runFlow(ctx, flow, execCtx)
+}
+
+// StartFlow 异步执行流程
+// 立即返回执行ID，调用方可通过事件流实时获取执行进度
+func (s *Service) StartFlow(flowID string, params map[string]any, debug bool) (string, error) {
+	flow, err := s.loadFlow(flowID)
+	if err != nil {
+		return "", err
+	}
+
+	// 异步执行前先校验参数，便于调用方及时获得错误
+	if _, err := model.ValidateParams(flow.Params, params); err != nil {
+		return "", err
+	}
+
+	execCtx := model.NewExecutionContext(flow.ID, params, s.logger)
+	execCtx.Debug = debug
+	go s.runFlow(context.Background(), flow, execCtx)
+	return execCtx.ExecutionID, nil
+}
+
+// SubscribeEvents 订阅流程的执行事件
+// executionID 为空时接收该流程所有执行的事件；指定执行时先重放该执行已发布的事件，
+// 事件总线中已没有该执行的事件且执行日志显示已结束时，只返回执行结束事件
+func (s *Service) SubscribeEvents(flowID uint, executionID string) (<-chan model.Event, func()) {
+	filter := func(event model.Event) bool {
+		return event.FlowID == flowID
+	}
+	if executionID == "" {
+		return s.engine.Events().Subscribe(filter, 0)
+	}
+	events, cancel, replayed := s.engine.Events().SubscribeExecution(executionID, filter, 0)
+	if replayed {
+		return events, cancel
+	}
+	if event, ok := finishedEvent(flowID, executionID); ok {
+		cancel()
+		finished := make(chan model.Event, 1)
+		finished <- event
+		close(finished)
+		return finished, func() {}
+	}
+	return events, cancel
+}
+
+// runFlow 执行已转换的流程并在调试模式下记录执行详情
+func (s *Service) runFlow(ctx context.Context, flow model.Flow, execCtx *model.ExecutionContext, opts ...engine.RunOption) (*model.ExecutionContext, error) {
+	debug := execCtx.Debug
+	params := execCtx.Params
+
+	// 设置密钥访问范围，解析出的密钥值在日志和结果中脱敏
+	scope := secretScope(flow.ID, flow.Name, flow.ProjectDirID)
+	execCtx.SetScope(scope)
+	execCtx.SetMasker(scope.Masker)
 
 	// 调试模式下记录初始状态
 	if debug {
@@ -116,9 +255,27 @@ func (s *Service) ExecuteFlow(ctx context.Context, flowID string, params map[str
 		s.logger.Info("【调试模式】初始数据: %v", params)
 	}
 
+	// 按流程的并发策略申请执行
+	runCtx, release, err := s.runs.acquire(ctx, flow, execCtx.ExecutionID)
+	if err != nil {
+		s.logger.Warn("流程 %s (ID: %d) 未执行: %v", flow.Name, flow.ID, err)
+		if !execCtx.HasEventEmitter() {
+			execCtx.SetEventEmitter(s.engine.Events())
+		}
+		execCtx.Status = model.Skipped
+		execCtx.EndTime = time.Now()
+		execCtx.Emit(model.Event{Type: model.EventFlowFinished, Status: execCtx.Status, Message: err.Error()})
+		return execCtx, err
+	}
+	defer release()
+
 	// 执行流程
 	s.logger.Info("开始执行流程: %s (ID: %d)", flow.Name, flow.ID)
-	execCtx, err = s.engine.Execute(ctx, flow, params)
+	flowLog := startFlowLog(flow, execCtx)
+	// 有节点等待审批或信号时，其他节点执行结束后更新等待记录中保存的执行状态
+	opts = append(opts, engine.WithAfterNode(wait.SaveState))
+	execCtx, err = s.engine.Run(runCtx, flow, execCtx, opts...)
+	finishFlowLog(flowLog, flow, execCtx, err)
 
 	// 调试模式下记录完整执行结果
 	if debug && execCtx != nil {
@@ -146,7 +303,7 @@ func (s *Service) ExecuteFlow(ctx context.Context, flowID string, params map[str
 			// 记录节点结果数据
 			if result, ok := execCtx.NodeResults[nodeID]; ok {
 				resultJSON, _ := json.Marshal(result)
-				s.logger.Info("【调试模式】节点 %s 结果数据: %s", nodeName, string(resultJSON))
+				s.logger.Info("【调试模式】节点 %s 结果数据: %s", nodeName, execCtx.Mask(string(resultJSON)))
 			}
 
 			// 记录节点错误信息
@@ -227,6 +384,39 @@ func (s *Service) GetRegisteredHandlers() []string {
 	return types
 }
 
+// GetLeakedHandlers 获取超时后仍未退出的节点处理器
+func (s *Service) GetLeakedHandlers() []engine.LeakedHandler {
+	return s.engine.LeakedHandlers()
+}
+
+// GetFlowParams 获取流程开始节点声明的输入参数定义
+func (s *Service) GetFlowParams(flowID string) ([]model.ParamSchema, error) {
+	flow, err := s.loadFlow(flowID)
+	if err != nil {
+		return nil, err
+	}
+	if flow.Params == nil {
+		return []model.ParamSchema{}, nil
+	}
+	return flow.Params, nil
+}
+
+// GetFlowRuns 获取流程的并发配置和当前执行情况
+func (s *Service) GetFlowRuns(flowID string) (FlowRuns, error) {
+	flow, err := s.loadFlow(flowID)
+	if err != nil {
+		return FlowRuns{}, err
+	}
+	runs := s.runs.runs(flow.ID)
+	runs.Policy, runs.MaxQueue = flowPolicy(flow)
+	return runs, nil
+}
+
+// ListFlowRuns 获取所有正在执行或排队的流程
+func (s *Service) ListFlowRuns() []FlowRuns {
+	return s.runs.allRuns()
+}
+
 // 全局服务实例
 var defaultService *Service
 
@@ -235,9 +425,21 @@ func Init() {
 	if defaultService == nil {
 		defaultService = NewService()
 		log.Println("DAGFlow服务已初始化")
+		// 加载插件，需在恢复执行前注册插件的节点类型
+		defaultService.LoadPlugins(PluginDir())
+		// 恢复服务重启前暂停中的执行，并定期清理过期制品
+		if global.DB != nil {
+			defaultService.ResumeWaiting()
+			artifact.StartCleanup()
+		}
 	}
 }
 
+// PluginDir 插件目录
+func PluginDir() string {
+	return filepath.Join(data.WorkDir, "plugins")
+}
+
 // GetService 获取DAGFlow服务实例
 func GetService() *Service {
 	if defaultService == nil {
@@ -0,0 +1,43 @@
+package dagflow
+
+import (
+	"fmt"
+	"server/service/basic"
+	"server/service/sflow"
+	"server/utils/global"
+	"testing"
+	"time"
+
+	"github.com/glebarez/sqlite"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+	gormlogger "gorm.io/gorm/logger"
+)
+
+// TestCheckFlowAccess 测试只有存在的用户可以访问存在的流程
+func TestCheckFlowAccess(t *testing.T) {
+	dsn := fmt.Sprintf("file:access_%d?mode=memory&cache=shared", time.Now().UnixNano())
+	conn, err := gorm.Open(sqlite.Open(dsn), &gorm.Config{Logger: gormlogger.Discard})
+	require.NoError(t, err)
+	require.NoError(t, conn.AutoMigrate(&basic.User{}, &sflow.SFlow{}))
+	old := global.DB
+	global.DB = conn
+	t.Cleanup(func() { global.DB = old })
+
+	user := basic.User{Account: "tester", Name: "tester"}
+	require.NoError(t, global.DB.Create(&user).Error)
+	flow := sflow.SFlow{Name: "flow"}
+	require.NoError(t, global.DB.Create(&flow).Error)
+	flowID := fmt.Sprint(flow.ID)
+
+	s := &Service{}
+	assert.NoError(t, s.CheckFlowAccess(flowID, user.ID))
+	assert.ErrorIs(t, s.CheckFlowAccess(flowID, 0), ErrFlowForbidden)
+	assert.ErrorIs(t, s.CheckFlowAccess(flowID, user.ID+1), ErrFlowForbidden)
+	assert.ErrorIs(t, s.CheckFlowAccess(fmt.Sprint(flow.ID+1), user.ID), ErrFlowForbidden)
+
+	// 已删除的流程不能访问
+	require.NoError(t, global.DB.Delete(&flow).Error)
+	assert.ErrorIs(t, s.CheckFlowAccess(flowID, user.ID), ErrFlowForbidden)
+}
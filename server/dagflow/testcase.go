@@ -0,0 +1,255 @@
+package dagflow
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"server/dagflow/core/el"
+	"server/dagflow/engine"
+	"server/dagflow/handler"
+	"server/dagflow/model"
+	"server/service/sflow"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// testCaseTimeout 单个测试用例的最长执行时间
+const testCaseTimeout = 5 * time.Minute
+
+// TestMock 模拟节点，替换节点的处理器直接返回结果或错误
+type TestMock struct {
+	Result any    `json:"result"` // 节点结果
+	Error  string `json:"error"`  // 错误信息，不为空时节点失败
+}
+
+// TestExpect 测试用例的断言
+type TestExpect struct {
+	Status     string            `json:"status"`     // 期望的流程状态，默认completed
+	Error      string            `json:"error"`      // 期望的流程错误信息（包含匹配）
+	NodeStatus map[string]string `json:"nodeStatus"` // 期望的节点状态，键为节点ID
+	Assertions []string          `json:"assertions"` // EL断言，在流程最终数据上计算，结果应为true
+}
+
+// TestCaseResult 单个测试用例的运行结果
+type TestCaseResult struct {
+	CaseID      uint     `json:"caseId"`          // 用例ID
+	Name        string   `json:"name"`            // 用例名称
+	Passed      bool     `json:"passed"`          // 是否通过
+	Status      string   `json:"status"`          // 流程状态
+	Error       string   `json:"error,omitempty"` // 流程错误信息
+	Failures    []string `json:"failures"`        // 未通过的断言
+	Duration    int64    `json:"duration"`        // 执行时长(毫秒)
+	ExecutionID string   `json:"executionId"`     // 执行ID
+	Logs        []string `json:"logs,omitempty"`  // 执行日志，仅未通过时返回
+}
+
+// TestReport 流程测试报告
+type TestReport struct {
+	FlowID   uint             `json:"flowId"`   // 流程ID
+	FlowName string           `json:"flowName"` // 流程名称
+	Total    int              `json:"total"`    // 用例总数
+	Passed   int              `json:"passed"`   // 通过数
+	Failed   int              `json:"failed"`   // 未通过数
+	Duration int64            `json:"duration"` // 总时长(毫秒)
+	Cases    []TestCaseResult `json:"cases"`    // 各用例结果
+}
+
+// Success 是否全部通过
+func (r TestReport) Success() bool {
+	return r.Failed == 0
+}
+
+// RunTestCases 运行流程的所有测试用例，返回测试报告
+// 测试执行不记录流程日志、不发送通知，也不受流程并发策略限制
+func (s *Service) RunTestCases(ctx context.Context, flowID string) (*TestReport, error) {
+	flow, err := s.loadFlow(flowID)
+	if err != nil {
+		return nil, err
+	}
+	cases, err := sflow.SFlowTestCase{}.ListByFlow(flow.ID)
+	if err != nil {
+		return nil, fmt.Errorf("查询测试用例失败: %v", err)
+	}
+	if len(cases) == 0 {
+		return nil, errors.New("流程没有可运行的测试用例")
+	}
+
+	report := &TestReport{FlowID: flow.ID, FlowName: flow.Name, Cases: make([]TestCaseResult, 0, len(cases))}
+	start := time.Now()
+	for _, testCase := range cases {
+		result := s.runTestCase(ctx, flow, testCase)
+		sflow.SFlowTestCase{}.UpdateResult(testCase.ID, result.Passed)
+		report.Cases = append(report.Cases, result)
+		if result.Passed {
+			report.Passed++
+		} else {
+			report.Failed++
+		}
+	}
+	report.Total = len(cases)
+	report.Duration = time.Since(start).Milliseconds()
+	return report, nil
+}
+
+// runTestCase 运行单个测试用例
+func (s *Service) runTestCase(ctx context.Context, flow model.Flow, testCase sflow.SFlowTestCase) (result TestCaseResult) {
+	result = TestCaseResult{CaseID: testCase.ID, Name: testCase.Name, Failures: make([]string, 0)}
+	start := time.Now()
+	defer func() {
+		result.Duration = time.Since(start).Milliseconds()
+	}()
+
+	var params map[string]any
+	var mocks map[string]TestMock
+	var expect TestExpect
+	for name, item := range map[string]struct {
+		text  string
+		value any
+	}{
+		"输入参数": {testCase.Params, &params},
+		"模拟节点": {testCase.Mocks, &mocks},
+		"断言":   {testCase.Expect, &expect},
+	} {
+		if item.text == "" {
+			continue
+		}
+		if err := json.Unmarshal([]byte(item.text), item.value); err != nil {
+			result.Failures = append(result.Failures, fmt.Sprintf("%s格式错误: %v", name, err))
+		}
+	}
+	for nodeID := range mocks {
+		if _, ok := flow.Nodes[nodeID]; !ok {
+			result.Failures = append(result.Failures, fmt.Sprintf("模拟的节点不存在: %s", nodeID))
+		}
+	}
+	if len(result.Failures) > 0 {
+		return result
+	}
+
+	// 设置与正式执行相同的密钥访问范围
+	execCtx := model.NewExecutionContext(flow.ID, params, s.logger)
+	scope := secretScope(flow.ID, flow.Name, flow.ProjectDirID)
+	execCtx.SetScope(scope)
+	execCtx.SetMasker(scope.Masker)
+
+	runCtx, cancel := context.WithTimeout(ctx, testCaseTimeout)
+	defer cancel()
+	execCtx, err := s.engine.Run(runCtx, flow, execCtx, engine.WithHandlerOverride(func(node model.TaskNode) handler.TaskHandler {
+		if mock, ok := mocks[node.ID]; ok {
+			return &mockHandler{nodeType: node.Type, mock: mock}
+		}
+		return nil
+	}))
+	if execCtx == nil {
+		result.Status = string(model.Failed)
+		result.Error = err.Error()
+		result.Failures = append(result.Failures, "流程无法执行: "+err.Error())
+		return result
+	}
+	result.ExecutionID = execCtx.ExecutionID
+	result.Status = string(execCtx.Status)
+	if err != nil {
+		result.Error = execCtx.Mask(err.Error())
+	}
+	result.Failures = checkExpect(flow, execCtx, result, expect)
+	result.Passed = len(result.Failures) == 0
+	if !result.Passed {
+		result.Logs = execCtx.Logs()
+	}
+	return result
+}
+
+// checkExpect 检查执行结果是否满足断言，返回未通过的断言
+func checkExpect(flow model.Flow, execCtx *model.ExecutionContext, result TestCaseResult, expect TestExpect) []string {
+	failures := make([]string, 0)
+
+	// 流程状态和错误信息
+	status := expect.Status
+	if status == "" {
+		status = string(model.Completed)
+	}
+	if result.Status != status {
+		failures = append(failures, fmt.Sprintf("流程状态为 %s，期望 %s", result.Status, status))
+	}
+	if expect.Error != "" && !strings.Contains(result.Error, expect.Error) {
+		failures = append(failures, fmt.Sprintf("流程错误信息为 %q，期望包含 %q", result.Error, expect.Error))
+	}
+
+	// 节点状态
+	for nodeID, want := range expect.NodeStatus {
+		if _, ok := flow.Nodes[nodeID]; !ok {
+			failures = append(failures, fmt.Sprintf("断言的节点不存在: %s", nodeID))
+			continue
+		}
+		if got := execCtx.GetNodeStatus(nodeID); string(got) != want {
+			failures = append(failures, fmt.Sprintf("节点 %s 状态为 %s，期望 %s", nodeName(flow, nodeID), got, want))
+		}
+	}
+
+	// 最终数据上的EL断言
+	env := execCtx.ELEnv()
+	for _, assertion := range expect.Assertions {
+		value, err := el.Evaluate(assertion, env)
+		if err != nil {
+			failures = append(failures, fmt.Sprintf("断言 %s 计算失败: %v", assertion, err))
+			continue
+		}
+		if !truthy(value) {
+			failures = append(failures, fmt.Sprintf("断言 %s 不成立，结果为 %v", assertion, value))
+		}
+	}
+	return failures
+}
+
+// truthy 判断断言结果是否为真
+func truthy(value any) bool {
+	switch v := value.(type) {
+	case bool:
+		return v
+	case string:
+		b, err := strconv.ParseBool(v)
+		return err == nil && b
+	default:
+		return false
+	}
+}
+
+// nodeName 获取节点名称，用于测试报告
+func nodeName(flow model.Flow, nodeID string) string {
+	if node, ok := flow.Nodes[nodeID]; ok && node.Name != "" {
+		return fmt.Sprintf("%s(%s)", node.Name, nodeID)
+	}
+	return nodeID
+}
+
+// mockHandler 模拟节点处理器，直接返回测试用例中设置的结果或错误，演练回放时返回原执行记录的结果
+type mockHandler struct {
+	nodeType string
+	mock     TestMock
+	replay   bool
+}
+
+// GetType 获取处理器类型
+func (h *mockHandler) GetType() string {
+	return h.nodeType
+}
+
+// Handle 返回模拟结果
+func (h *mockHandler) Handle(ctx context.Context, node model.TaskNode, execCtx *model.ExecutionContext) (any, error) {
+	if h.replay {
+		execCtx.Log("info", "节点 %s 有副作用，演练模式下使用原执行记录的结果", node.Name)
+	} else {
+		execCtx.Log("info", "节点 %s 使用模拟结果", node.Name)
+	}
+	if h.mock.Error != "" {
+		return nil, errors.New(h.mock.Error)
+	}
+	return h.mock.Result, nil
+}
+
+// Validate 模拟节点不校验配置
+func (h *mockHandler) Validate(node model.TaskNode) error {
+	return nil
+}
@@ -39,6 +39,7 @@ func (c *FlowConverter) ConvertFromSFlow(sflow *sflow.SFlow) (model.Flow, error)
 	// 找到开始和结束节点
 	startNodeID := ""
 	endNodeID := ""
+	finallyID := ""
 
 	// 转换为Flow格式
 	nodes := make(map[string]model.TaskNode)
@@ -85,6 +86,12 @@ func (c *FlowConverter) ConvertFromSFlow(sflow *sflow.SFlow) (model.Flow, error)
 				taskNode.ExceptionHandle = ignoreException
 			}
 
+			// 设置超时时间
+			taskNode.Timeout = parseTimeout(cell.Data.Form["nodeTimeout"])
+
+			// 设置是否有副作用
+			taskNode.SideEffect = parseBool(cell.Data.Form["sideEffect"])
+
 			// 设置结果名称
 			if datakey, ok := cell.Data.Form["datakey"].(string); ok {
 				taskNode.ResultName = datakey
@@ -102,6 +109,11 @@ func (c *FlowConverter) ConvertFromSFlow(sflow *sflow.SFlow) (model.Flow, error)
 				return model.Flow{}, errors.New("流程图中有多个结束节点")
 			}
 			endNodeID = cell.ID
+		} else if cell.Shape == "finally" {
+			if finallyID != "" {
+				return model.Flow{}, errors.New("流程图中有多个finally节点")
+			}
+			finallyID = cell.ID
 		}
 
 		nodes[cell.ID] = taskNode
@@ -125,6 +137,10 @@ func (c *FlowConverter) ConvertFromSFlow(sflow *sflow.SFlow) (model.Flow, error)
 			if expr, ok := cell.Data.Form["expr"].(string); ok {
 				edge.Expression = expr
 			}
+			// 设置连线类型
+			if kind, ok := cell.Data.Form["kind"].(string); ok && kind == model.EdgeError {
+				edge.Kind = model.EdgeError
+			}
 		}
 
 		// 设置连接点
@@ -158,6 +174,12 @@ func (c *FlowConverter) ConvertFromSFlow(sflow *sflow.SFlow) (model.Flow, error)
 		}
 	}
 
+	// 从开始节点获取输入参数定义
+	params, err := parseParamSchema(nodes[startNodeID].Properties["params"])
+	if err != nil {
+		return model.Flow{}, err
+	}
+
 	// 创建Flow模型
 	flow := model.Flow{
 		ID:           sflow.ID,
@@ -171,6 +193,13 @@ func (c *FlowConverter) ConvertFromSFlow(sflow *sflow.SFlow) (model.Flow, error)
 		EndNodeID:    endNodeID,
 		ReturnResult: returnResult,
 		ResultType:   resultType,
+		Timeout:      sflow.Timeout,
+		Workers:      sflow.Workers,
+		Concurrency:  sflow.Concurrency,
+		MaxQueue:     sflow.MaxQueue,
+		Params:       params,
+		FinallyID:    finallyID,
+		ProjectDirID: sflow.ProjectDirID,
 	}
 
 	return flow, nil
@@ -287,3 +316,53 @@ func parseCacheTime(cacheTimeStr string) int {
 	}
 	return cacheTime
 }
+
+// parseParamSchema 解析开始节点声明的输入参数定义
+func parseParamSchema(value any) ([]model.ParamSchema, error) {
+	if value == nil {
+		return nil, nil
+	}
+	if s, ok := value.(string); ok {
+		if s == "" {
+			return nil, nil
+		}
+		value = json.RawMessage(s)
+	}
+	content, err := json.Marshal(value)
+	if err != nil {
+		return nil, fmt.Errorf("解析输入参数定义失败: %v", err)
+	}
+	var params []model.ParamSchema
+	if err := json.Unmarshal(content, &params); err != nil {
+		return nil, fmt.Errorf("解析输入参数定义失败: %v", err)
+	}
+	return params, nil
+}
+
+// parseTimeout 解析超时时间(秒)，表单中可能为字符串或数字，无效时返回0表示不限制
+func parseTimeout(value any) int {
+	var timeout int
+	switch v := value.(type) {
+	case float64:
+		timeout = int(v)
+	case string:
+		if _, err := fmt.Sscanf(v, "%d", &timeout); err != nil {
+			return 0
+		}
+	}
+	if timeout < 0 {
+		return 0
+	}
+	return timeout
+}
+
+// parseBool 解析布尔值，表单中可能为布尔值或字符串
+func parseBool(value any) bool {
+	switch v := value.(type) {
+	case bool:
+		return v
+	case string:
+		return v == "true" || v == "1"
+	}
+	return false
+}
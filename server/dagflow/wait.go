@@ -0,0 +1,134 @@
+package dagflow
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"server/core/app/request"
+	"server/dagflow/engine"
+	"server/dagflow/handler/wait"
+	"server/dagflow/model"
+	"server/service/basic"
+	"server/service/sflow"
+	"server/utils/global"
+	"strings"
+)
+
+// ErrNotApprover 当前用户不是审批人
+var ErrNotApprover = errors.New("当前用户无权审批")
+
+// ListWaits 分页查询等待记录
+func (s *Service) ListWaits(query request.PageQuery) ([]sflow.SFlowWait, int64, error) {
+	return sflow.SFlowWait{}.List(query)
+}
+
+// DecideApproval 审批等待中的审批节点
+// 节点配置了审批人时只有审批人和超级管理员可以审批
+func (s *Service) DecideApproval(waitID any, userID uint, approved bool, comment string) error {
+	record, err := sflow.SFlowWait{}.Load(waitID)
+	if err != nil {
+		return fmt.Errorf("等待记录不存在: %v", err)
+	}
+	if record.Kind != sflow.WaitApproval {
+		return errors.New("等待记录不是审批节点")
+	}
+	if !canApprove(record, userID) {
+		return ErrNotApprover
+	}
+
+	status := sflow.WaitRejected
+	if approved {
+		status = sflow.WaitApproved
+	}
+	if err := record.Resolve(record.ID, status, userID, comment, ""); err != nil {
+		return err
+	}
+	wait.Notify(record.ID)
+	return nil
+}
+
+// Signal 向等待中的执行发送信号，返回被唤醒的等待节点数量
+func (s *Service) Signal(executionID, name string, payload any) (int, error) {
+	if executionID == "" || name == "" {
+		return 0, errors.New("执行ID和信号名称不能为空")
+	}
+	data := ""
+	if payload != nil {
+		bytes, err := json.Marshal(payload)
+		if err != nil {
+			return 0, fmt.Errorf("信号数据格式错误: %v", err)
+		}
+		data = string(bytes)
+	}
+
+	list, err := sflow.SFlowWait{}.FindSignal(executionID, name)
+	if err != nil {
+		return 0, err
+	}
+	count := 0
+	for _, record := range list {
+		// 已被超时或取消处理的记录跳过
+		if record.Resolve(record.ID, sflow.WaitSignaled, 0, "", data) != nil {
+			continue
+		}
+		wait.Notify(record.ID)
+		count++
+	}
+	if count == 0 {
+		return 0, fmt.Errorf("没有等待信号 %s 的节点", name)
+	}
+	return count, nil
+}
+
+// ResumeWaiting 恢复服务重启前暂停中的执行
+// 同一执行的等待记录保存的是相同的最新执行状态，每个执行只恢复一次，恢复后各等待节点重新找到自己的等待记录继续等待
+func (s *Service) ResumeWaiting() {
+	list, err := sflow.SFlowWait{}.ListWaiting()
+	if err != nil {
+		s.logger.Error("查询等待记录失败: %v", err)
+		return
+	}
+	latest := make(map[string]sflow.SFlowWait)
+	for _, record := range list {
+		latest[record.ExecutionID] = record
+	}
+	for executionID, record := range latest {
+		if err := s.resume(record); err != nil {
+			s.logger.Error("恢复执行 %s 失败: %v", executionID, err)
+		}
+	}
+}
+
+// resume 从等待记录保存的执行状态恢复执行
+func (s *Service) resume(record sflow.SFlowWait) error {
+	flow, err := s.loadFlow(fmt.Sprint(record.SFlowId))
+	if err != nil {
+		return err
+	}
+	execCtx := model.NewExecutionContext(flow.ID, nil, s.logger)
+	if err := execCtx.FromJSON(record.State); err != nil {
+		return fmt.Errorf("解析执行状态失败: %v", err)
+	}
+	execCtx.Debug = false
+	s.logger.Info("恢复执行: %s (流程: %s, 等待节点: %s)", execCtx.ExecutionID, flow.Name, record.NodeName)
+	go s.runFlow(context.Background(), flow, execCtx, engine.WithRestore())
+	return nil
+}
+
+// canApprove 检查用户是否可以审批
+func canApprove(record sflow.SFlowWait, userID uint) bool {
+	if record.Approvers == "" {
+		return userID > 0
+	}
+	for _, id := range strings.Split(record.Approvers, ",") {
+		if id == fmt.Sprint(userID) {
+			return true
+		}
+	}
+	var user basic.User
+	if global.DB.Model(&basic.User{}).Select("id", "is_admin").Take(&user, userID).Error != nil {
+		return false
+	}
+	return user.IsAdmin == 1
+}
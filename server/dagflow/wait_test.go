@@ -0,0 +1,170 @@
+package dagflow
+
+import (
+	"context"
+	"fmt"
+	"server/dagflow/engine"
+	"server/dagflow/handler"
+	"server/dagflow/handler/wait"
+	"server/dagflow/model"
+	"server/service/sflow"
+	"server/utils/global"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/glebarez/sqlite"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+	gormlogger "gorm.io/gorm/logger"
+)
+
+// waitTestType 等待测试中普通节点的类型
+const waitTestType = "wait-test"
+
+// branchHandler 记录执行次数的测试处理器，p节点等待放行后才执行结束
+type branchHandler struct {
+	release chan struct{}
+	mu      sync.Mutex
+	runs    map[string]int
+}
+
+// Handle 记录执行次数，返回节点ID
+func (h *branchHandler) Handle(ctx context.Context, node model.TaskNode, execCtx *model.ExecutionContext) (any, error) {
+	h.mu.Lock()
+	h.runs[node.ID]++
+	h.mu.Unlock()
+	if node.ID == "p" {
+		<-h.release
+	}
+	return node.ID, nil
+}
+
+// GetType 获取处理器类型
+func (h *branchHandler) GetType() string {
+	return waitTestType
+}
+
+// Validate 测试节点不校验配置
+func (h *branchHandler) Validate(node model.TaskNode) error {
+	return nil
+}
+
+// count 获取节点的执行次数
+func (h *branchHandler) count(nodeID string) int {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.runs[nodeID]
+}
+
+// setupWaitDB 为测试创建独立的内存数据库，测试结束后恢复全局数据库连接
+func setupWaitDB(t *testing.T) {
+	t.Helper()
+	dsn := fmt.Sprintf("file:wait_%d?mode=memory&cache=shared", time.Now().UnixNano())
+	conn, err := gorm.Open(sqlite.Open(dsn), &gorm.Config{Logger: gormlogger.Discard})
+	require.NoError(t, err)
+	require.NoError(t, conn.AutoMigrate(&sflow.SFlowWait{}))
+	old := global.DB
+	global.DB = conn
+	t.Cleanup(func() {
+		global.DB = old
+		if sqlDB, err := conn.DB(); err == nil {
+			sqlDB.Close()
+		}
+	})
+}
+
+// savedState 解析等待记录中保存的执行状态
+func savedState(t *testing.T, executionID, nodeID string) (sflow.SFlowWait, *model.ExecutionContext) {
+	t.Helper()
+	record, err := sflow.SFlowWait{}.FindByNode(executionID, nodeID)
+	require.NoError(t, err)
+	execCtx := model.NewExecutionContext(1, nil, nil)
+	if record.ID != 0 {
+		require.NoError(t, execCtx.FromJSON(record.State))
+	}
+	return record, execCtx
+}
+
+// TestRestoreWaitWithParallelBranch 测试等待期间并行分支执行结束后保存执行状态，重启恢复时该分支不再执行
+func TestRestoreWaitWithParallelBranch(t *testing.T) {
+	setupWaitDB(t)
+	h := &branchHandler{release: make(chan struct{}), runs: make(map[string]int)}
+	registry := handler.NewHandlerRegistry()
+	registry.Register(h)
+	registry.Register(&wait.SignalHandler{})
+	e := engine.NewEngine(registry, nil)
+
+	flow := model.Flow{
+		ID:          1,
+		Name:        "wait",
+		Nodes:       make(map[string]model.TaskNode),
+		Edges:       make(map[string]model.Edge),
+		StartNodeID: "start",
+		EndNodeID:   "end",
+	}
+	for _, id := range []string{"start", "p", "end"} {
+		flow.Nodes[id] = model.TaskNode{ID: id, Name: id, Type: waitTestType}
+	}
+	flow.Nodes["w"] = model.TaskNode{ID: "w", Name: "w", Type: wait.TypeSignal, Properties: map[string]any{"signal": "go"}}
+	for _, pair := range [][2]string{{"start", "w"}, {"start", "p"}, {"w", "end"}, {"p", "end"}} {
+		id := pair[0] + "->" + pair[1]
+		flow.Edges[id] = model.Edge{ID: id, Name: id, Source: pair[0], Target: pair[1]}
+	}
+
+	// 第一次执行：等待节点创建记录后，并行分支才执行结束
+	ctx, cancel := context.WithCancel(context.Background())
+	first := model.NewExecutionContext(flow.ID, nil, nil)
+	firstDone := make(chan struct{})
+	go func() {
+		defer close(firstDone)
+		e.Run(ctx, flow, first, engine.WithWorkers(2), engine.WithAfterNode(wait.SaveState))
+	}()
+	t.Cleanup(func() {
+		cancel()
+		<-firstDone
+	})
+	require.Eventually(t, func() bool {
+		record, _ := savedState(t, first.ExecutionID, "w")
+		return record.ID != 0
+	}, time.Second, 5*time.Millisecond, "等待节点没有创建等待记录")
+	close(h.release)
+	require.Eventually(t, func() bool {
+		_, state := savedState(t, first.ExecutionID, "w")
+		return state.GetNodeStatus("p") == model.Completed
+	}, time.Second, 5*time.Millisecond, "并行分支执行结束后没有保存执行状态")
+
+	// 模拟服务重启：按等待记录中保存的状态恢复执行
+	record, second := savedState(t, first.ExecutionID, "w")
+	assert.Equal(t, sflow.WaitPending, record.Status)
+	secondDone := make(chan error, 1)
+	go func() {
+		_, err := e.Run(context.Background(), flow, second, engine.WithRestore(), engine.WithAfterNode(wait.SaveState))
+		secondDone <- err
+	}()
+	require.Eventually(t, func() bool {
+		return strings.Contains(strings.Join(second.Logs(), "\n"), "节点 w 等待信号")
+	}, time.Second, 5*time.Millisecond, "恢复后等待节点没有继续等待")
+
+	require.NoError(t, record.Resolve(record.ID, sflow.WaitSignaled, 0, "", `"ok"`))
+	wait.Notify(record.ID)
+	select {
+	case err := <-secondDone:
+		require.NoError(t, err)
+	case <-time.After(time.Second):
+		t.Fatal("恢复的执行没有结束")
+	}
+
+	assert.Equal(t, 1, h.count("start"))
+	assert.Equal(t, 1, h.count("p"))
+	want := map[string]model.NodeExecutionStatus{"p": model.Completed, "w": model.Completed, "end": model.Completed}
+	for nodeID, status := range want {
+		assert.Equal(t, status, second.GetNodeStatus(nodeID), "节点 %s", nodeID)
+	}
+	result, _ := second.GetData("w")
+	assert.Equal(t, "ok", result)
+	result, _ = second.GetData("p")
+	assert.Equal(t, "p", result)
+}
@@ -3,10 +3,13 @@ package route
 import (
 	"fmt"
 	"server/app/basic/projectdir"
+	"server/app/basic/secret"
 	"server/app/basic/system"
 	"server/app/basic/user"
+	"server/app/logtail"
 	"server/app/nas/external"
 	"server/app/nas/webdav"
+	"server/app/notify"
 	"server/app/schtask"
 	"server/app/sflow"
 	"server/app/term"
@@ -60,6 +63,8 @@ func Init(app *gin.Engine) {
 			println("------------------")
 			// 添加项目目录相关路由
 			projectdir.AddRoutes(Basic)
+			// 添加密钥管理相关路由
+			secret.AddRoutes(Basic)
 		}
 
 		// 终端管理路由组
@@ -84,6 +89,7 @@ func Init(app *gin.Engine) {
 			// 添加作业流程相关路由
 			sflow.SFlowApp{}.AddRoutes(SFlowSystem)
 			sflow.SFlowLogApp{}.AddRoutes(SFlowSystem)
+			sflow.SFlowTestCaseApp{}.AddRoutes(SFlowSystem)
 		}
 
 		// DAG流程路由组，需要认证中间件保护
@@ -94,6 +100,21 @@ func Init(app *gin.Engine) {
 			ragFlowAPI.AddRoutes(RagFlowSystem)
 		}
 
+		// DAG流程执行事件流路由组，SSE连接使用一次性令牌鉴权
+		DagFlowEvents := v1.Group("/dagflow-events")
+		{
+			// 添加流程执行事件流相关路由
+			eventAPI := &api.DAGFlowAPI{}
+			eventAPI.AddEventRoutes(DagFlowEvents)
+		}
+
+		// 执行日志实时查看路由组，SSE连接使用一次性令牌鉴权
+		LogTail := v1.Group("/log-tail")
+		{
+			// 添加计划任务和流程日志跟踪相关路由
+			logtail.AddRoutes(LogTail)
+		}
+
 		// 文件存储(NAS)路由组，需要认证中间件保护
 		NasSystem := v1.Group("/nas", middleware.AuthMiddleware)
 		{
@@ -103,6 +124,13 @@ func Init(app *gin.Engine) {
 			external.AddRoutes(NasSystem)
 		}
 
+		// 通知中心路由组，需要认证中间件保护
+		NotifySystem := v1.Group("/notify", middleware.AuthMiddleware)
+		{
+			// 添加通知渠道、规则和发送记录相关路由
+			notify.AddRoutes(NotifySystem)
+		}
+
 		// 配置管理路由组，通过API密钥认证保护
 		Config := v1.Group("/config", middleware.AuthApiKeyMiddleware)
 		{
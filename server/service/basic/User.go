@@ -106,3 +106,19 @@ func (e *User) GetByAccount(account string) (User, error) {
 	}
 	return user, nil
 }
+
+// Exists 检查用户是否存在，已删除的用户不能再访问资源
+// 参数:
+//   - id: 用户ID
+//
+// 返回:
+//   - bool: 用户是否存在
+//   - error: 查询过程中可能发生的错误
+func (e *User) Exists(id uint) (bool, error) {
+	if id == 0 {
+		return false, nil
+	}
+	var count int64
+	err := global.DB.Model(&User{}).Where("id = ?", id).Count(&count).Error
+	return count > 0, err
+}
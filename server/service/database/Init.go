@@ -12,8 +12,11 @@ import (
 
 	"server/service/basic"
 	"server/service/nas"
+	"server/service/notify"
 	"server/service/scheduled"
+	"server/service/scheduled/cluster"
 	"server/service/scheduled/log"
+	"server/service/secret"
 	"server/service/sflow"
 
 	"github.com/glebarez/sqlite"
@@ -83,14 +86,24 @@ func Init() {
 
 	// 自动迁移数据表结构，确保模型对应的数据表存在且结构正确
 	db.AutoMigrate(
-		&basic.User{},        // 用户表
-		&basic.ProjectDir{},  // 项目目录表
-		&sflow.SFlow{},       // 流程配置表
-		&sflow.SFlowLog{},    // 流程日志表
-		&nas.Webdav{},        // WebDAV配置表
-		&nas.ExternalNas{},   // 外部存储配置表
-		&scheduled.SchTask{}, // 计划任务表
-		&log.SchLog{},        // 计划任务日志表
+		&basic.User{},            // 用户表
+		&basic.ProjectDir{},      // 项目目录表
+		&sflow.SFlow{},           // 流程配置表
+		&sflow.SFlowLog{},        // 流程日志表
+		&sflow.SFlowWait{},       // 流程等待记录表
+		&sflow.SFlowTestCase{},   // 流程测试用例表
+		&sflow.SFlowArtifact{},   // 流程制品表
+		&sflow.SFlowVersion{},    // 流程版本表
+		&nas.Webdav{},            // WebDAV配置表
+		&nas.ExternalNas{},       // 外部存储配置表
+		&scheduled.SchTask{},     // 计划任务表
+		&log.SchLog{},            // 计划任务日志表
+		&cluster.SchLock{},       // 计划任务集群锁表
+		&secret.Secret{},         // 密钥表
+		&secret.SecretUsage{},    // 密钥使用审计表
+		&notify.NotifyChannel{},  // 通知渠道表
+		&notify.NotifyRule{},     // 通知规则表
+		&notify.NotifyDelivery{}, // 通知发送记录表
 	)
 	logger.LOG.Debug("database AutoMigrate successfully")
 
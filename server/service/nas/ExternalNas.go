@@ -4,6 +4,7 @@ import (
 	"log"
 	"server/core/app/request"
 	"server/core/db"
+	"server/service/secret"
 	"server/utils/config"
 	"server/utils/global"
 	"server/utils/rclone"
@@ -22,7 +23,7 @@ type ExternalNas struct {
 	RcName                    string `gorm:"comment:'标识'" json:"rc_name"`      // rclone配置中的唯一标识符
 	Type                      string `gorm:"comment:'类型'" json:"type"`         // 存储服务类型，如s3、ftp、webdav等
 	IsAdv                     uint   `gorm:"comment:'是否开启高级配置'" json:"is_adv"` // 是否启用高级配置：0=否，1=是
-	Config                    string `gorm:"comment:'配置'" json:"config"`       // 存储服务的配置信息，加密存储
+	Config                    string `gorm:"comment:'配置'" json:"config"`       // 存储服务的配置信息，加密存储，可使用${secret:NAME}引用密钥
 	Remark                    string `gorm:"comment:'备注'" json:"remark"`       // 备注说明
 	IsSync                    bool   `sql:"-" gorm:"-" json:"is_sync"`         // 是否与rclone配置同步，非数据库字段
 }
@@ -97,17 +98,33 @@ func (extNas ExternalNas) Save(entity *ExternalNas, columns ...string) error {
 // 创建
 func (extNas ExternalNas) Create(entity *ExternalNas) error {
 	log.Println("Create--------------------")
-	err := rclone.ConfigCreate(entity.RcName, entity.Type, entity.Config)
+	// 插入前还没有ID，先不记录密钥使用，插入后按新的ID记录
+	config := entity.Config
+	rcConfig, err := secret.Expand(config, &secret.Scope{EntityName: entity.Name})
 	if err != nil {
 		return err
 	}
-	return global.DB.Model(entity).Create(entity).Error
+	err = rclone.ConfigCreate(entity.RcName, entity.Type, rcConfig)
+	if err != nil {
+		return err
+	}
+	if err := global.DB.Model(entity).Create(entity).Error; err != nil {
+		return err
+	}
+	if _, err := secret.Expand(config, entity.secretScope()); err != nil {
+		log.Println("记录密钥使用失败:", err)
+	}
+	return nil
 }
 
 // 更新
 func (extNas ExternalNas) Update(entity *ExternalNas, columns ...string) error {
 	log.Println("Update--------------------")
-	err := rclone.ConfigUpdate(entity.RcName, entity.Type, entity.Config)
+	rcConfig, err := entity.expandConfig()
+	if err != nil {
+		return err
+	}
+	err = rclone.ConfigUpdate(entity.RcName, entity.Type, rcConfig)
 	if err != nil {
 		return err
 	}
@@ -118,6 +135,21 @@ func (extNas ExternalNas) Update(entity *ExternalNas, columns ...string) error {
 	}
 }
 
+// expandConfig 替换配置中引用的密钥，数据库中保留占位符，仅写入rclone的配置使用密钥值
+// 外部存储不属于项目目录，只能引用全局密钥；轮换密钥后需重新保存配置
+func (entity *ExternalNas) expandConfig() (string, error) {
+	return secret.Expand(entity.Config, entity.secretScope())
+}
+
+// secretScope 外部存储引用密钥的使用范围，用于记录密钥使用
+func (entity *ExternalNas) secretScope() *secret.Scope {
+	return &secret.Scope{
+		EntityType: secret.EntityExternalNas,
+		EntityID:   entity.ID,
+		EntityName: entity.Name,
+	}
+}
+
 // 按主键删除
 func (extNas ExternalNas) Delete(id any) error {
 	log.Println("Delete--------------------")
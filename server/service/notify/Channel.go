@@ -0,0 +1,66 @@
+package notify
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"server/core/db"
+	"server/utils/config"
+	"server/utils/xxtea"
+
+	"gorm.io/gorm"
+)
+
+// NotifyChannel 通知渠道模型
+// 渠道配置中包含地址、密钥和密码等敏感信息，加密存储
+type NotifyChannel struct {
+	db.BaseModel[NotifyChannel]        // 嵌入基础模型，提供ID、创建时间等公共字段
+	Name                        string `gorm:"comment:'名称' size:128" json:"name"`    // 渠道名称
+	Type                        string `gorm:"comment:'类型' size:20" json:"type"`     // 渠道类型：webhook、email、dingtalk、wecom、feishu
+	Config                      string `gorm:"comment:'配置' size:8192" json:"config"` // 渠道配置(JSON)，加密存储
+	Remark                      string `gorm:"comment:'备注'" json:"remark"`           // 备注说明
+}
+
+// TableName 指定数据库表名
+func (NotifyChannel) TableName() string {
+	return "notify_channel"
+}
+
+// AfterFind GORM钩子，查询后解密渠道配置
+func (u *NotifyChannel) AfterFind(tx *gorm.DB) (err error) {
+	u.SupperAfterFind()
+	u.Config = xxtea.DecryptAuto(u.Config, config.CONF.Db.DataKey)
+	return
+}
+
+// BeforeSave GORM钩子，保存前加密渠道配置
+func (u *NotifyChannel) BeforeSave(tx *gorm.DB) (err error) {
+	u.Config = xxtea.EncryptAuto(u.Config, config.CONF.Db.DataKey)
+	return
+}
+
+// Save 保存通知渠道，校验渠道类型和配置格式
+func (entity NotifyChannel) Save(data *NotifyChannel, columns ...string) error {
+	if data.Name == "" {
+		return errors.New("渠道名称不能为空")
+	}
+	if _, err := getSender(data.Type); err != nil {
+		return err
+	}
+	if _, err := data.ConfigMap(); err != nil {
+		return err
+	}
+	return entity.BaseModel.Save(data, columns...)
+}
+
+// ConfigMap 解析渠道配置
+func (entity NotifyChannel) ConfigMap() (map[string]any, error) {
+	config := make(map[string]any)
+	if entity.Config == "" {
+		return config, nil
+	}
+	if err := json.Unmarshal([]byte(entity.Config), &config); err != nil {
+		return nil, fmt.Errorf("渠道配置格式错误: %v", err)
+	}
+	return config, nil
+}
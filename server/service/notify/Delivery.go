@@ -0,0 +1,122 @@
+package notify
+
+import (
+	"encoding/json"
+	"server/core/app/request"
+	"server/core/db"
+	"server/utils/global"
+	"time"
+)
+
+// 发送状态
+const (
+	StatusPending  = "pending"  // 待发送
+	StatusSuccess  = "success"  // 发送成功
+	StatusRetrying = "retrying" // 发送失败，等待重试
+	StatusFailed   = "failed"   // 重试次数用尽，发送失败
+)
+
+// maxAttempts 单条通知的最大发送次数
+const maxAttempts = 3
+
+// retryDelays 发送失败后的重试间隔，按已发送次数取值
+var retryDelays = []time.Duration{time.Minute, 5 * time.Minute}
+
+// NotifyDelivery 通知发送记录
+// 每条通知在每个渠道上的发送情况，失败后按间隔自动重试
+type NotifyDelivery struct {
+	ID          uint         `gorm:"primary_key" json:"id"`                       // 主键ID
+	RuleID      uint         `gorm:"default:0;comment:'规则ID'" json:"rule_id"`     // 触发通知的规则ID，手动发送时为0
+	ChannelID   uint         `gorm:"index;comment:'渠道ID'" json:"channel_id"`      // 通知渠道ID
+	ChannelName string       `gorm:"comment:'渠道名称'" json:"channel_name"`          // 通知渠道名称
+	Event       string       `gorm:"index;comment:'事件类型' size:50" json:"event"`   // 事件类型
+	Title       string       `gorm:"comment:'标题' size:512" json:"title"`          // 消息标题
+	Message     string       `gorm:"comment:'消息内容' size:65535" json:"message"`    // 消息(JSON)，重试时使用
+	Status      string       `gorm:"index;comment:'状态' size:20" json:"status"`    // 发送状态
+	Attempts    int          `gorm:"default:0;comment:'发送次数'" json:"attempts"`    // 已发送次数
+	LastError   string       `gorm:"comment:'最近错误' size:2048" json:"last_error"`  // 最近一次发送的错误
+	NextRetryAt db.LocalTime `gorm:"index;comment:'下次重试时间'" json:"next_retry_at"` // 下次重试时间
+	SentAt      db.LocalTime `gorm:"comment:'发送成功时间'" json:"sent_at"`             // 发送成功时间
+	CreatedAt   db.LocalTime `gorm:"comment:'创建时间'" json:"created_at"`            // 创建时间
+}
+
+// TableName 指定数据库表名
+func (NotifyDelivery) TableName() string {
+	return "notify_delivery"
+}
+
+// newDelivery 创建待发送的通知记录
+func newDelivery(ruleID uint, channel NotifyChannel, msg Message) (*NotifyDelivery, error) {
+	message, err := json.Marshal(msg)
+	if err != nil {
+		return nil, err
+	}
+	delivery := &NotifyDelivery{
+		RuleID:      ruleID,
+		ChannelID:   channel.ID,
+		ChannelName: channel.Name,
+		Event:       msg.Event,
+		Title:       msg.Title,
+		Message:     string(message),
+		Status:      StatusPending,
+		CreatedAt:   db.LocalTime{}.Now(),
+	}
+	return delivery, global.DB.Create(delivery).Error
+}
+
+// message 解析发送记录中的消息
+func (entity NotifyDelivery) message() (Message, error) {
+	var msg Message
+	err := json.Unmarshal([]byte(entity.Message), &msg)
+	return msg, err
+}
+
+// finish 记录一次发送的结果，失败且未超过最大次数时安排重试
+func (entity *NotifyDelivery) finish(err error) {
+	entity.Attempts++
+	if err == nil {
+		entity.Status = StatusSuccess
+		entity.LastError = ""
+		entity.SentAt = db.LocalTime{}.Now()
+	} else {
+		entity.LastError = err.Error()
+		if entity.Attempts < maxAttempts && entity.Event != eventTest {
+			entity.Status = StatusRetrying
+			entity.NextRetryAt = db.LocalTime{Time: time.Now().Add(retryDelays[min(entity.Attempts, len(retryDelays))-1])}
+		} else {
+			entity.Status = StatusFailed
+		}
+	}
+	global.DB.Model(entity).Select("status", "attempts", "last_error", "next_retry_at", "sent_at").Updates(entity)
+}
+
+// Load 按主键查询发送记录
+func (entity NotifyDelivery) Load(id any) (NotifyDelivery, error) {
+	err := global.DB.Model(&NotifyDelivery{}).Take(&entity, id).Error
+	return entity, err
+}
+
+// ListDue 查询到达重试时间的发送记录
+func (entity NotifyDelivery) ListDue() ([]NotifyDelivery, error) {
+	list := make([]NotifyDelivery, 0)
+	err := global.DB.Model(&NotifyDelivery{}).
+		Where("status = ? and next_retry_at <= ?", StatusRetrying, time.Now()).
+		Order("id").Limit(100).Find(&list).Error
+	return list, err
+}
+
+// List 分页查询发送记录
+func (entity NotifyDelivery) List(query request.PageQuery) (list []NotifyDelivery, count int64, err error) {
+	modelDb := db.QueryWhere(global.DB.Model(&NotifyDelivery{}), query.Filters)
+	if err = modelDb.Count(&count).Error; err != nil {
+		return nil, 0, err
+	}
+	if query.Page < 1 {
+		query.Page = 1
+	}
+	if query.Size > 0 {
+		modelDb = modelDb.Limit(query.Size).Offset((query.Page - 1) * query.Size)
+	}
+	err = modelDb.Order("id desc").Find(&list).Error
+	return list, count, err
+}
@@ -0,0 +1,220 @@
+package notify
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"server/utils/global"
+	"server/utils/logger"
+	"strings"
+	"sync"
+	"time"
+)
+
+// sendTimeout 单次发送的超时时间
+const sendTimeout = 15 * time.Second
+
+// retryInterval 检查待重试通知的间隔
+const retryInterval = 30 * time.Second
+
+var startOnce sync.Once
+
+// Start 启动通知重试任务，定期重新发送失败的通知
+func Start() {
+	startOnce.Do(func() {
+		go func() {
+			ticker := time.NewTicker(retryInterval)
+			defer ticker.Stop()
+			for range ticker.C {
+				retryDue()
+			}
+		}()
+	})
+}
+
+// Publish 发布事件，按订阅规则异步发送到各通知渠道
+func Publish(targetID uint, msg Message) {
+	if global.DB == nil {
+		return
+	}
+	if msg.Time.IsZero() {
+		msg.Time = time.Now()
+	}
+	go func() {
+		rules, err := NotifyRule{}.Match(msg.Event, targetID)
+		if err != nil {
+			logger.LOG.Errorf("查询通知规则失败: %s", err.Error())
+			return
+		}
+		for _, rule := range rules {
+			// 执行时间过长事件由WatchLongRunning按各规则的阈值单独发送
+			if rule.Event == EventSchTaskLongRunning {
+				continue
+			}
+			sendRule(rule, msg)
+		}
+	}()
+}
+
+// WatchLongRunning 监视任务执行时间，超过规则阈值时发送执行时间过长通知
+// 任务结束时调用返回的函数停止监视
+func WatchLongRunning(targetID uint, msg Message) func() {
+	if global.DB == nil {
+		return func() {}
+	}
+	rules, err := NotifyRule{}.Match(EventSchTaskLongRunning, targetID)
+	if err != nil || len(rules) == 0 {
+		return func() {}
+	}
+	msg.Event = EventSchTaskLongRunning
+	timers := make([]*time.Timer, 0, len(rules))
+	for _, rule := range rules {
+		rule := rule
+		timers = append(timers, time.AfterFunc(time.Duration(rule.Threshold)*time.Second, func() {
+			ruleMsg := msg
+			ruleMsg.Time = time.Now()
+			ruleMsg.Content = fmt.Sprintf("%s\n已执行超过 %d 秒", msg.Content, rule.Threshold)
+			sendRule(rule, ruleMsg)
+		}))
+	}
+	return func() {
+		for _, timer := range timers {
+			timer.Stop()
+		}
+	}
+}
+
+// sendRule 将消息发送到规则订阅的所有渠道
+func sendRule(rule NotifyRule, msg Message) {
+	for _, channelID := range rule.Channels() {
+		if _, err := deliver(context.Background(), rule.ID, channelID, msg); err != nil {
+			logger.LOG.Errorf("发送通知失败，规则:%s 渠道ID:%d 错误:%s", rule.Name, channelID, err.Error())
+		}
+	}
+}
+
+// Send 立即发送消息到指定渠道，返回各渠道的发送记录
+// 部分渠道发送失败时会自动重试，所有渠道都发送失败时返回错误
+func Send(ctx context.Context, channelIDs []uint, msg Message) ([]NotifyDelivery, error) {
+	if len(channelIDs) == 0 {
+		return nil, errors.New("通知渠道不能为空")
+	}
+	if msg.Time.IsZero() {
+		msg.Time = time.Now()
+	}
+	list := make([]NotifyDelivery, 0, len(channelIDs))
+	errs := make([]string, 0)
+	for _, channelID := range channelIDs {
+		delivery, err := deliver(ctx, 0, channelID, msg)
+		if delivery != nil {
+			list = append(list, *delivery)
+		}
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("渠道%d: %s", channelID, err.Error()))
+		}
+	}
+	if len(errs) == len(channelIDs) {
+		return list, errors.New("通知发送失败: " + strings.Join(errs, "; "))
+	}
+	return list, nil
+}
+
+// TestSend 发送测试消息，用于检查渠道配置
+func TestSend(channelID uint) (NotifyDelivery, error) {
+	msg := Message{
+		Event:   eventTest,
+		Level:   "info",
+		Title:   "测试通知",
+		Content: "这是一条测试通知，收到此消息说明通知渠道配置正确。",
+		Time:    time.Now(),
+	}
+	delivery, err := deliver(context.Background(), 0, channelID, msg)
+	if delivery == nil {
+		return NotifyDelivery{}, err
+	}
+	return *delivery, err
+}
+
+// Retry 立即重新发送指定的通知
+func Retry(deliveryID any) (NotifyDelivery, error) {
+	delivery, err := NotifyDelivery{}.Load(deliveryID)
+	if err != nil {
+		return delivery, err
+	}
+	if delivery.Status == StatusSuccess {
+		return delivery, errors.New("通知已发送成功")
+	}
+	// 手动重试时重新计算发送次数
+	if delivery.Status == StatusFailed {
+		delivery.Attempts = 0
+	}
+	err = attempt(context.Background(), &delivery)
+	return delivery, err
+}
+
+// deliver 创建发送记录并发送
+func deliver(ctx context.Context, ruleID, channelID uint, msg Message) (*NotifyDelivery, error) {
+	channel, err := NotifyChannel{}.Load(channelID)
+	if err != nil || channel.ID == 0 {
+		return nil, fmt.Errorf("通知渠道不存在: %d", channelID)
+	}
+	if channel.IsDisable == 1 {
+		return nil, fmt.Errorf("通知渠道已禁用: %s", channel.Name)
+	}
+	delivery, err := newDelivery(ruleID, channel, msg)
+	if err != nil {
+		return nil, err
+	}
+	return delivery, send(ctx, delivery, channel, msg)
+}
+
+// attempt 重新发送已有的发送记录
+func attempt(ctx context.Context, delivery *NotifyDelivery) error {
+	msg, err := delivery.message()
+	if err != nil {
+		delivery.Attempts = maxAttempts
+		delivery.finish(fmt.Errorf("消息格式错误: %v", err))
+		return err
+	}
+	channel, err := NotifyChannel{}.Load(delivery.ChannelID)
+	if err != nil || channel.ID == 0 {
+		err = fmt.Errorf("通知渠道不存在: %d", delivery.ChannelID)
+		delivery.finish(err)
+		return err
+	}
+	return send(ctx, delivery, channel, msg)
+}
+
+// send 通过渠道发送消息并记录结果
+func send(ctx context.Context, delivery *NotifyDelivery, channel NotifyChannel, msg Message) error {
+	err := func() error {
+		sender, err := getSender(channel.Type)
+		if err != nil {
+			return err
+		}
+		config, err := channel.ConfigMap()
+		if err != nil {
+			return err
+		}
+		sendCtx, cancel := context.WithTimeout(ctx, sendTimeout)
+		defer cancel()
+		return sender.Send(sendCtx, config, msg)
+	}()
+	delivery.finish(err)
+	return err
+}
+
+// retryDue 重新发送到达重试时间的通知
+func retryDue() {
+	if global.DB == nil {
+		return
+	}
+	list, err := NotifyDelivery{}.ListDue()
+	if err != nil {
+		logger.LOG.Errorf("查询待重试通知失败: %s", err.Error())
+		return
+	}
+	for i := range list {
+		attempt(context.Background(), &list[i])
+	}
+}
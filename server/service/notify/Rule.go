@@ -0,0 +1,71 @@
+package notify
+
+import (
+	"errors"
+	"server/core/db"
+	"server/utils/global"
+	"strconv"
+	"strings"
+)
+
+// 通知事件类型
+const (
+	EventSchTaskSuccess     = "sch_task_success"      // 计划任务执行成功
+	EventSchTaskFailed      = "sch_task_failed"       // 计划任务执行失败
+	EventSchTaskLongRunning = "sch_task_long_running" // 计划任务执行时间过长
+	EventSFlowFailed        = "sflow_failed"          // 作业流程执行失败
+
+	eventTest = "test" // 测试消息，发送失败时不重试
+)
+
+// NotifyRule 通知规则模型
+// 将事件订阅到一个或多个通知渠道，可限定事件来源的任务或流程
+type NotifyRule struct {
+	db.BaseModel[NotifyRule]        // 嵌入基础模型，提供ID、创建时间等公共字段
+	Name                     string `gorm:"comment:'名称' size:128" json:"name"`              // 规则名称
+	Event                    string `gorm:"comment:'事件类型' size:50" json:"event"`            // 订阅的事件类型
+	TargetID                 uint   `gorm:"default:0;comment:'事件来源ID'" json:"target_id"`    // 计划任务或流程ID，0表示全部
+	ChannelIDs               string `gorm:"comment:'通知渠道ID，逗号分隔'" json:"channel_ids"`       // 通知渠道ID，多个以逗号分隔
+	Threshold                int    `gorm:"default:0;comment:'执行时间阈值(秒)'" json:"threshold"` // 执行时间过长事件的阈值(秒)
+	Remark                   string `gorm:"comment:'备注'" json:"remark"`                     // 备注说明
+}
+
+// TableName 指定数据库表名
+func (NotifyRule) TableName() string {
+	return "notify_rule"
+}
+
+// Save 保存通知规则
+func (entity NotifyRule) Save(data *NotifyRule, columns ...string) error {
+	if data.Event == "" {
+		return errors.New("事件类型不能为空")
+	}
+	if len(data.Channels()) == 0 {
+		return errors.New("通知渠道不能为空")
+	}
+	if data.Event == EventSchTaskLongRunning && data.Threshold <= 0 {
+		return errors.New("执行时间过长事件需要设置时间阈值")
+	}
+	return entity.BaseModel.Save(data, columns...)
+}
+
+// Channels 获取规则的通知渠道ID
+func (entity NotifyRule) Channels() []uint {
+	ids := make([]uint, 0)
+	for _, item := range strings.Split(entity.ChannelIDs, ",") {
+		id, err := strconv.ParseUint(strings.TrimSpace(item), 10, 64)
+		if err == nil && id > 0 {
+			ids = append(ids, uint(id))
+		}
+	}
+	return ids
+}
+
+// Match 查询订阅了事件的启用规则
+func (entity NotifyRule) Match(event string, targetID uint) ([]NotifyRule, error) {
+	list := make([]NotifyRule, 0)
+	err := global.DB.Model(&NotifyRule{}).
+		Where("event = ? and is_disable = 0 and (target_id = 0 or target_id = ?)", event, targetID).
+		Find(&list).Error
+	return list, err
+}
@@ -0,0 +1,119 @@
+// Package notify 提供通知中心：通知渠道、订阅规则和发送记录
+// 渠道类型通过RegisterSender注册，内置通用Webhook、SMTP邮件以及钉钉、企业微信、飞书机器人
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"text/template"
+	"time"
+)
+
+// Message 通知消息
+type Message struct {
+	Event   string         `json:"event"`   // 事件类型
+	Level   string         `json:"level"`   // 级别：info、warn、error
+	Title   string         `json:"title"`   // 标题
+	Content string         `json:"content"` // 内容
+	Data    map[string]any `json:"data"`    // 附加数据，可在模板中使用
+	Time    time.Time      `json:"time"`    // 事件时间
+}
+
+// Sender 通知渠道发送接口
+type Sender interface {
+	// Send 使用渠道配置发送消息
+	Send(ctx context.Context, config map[string]any, msg Message) error
+}
+
+var (
+	sendersMu sync.RWMutex
+	senders   = make(map[string]Sender)
+)
+
+// RegisterSender 注册通知渠道类型
+func RegisterSender(channelType string, sender Sender) {
+	sendersMu.Lock()
+	defer sendersMu.Unlock()
+	senders[channelType] = sender
+}
+
+// getSender 获取通知渠道类型的发送器
+func getSender(channelType string) (Sender, error) {
+	sendersMu.RLock()
+	defer sendersMu.RUnlock()
+	sender, ok := senders[channelType]
+	if !ok {
+		return nil, fmt.Errorf("不支持的通知渠道类型: %s", channelType)
+	}
+	return sender, nil
+}
+
+// SenderTypes 获取已注册的通知渠道类型
+func SenderTypes() []string {
+	sendersMu.RLock()
+	defer sendersMu.RUnlock()
+	types := make([]string, 0, len(senders))
+	for channelType := range senders {
+		types = append(types, channelType)
+	}
+	return types
+}
+
+// templateFuncs 消息模板可用的函数，json用于在JSON模板中安全地输出字符串
+var templateFuncs = template.FuncMap{
+	"json": func(v any) (string, error) {
+		bytes, err := json.Marshal(v)
+		return string(bytes), err
+	},
+}
+
+// render 使用消息渲染模板
+func render(text string, msg Message) (string, error) {
+	tpl, err := template.New("notify").Funcs(templateFuncs).Parse(text)
+	if err != nil {
+		return "", fmt.Errorf("模板格式错误: %v", err)
+	}
+	var buf bytes.Buffer
+	if err := tpl.Execute(&buf, msg); err != nil {
+		return "", fmt.Errorf("模板渲染失败: %v", err)
+	}
+	return buf.String(), nil
+}
+
+// postJSON 发送JSON请求，响应状态码不是2xx时返回错误
+func postJSON(ctx context.Context, method, url string, headers map[string]string, body []byte) ([]byte, error) {
+	if url == "" {
+		return nil, fmt.Errorf("通知地址不能为空")
+	}
+	req, err := http.NewRequestWithContext(ctx, method, url, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	respBody, _ := io.ReadAll(io.LimitReader(resp.Body, 64*1024))
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return respBody, fmt.Errorf("通知地址返回错误状态码 %d: %s", resp.StatusCode, string(respBody))
+	}
+	return respBody, nil
+}
+
+// configString 获取字符串类型的渠道配置
+func configString(config map[string]any, key string) string {
+	if v, ok := config[key]; ok && v != nil {
+		return fmt.Sprint(v)
+	}
+	return ""
+}
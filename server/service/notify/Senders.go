@@ -0,0 +1,296 @@
+package notify
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/tls"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/smtp"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// 内置通知渠道类型
+const (
+	TypeWebhook  = "webhook"  // 通用Webhook
+	TypeEmail    = "email"    // SMTP邮件
+	TypeDingTalk = "dingtalk" // 钉钉机器人
+	TypeWeCom    = "wecom"    // 企业微信机器人
+	TypeFeishu   = "feishu"   // 飞书机器人
+)
+
+func init() {
+	RegisterSender(TypeWebhook, WebhookSender{})
+	RegisterSender(TypeEmail, EmailSender{})
+	RegisterSender(TypeDingTalk, DingTalkSender{})
+	RegisterSender(TypeWeCom, WeComSender{})
+	RegisterSender(TypeFeishu, FeishuSender{})
+}
+
+// defaultWebhookTemplate 未配置模板时Webhook发送的消息体
+const defaultWebhookTemplate = `{"event":{{json .Event}},"level":{{json .Level}},"title":{{json .Title}},"content":{{json .Content}},"data":{{json .Data}},"time":{{json .Time}}}`
+
+// WebhookSender 通用Webhook
+// 配置：url 地址、method 请求方法(默认POST)、headers 请求头、template JSON消息模板(Go模板语法)
+type WebhookSender struct{}
+
+// Send 发送Webhook请求
+func (WebhookSender) Send(ctx context.Context, config map[string]any, msg Message) error {
+	text := configString(config, "template")
+	if text == "" {
+		text = defaultWebhookTemplate
+	}
+	body, err := render(text, msg)
+	if err != nil {
+		return err
+	}
+	if !json.Valid([]byte(body)) {
+		return errors.New("模板渲染结果不是有效的JSON")
+	}
+	method := strings.ToUpper(configString(config, "method"))
+	if method == "" {
+		method = "POST"
+	}
+	headers := make(map[string]string)
+	if h, ok := config["headers"].(map[string]any); ok {
+		for k, v := range h {
+			headers[k] = fmt.Sprint(v)
+		}
+	}
+	_, err = postJSON(ctx, method, configString(config, "url"), headers, []byte(body))
+	return err
+}
+
+// EmailSender SMTP邮件
+// 配置：host、port、username、password、from、to(多个收件人以逗号分隔)、
+// tls 加密方式：none 不加密、starttls(默认)、ssl
+type EmailSender struct{}
+
+// Send 发送邮件
+func (EmailSender) Send(ctx context.Context, config map[string]any, msg Message) error {
+	host := configString(config, "host")
+	port := configString(config, "port")
+	if host == "" {
+		return errors.New("SMTP服务器地址不能为空")
+	}
+	if port == "" {
+		port = "25"
+	}
+	from := configString(config, "from")
+	if from == "" {
+		from = configString(config, "username")
+	}
+	to := make([]string, 0)
+	for _, addr := range strings.Split(configString(config, "to"), ",") {
+		if addr = strings.TrimSpace(addr); addr != "" {
+			to = append(to, addr)
+		}
+	}
+	if from == "" || len(to) == 0 {
+		return errors.New("发件人和收件人不能为空")
+	}
+
+	address := net.JoinHostPort(host, port)
+	dialer := &net.Dialer{Timeout: 15 * time.Second}
+	var conn net.Conn
+	var err error
+	mode := configString(config, "tls")
+	if mode == "ssl" {
+		conn, err = tls.DialWithDialer(dialer, "tcp", address, &tls.Config{ServerName: host})
+	} else {
+		conn, err = dialer.DialContext(ctx, "tcp", address)
+	}
+	if err != nil {
+		return fmt.Errorf("连接SMTP服务器失败: %v", err)
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+	client, err := smtp.NewClient(conn, host)
+	if err != nil {
+		conn.Close()
+		return fmt.Errorf("连接SMTP服务器失败: %v", err)
+	}
+	defer client.Close()
+
+	if mode != "ssl" && mode != "none" {
+		if ok, _ := client.Extension("STARTTLS"); ok {
+			if err := client.StartTLS(&tls.Config{ServerName: host}); err != nil {
+				return fmt.Errorf("STARTTLS失败: %v", err)
+			}
+		}
+	}
+	if username := configString(config, "username"); username != "" {
+		if ok, _ := client.Extension("AUTH"); ok {
+			if err := client.Auth(newPlainAuth(username, configString(config, "password"), host)); err != nil {
+				return fmt.Errorf("SMTP认证失败: %v", err)
+			}
+		}
+	}
+	if err := client.Mail(from); err != nil {
+		return err
+	}
+	for _, addr := range to {
+		if err := client.Rcpt(addr); err != nil {
+			return err
+		}
+	}
+	writer, err := client.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := writer.Write(buildMail(from, to, msg)); err != nil {
+		return err
+	}
+	if err := writer.Close(); err != nil {
+		return err
+	}
+	return client.Quit()
+}
+
+// newPlainAuth 创建PLAIN认证，允许在未加密的连接上认证，便于使用本地测试服务器
+func newPlainAuth(username, password, host string) smtp.Auth {
+	return &plainAuth{username: username, password: password, host: host}
+}
+
+// plainAuth 不校验连接是否加密的PLAIN认证
+type plainAuth struct {
+	username, password, host string
+}
+
+// Start 开始认证
+func (a *plainAuth) Start(server *smtp.ServerInfo) (string, []byte, error) {
+	return "PLAIN", []byte("\x00" + a.username + "\x00" + a.password), nil
+}
+
+// Next 继续认证
+func (a *plainAuth) Next(fromServer []byte, more bool) ([]byte, error) {
+	if more {
+		return nil, errors.New("SMTP服务器返回了意外的认证质询")
+	}
+	return nil, nil
+}
+
+// buildMail 构造邮件内容
+func buildMail(from string, to []string, msg Message) []byte {
+	var b strings.Builder
+	b.WriteString("From: " + from + "\r\n")
+	b.WriteString("To: " + strings.Join(to, ", ") + "\r\n")
+	b.WriteString("Subject: =?UTF-8?B?" + base64.StdEncoding.EncodeToString([]byte(msg.Title)) + "?=\r\n")
+	b.WriteString("Date: " + msg.Time.Format(time.RFC1123Z) + "\r\n")
+	b.WriteString("MIME-Version: 1.0\r\n")
+	b.WriteString("Content-Type: text/plain; charset=UTF-8\r\n")
+	b.WriteString("Content-Transfer-Encoding: base64\r\n\r\n")
+	content := base64.StdEncoding.EncodeToString([]byte(msg.Content))
+	for len(content) > 76 {
+		b.WriteString(content[:76] + "\r\n")
+		content = content[76:]
+	}
+	b.WriteString(content + "\r\n")
+	return []byte(b.String())
+}
+
+// robotText 机器人消息的文本内容
+func robotText(msg Message) string {
+	if msg.Title == "" {
+		return msg.Content
+	}
+	return msg.Title + "\n" + msg.Content
+}
+
+// DingTalkSender 钉钉机器人
+// 配置：url Webhook地址、secret 加签密钥(可选)
+type DingTalkSender struct{}
+
+// Send 发送钉钉机器人消息
+func (DingTalkSender) Send(ctx context.Context, config map[string]any, msg Message) error {
+	target := configString(config, "url")
+	if secret := configString(config, "secret"); secret != "" {
+		timestamp := strconv.FormatInt(time.Now().UnixMilli(), 10)
+		sign := hmacSign(secret, timestamp+"\n"+secret)
+		target = appendQuery(target, url.Values{"timestamp": {timestamp}, "sign": {sign}})
+	}
+	body, _ := json.Marshal(map[string]any{
+		"msgtype": "text",
+		"text":    map[string]any{"content": robotText(msg)},
+	})
+	respBody, err := postJSON(ctx, "POST", target, nil, body)
+	if err != nil {
+		return err
+	}
+	return robotError(respBody, "errcode", "errmsg")
+}
+
+// WeComSender 企业微信机器人
+// 配置：url Webhook地址
+type WeComSender struct{}
+
+// Send 发送企业微信机器人消息
+func (WeComSender) Send(ctx context.Context, config map[string]any, msg Message) error {
+	body, _ := json.Marshal(map[string]any{
+		"msgtype": "text",
+		"text":    map[string]any{"content": robotText(msg)},
+	})
+	respBody, err := postJSON(ctx, "POST", configString(config, "url"), nil, body)
+	if err != nil {
+		return err
+	}
+	return robotError(respBody, "errcode", "errmsg")
+}
+
+// FeishuSender 飞书机器人
+// 配置：url Webhook地址、secret 签名校验密钥(可选)
+type FeishuSender struct{}
+
+// Send 发送飞书机器人消息
+func (FeishuSender) Send(ctx context.Context, config map[string]any, msg Message) error {
+	payload := map[string]any{
+		"msg_type": "text",
+		"content":  map[string]any{"text": robotText(msg)},
+	}
+	if secret := configString(config, "secret"); secret != "" {
+		timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+		payload["timestamp"] = timestamp
+		payload["sign"] = hmacSign(timestamp+"\n"+secret, "")
+	}
+	body, _ := json.Marshal(payload)
+	respBody, err := postJSON(ctx, "POST", configString(config, "url"), nil, body)
+	if err != nil {
+		return err
+	}
+	return robotError(respBody, "code", "msg")
+}
+
+// hmacSign 计算HmacSHA256签名并进行Base64编码
+func hmacSign(key, data string) string {
+	mac := hmac.New(sha256.New, []byte(key))
+	mac.Write([]byte(data))
+	return base64.StdEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// appendQuery 为地址追加查询参数
+func appendQuery(target string, values url.Values) string {
+	if strings.Contains(target, "?") {
+		return target + "&" + values.Encode()
+	}
+	return target + "?" + values.Encode()
+}
+
+// robotError 检查机器人接口返回的错误码，错误码不为0时返回错误
+func robotError(respBody []byte, codeKey, msgKey string) error {
+	var result map[string]any
+	if err := json.Unmarshal(respBody, &result); err != nil {
+		return nil
+	}
+	if code, ok := result[codeKey].(float64); ok && code != 0 {
+		return fmt.Errorf("机器人接口返回错误 %v: %v", code, result[msgKey])
+	}
+	return nil
+}
@@ -0,0 +1,182 @@
+// Package retention 计划任务和流程执行日志的保留策略
+// 按保留数量、保留天数和日志文件总大小清理日志记录及其日志文件，执行中的日志不会被清理
+package retention
+
+import (
+	"os"
+	"server/core/db"
+	"server/utils/config"
+	"server/utils/global"
+	"server/utils/logger"
+	"sort"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// cleanupInterval 定期清理的间隔
+const cleanupInterval = 24 * time.Hour
+
+// deleteBatch 每批删除的日志记录数
+const deleteBatch = 500
+
+// Scope 限定日志记录范围的查询条件，如所属任务
+type Scope func(tx *gorm.DB) *gorm.DB
+
+// record 日志记录的ID和日志文件
+type record struct {
+	ID      uint
+	LogPath string
+}
+
+// finished 已执行结束的日志记录查询
+// model为日志模型，需要包含id、status、log_path和end_time字段
+func finished(model any, scope Scope) *gorm.DB {
+	tx := global.DB.Model(model).Where("status <> 0")
+	if scope != nil {
+		tx = scope(tx)
+	}
+	return tx
+}
+
+// Prune 只保留范围内最近的keep条日志，keep不大于0时不清理，返回删除的数量
+func Prune(model any, scope Scope, keep int) (int, error) {
+	if keep <= 0 {
+		return 0, nil
+	}
+	var records []record
+	err := finished(model, scope).Select("id", "log_path").Order("id desc").Limit(-1).Offset(keep).Find(&records).Error
+	if err != nil {
+		return 0, err
+	}
+	return remove(model, records)
+}
+
+// Purge 删除范围内所有已执行结束的日志，返回删除的数量
+func Purge(model any, scope Scope) (int, error) {
+	var records []record
+	if err := finished(model, scope).Select("id", "log_path").Find(&records).Error; err != nil {
+		return 0, err
+	}
+	return remove(model, records)
+}
+
+// Cleanup 删除结束时间超过keepDays天的日志，以及日志文件总大小超过maxBytes时较早的日志
+// 日志文件总大小按所有模型的日志合计，从结束时间最近的日志开始累计
+// keepDays或maxBytes不大于0时不按该条件清理，返回删除的数量
+func Cleanup(keepDays int, maxBytes int64, models ...any) (int, error) {
+	total := 0
+	if keepDays > 0 {
+		before := time.Now().AddDate(0, 0, -keepDays)
+		for _, model := range models {
+			var records []record
+			if err := finished(model, nil).Select("id", "log_path").Where("end_time < ?", before).Find(&records).Error; err != nil {
+				return total, err
+			}
+			count, err := remove(model, records)
+			total += count
+			if err != nil {
+				return total, err
+			}
+		}
+	}
+	if maxBytes > 0 {
+		count, err := cleanupSize(maxBytes, models)
+		total += count
+		if err != nil {
+			return total, err
+		}
+	}
+	return total, nil
+}
+
+// timedRecord 按结束时间合并多个模型的日志时使用的记录
+type timedRecord struct {
+	ID      uint
+	LogPath string
+	EndTime db.LocalTime
+	model   int // 所属模型在models中的下标
+}
+
+// cleanupSize 合并所有模型的日志，从结束时间最近的日志开始累计文件大小，超出上限后的日志都删除
+func cleanupSize(maxBytes int64, models []any) (int, error) {
+	var all []timedRecord
+	for i, model := range models {
+		var records []timedRecord
+		if err := finished(model, nil).Select("id", "log_path", "end_time").Find(&records).Error; err != nil {
+			return 0, err
+		}
+		for j := range records {
+			records[j].model = i
+		}
+		all = append(all, records...)
+	}
+	sort.SliceStable(all, func(i, j int) bool { return all[i].EndTime.After(all[j].EndTime.Time) })
+
+	var size int64
+	for i, item := range all {
+		if item.LogPath == "" {
+			continue
+		}
+		if info, err := os.Stat(item.LogPath); err == nil {
+			size += info.Size()
+		}
+		if size <= maxBytes {
+			continue
+		}
+		// 按模型分组删除超出上限的日志
+		groups := make([][]record, len(models))
+		for _, over := range all[i:] {
+			groups[over.model] = append(groups[over.model], record{ID: over.ID, LogPath: over.LogPath})
+		}
+		total := 0
+		for m, records := range groups {
+			count, err := remove(models[m], records)
+			total += count
+			if err != nil {
+				return total, err
+			}
+		}
+		return total, nil
+	}
+	return 0, nil
+}
+
+// remove 删除日志文件和日志记录
+func remove(model any, records []record) (int, error) {
+	for start := 0; start < len(records); start += deleteBatch {
+		batch := records[start:min(start+deleteBatch, len(records))]
+		ids := make([]uint, 0, len(batch))
+		for _, item := range batch {
+			if item.LogPath != "" {
+				if err := os.Remove(item.LogPath); err != nil && !os.IsNotExist(err) {
+					logger.LOG.Warnf("删除日志文件 %s 失败: %v", item.LogPath, err)
+				}
+			}
+			ids = append(ids, item.ID)
+		}
+		if err := global.DB.Where("id IN ?", ids).Delete(model).Error; err != nil {
+			return start, err
+		}
+	}
+	return len(records), nil
+}
+
+// StartCleanup 启动定期清理，启动时立即执行一次，之后每天执行
+// models为需要清理的日志模型，按配置的保留天数和所有模型的日志文件总大小清理，只应启动一次
+func StartCleanup(models ...any) {
+	go func() {
+		ticker := time.NewTicker(cleanupInterval)
+		defer ticker.Stop()
+		for {
+			keepDays := config.CONF.JobLog.KeepDays
+			maxBytes := int64(config.CONF.JobLog.MaxSize) * 1024 * 1024
+			if deleted, err := Cleanup(keepDays, maxBytes, models...); err != nil {
+				logger.LOG.Errorf("清理执行日志失败: %v", err)
+			} else if deleted > 0 {
+				logger.LOG.Infof("已清理 %d 条执行日志", deleted)
+			}
+			<-ticker.C
+		}
+	}()
+}
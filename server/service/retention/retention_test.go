@@ -0,0 +1,107 @@
+package retention
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"server/core/db"
+	"server/utils/global"
+	"testing"
+	"time"
+
+	"github.com/glebarez/sqlite"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+	gormlogger "gorm.io/gorm/logger"
+)
+
+// taskLog 测试用的计划任务日志模型
+type taskLog struct {
+	ID      uint
+	Status  int
+	LogPath string
+	EndTime db.LocalTime
+}
+
+// flowLog 测试用的流程日志模型
+type flowLog struct {
+	ID      uint
+	Status  int
+	LogPath string
+	EndTime db.LocalTime
+}
+
+// setupDB 为每个测试创建独立的内存数据库，测试结束后恢复全局数据库连接
+func setupDB(t *testing.T) {
+	t.Helper()
+	dsn := fmt.Sprintf("file:retention_%d?mode=memory&cache=shared", time.Now().UnixNano())
+	conn, err := gorm.Open(sqlite.Open(dsn), &gorm.Config{Logger: gormlogger.Discard})
+	require.NoError(t, err)
+	require.NoError(t, conn.AutoMigrate(&taskLog{}, &flowLog{}))
+	old := global.DB
+	global.DB = conn
+	t.Cleanup(func() {
+		global.DB = old
+		if sqlDB, err := conn.DB(); err == nil {
+			sqlDB.Close()
+		}
+	})
+}
+
+// createLog 创建已执行结束的日志和指定大小的日志文件，结束时间为相对当前的偏移
+func createLog(t *testing.T, entity any, size int, end time.Duration) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "run.log")
+	require.NoError(t, os.WriteFile(path, make([]byte, size), 0644))
+	endTime := db.LocalTime{Time: time.Now().Add(end)}
+	switch item := entity.(type) {
+	case *taskLog:
+		item.Status, item.LogPath, item.EndTime = 1, path, endTime
+	case *flowLog:
+		item.Status, item.LogPath, item.EndTime = 1, path, endTime
+	}
+	require.NoError(t, global.DB.Create(entity).Error)
+	return path
+}
+
+// TestCleanupSizeAcrossModels 测试日志文件大小按所有模型合计，超出上限时删除结束时间较早的日志
+func TestCleanupSizeAcrossModels(t *testing.T) {
+	setupDB(t)
+	newest := createLog(t, &taskLog{}, 400, -time.Minute)
+	newer := createLog(t, &flowLog{}, 400, -2*time.Minute)
+	older := createLog(t, &taskLog{}, 400, -3*time.Minute)
+	oldest := createLog(t, &flowLog{}, 400, -4*time.Minute)
+
+	// 单个模型都不超过上限，合计超过上限
+	deleted, err := Cleanup(0, 1000, &taskLog{}, &flowLog{})
+	require.NoError(t, err)
+	assert.Equal(t, 2, deleted)
+
+	for _, path := range []string{newest, newer} {
+		assert.FileExists(t, path)
+	}
+	for _, path := range []string{older, oldest} {
+		assert.NoFileExists(t, path)
+	}
+	var tasks, flows int64
+	global.DB.Model(&taskLog{}).Count(&tasks)
+	global.DB.Model(&flowLog{}).Count(&flows)
+	assert.Equal(t, int64(1), tasks)
+	assert.Equal(t, int64(1), flows)
+}
+
+// TestCleanupDisabled 测试保留天数和大小上限为0时不清理
+func TestCleanupDisabled(t *testing.T) {
+	setupDB(t)
+	createLog(t, &taskLog{}, 400, -100*24*time.Hour)
+	createLog(t, &flowLog{}, 400, -100*24*time.Hour)
+
+	deleted, err := Cleanup(0, 0, &taskLog{}, &flowLog{})
+	require.NoError(t, err)
+	assert.Equal(t, 0, deleted)
+
+	deleted, err = Cleanup(90, 0, &taskLog{}, &flowLog{})
+	require.NoError(t, err)
+	assert.Equal(t, 2, deleted)
+}
@@ -8,17 +8,22 @@ import (
 	"encoding/json"
 	"fmt"
 	"server/core/db"
+	"server/service/retention"
+	"server/service/scheduled/cluster"
 	"server/service/scheduled/job"
 	"server/service/scheduled/job/filebackup"
 	"server/service/scheduled/job/fileclean"
 	shell "server/service/scheduled/job/script"
 	"server/service/scheduled/job/task"
 	"server/service/scheduled/log"
+	"server/service/sflow"
 	"server/utils"
+	"server/utils/cmd"
 	"server/utils/cron"
 	"server/utils/global"
 	"server/utils/logger"
 	"strings"
+	"time"
 
 	"github.com/duke-git/lancet/v2/convertor"
 )
@@ -28,6 +33,7 @@ import (
 // - FILE_BACKUP: 文件备份任务
 // - FILE_CLEAN: 文件清理任务
 // - JOB_TASK: 作业任务
+// - DB_BACKUP: 数据库备份任务
 
 // SchTask 计划任务数据模型
 // 用于存储和管理系统中的定时任务
@@ -35,15 +41,39 @@ import (
 type SchTask struct {
 	db.BaseModel[SchTask]        // 基础模型字段（ID、创建时间等）
 	Name                  string `gorm:"comment:'名称' size:128" json:"name"`                 // 任务名称
-	Type                  string `gorm:"comment:'类型' size:50 default:'shell'" json:"type"`  // 任务类型（SHELL/FILE_BACKUP/FILE_CLEAN/JOB_TASK）
+	Type                  string `gorm:"comment:'类型' size:50 default:'shell'" json:"type"`  // 任务类型（SHELL/FILE_BACKUP/FILE_CLEAN/JOB_TASK/DB_BACKUP）
 	Cron                  string `gorm:"comment:'调度表达式' size:50" json:"cron"`               // Cron表达式
+	Timezone              string `gorm:"comment:'时区' size:64" json:"timezone"`              // 调度表达式的时区，如Asia/Shanghai，为空时使用服务器时区
 	LastStatus            uint   `gorm:"default:0;comment:'最近状态'" json:"last_status"`       // 任务最近执行状态
-	LastRunTime           string `gorm:"comment:'上次执行时间'" json:"last_run_time"`             // 上次执行时间
-	NextRunTime           string `gorm:"comment:'下次执行时间'" json:"next_run_time"`             // 下次执行时间
+	LastRunTime           string `gorm:"comment:'上次执行时间'" json:"last_run_time"`             // 上次开始执行的时间，服务器时区
+	NextRunTime           string `gorm:"comment:'下次执行时间'" json:"next_run_time"`             // 调度器中的下次执行时间，服务器时区，未调度时为空
 	LogKeepNum            uint   `gorm:"default:0;comment:'保留日志数量'" json:"log_keep_num"`    // 保留的日志数量
 	Script                string `gorm:"comment:'任务' size:102400 default:''" json:"script"` // 任务配置脚本（JSON格式）
 	Remark                string `gorm:"comment:'备注'" json:"remark"`                        // 任务备注
 	ProjectDirID          string `gorm:"comment:'项目目录ID';default:0" json:"project_dir_id"`  // 项目目录ID，关联到项目目录
+	Upstream              string `gorm:"comment:'上游任务ID' size:512" json:"upstream"`         // 依赖的上游任务ID，逗号分隔，上游任务都执行结束后触发执行
+	DependMode            string `gorm:"comment:'依赖条件' size:20" json:"depend_mode"`         // 依赖条件：success上游都执行成功，any上游执行结束即可，为空时为success
+	RetryMax              uint   `gorm:"default:0;comment:'最多执行次数'" json:"retry_max"`       // 失败时最多执行次数（包含第一次），0或1不重试
+	RetryDelay            uint   `gorm:"default:0;comment:'重试间隔'" json:"retry_delay"`       // 第一次重试前等待的秒数
+	RetryBackoff          uint   `gorm:"default:0;comment:'指数退避'" json:"retry_backoff"`     // 是否指数退避：1每次重试的等待时间翻倍
+	RetryOn               string `gorm:"comment:'重试条件' size:20" json:"retry_on"`            // 重试条件：all所有错误，timeout只重试超时错误，为空时为all
+	MisfirePolicy         string `gorm:"comment:'错过调度策略' size:20" json:"misfire_policy"`    // 服务停止期间错过调度的处理：ignore忽略，once补执行一次，all逐个补执行，为空时为ignore
+	MisfireMax            uint   `gorm:"default:0;comment:'最多补执行次数'" json:"misfire_max"`    // all策略最多补执行的次数，为0时为10
+	Timeout               uint   `gorm:"default:0;comment:'超时时间'" json:"timeout"`           // 执行超时秒数，为0时Shell任务为5小时，文件备份任务使用rclone配置，数据库备份任务不限制
+	WorkDir               string `gorm:"comment:'工作目录' size:512" json:"work_dir"`           // Shell任务的工作目录，为空时使用数据目录下的job目录
+	IsolateDir            uint   `gorm:"default:0;comment:'隔离临时目录'" json:"isolate_dir"`     // 1每次执行在工作目录下创建独立的临时目录作为工作目录，执行后删除
+	Env                   string `gorm:"comment:'环境变量' size:4096" json:"env"`               // Shell任务的环境变量，每行一个KEY=VALUE，#开头的行为注释
+	Interpreter           string `gorm:"comment:'解释器' size:20" json:"interpreter"`          // Shell任务的解释器：sh、bash、python，为空时为sh
+	RunAs                 string `gorm:"comment:'执行用户' size:64" json:"run_as"`              // 执行Shell任务的系统用户，仅Linux支持，为空时为当前用户
+	LimitNice             int    `gorm:"default:0;comment:'CPU优先级'" json:"limit_nice"`      // Shell任务的CPU调度优先级，-20到19，越大优先级越低，0不调整
+	LimitIOClass          uint   `gorm:"default:0;comment:'IO调度类别'" json:"limit_io_class"`  // Shell任务的IO调度类别：1实时 2尽力而为 3空闲，0不调整
+	LimitIOLevel          uint   `gorm:"default:0;comment:'IO优先级'" json:"limit_io_level"`   // Shell任务的IO优先级，0到7，越小优先级越高
+	LimitMemory           uint   `gorm:"default:0;comment:'内存上限'" json:"limit_memory"`      // Shell任务的内存上限(MB)，cgroup v2可用时限制物理内存，否则限制虚拟内存，0不限制
+	LimitNoFile           uint   `gorm:"default:0;comment:'打开文件数上限'" json:"limit_no_file"`  // Shell任务最多打开的文件数，0不限制
+	LimitCPU              uint   `gorm:"default:0;comment:'CPU上限'" json:"limit_cpu"`        // Shell任务的CPU上限百分比，100为一个核，需要cgroup v2，0不限制
+
+	trigger *job.Trigger `gorm:"-"` // 触发本次执行的上游任务
+	misfire *job.Misfire `gorm:"-"` // 补执行的错过的调度
 }
 
 // TableName 返回数据库表名
@@ -54,6 +84,25 @@ func (SchTask) TableName() string {
 // Create 创建新的计划任务
 // 首先将任务保存到数据库，然后将其添加到定时任务调度器中
 func (schTask SchTask) Create(entity *SchTask) error {
+	// 校验上游任务，不允许循环依赖
+	if err := entity.checkUpstream(); err != nil {
+		return err
+	}
+	if err := entity.checkRetry(); err != nil {
+		return err
+	}
+	if err := entity.checkCron(); err != nil {
+		return err
+	}
+	if err := entity.checkMisfire(); err != nil {
+		return err
+	}
+	if err := entity.checkExec(); err != nil {
+		return err
+	}
+	if err := entity.checkDbBackup(); err != nil {
+		return err
+	}
 	// 将任务保存到数据库
 	err := global.DB.Model(entity).Create(entity).Error
 	if err != nil {
@@ -65,12 +114,35 @@ func (schTask SchTask) Create(entity *SchTask) error {
 	if err != nil {
 		return err
 	}
-	return cron.AddJobTask(job)
+	if err = cron.AddJobTask(job); err != nil {
+		return err
+	}
+	updateNextRunTime(entity.ID)
+	return nil
 }
 
 // Update 更新计划任务
 // 首先更新数据库中的任务信息，然后更新调度器中的任务
 func (schTask SchTask) Update(entity *SchTask, columns ...string) (err error) {
+	// 校验上游任务，不允许循环依赖
+	if err = entity.checkUpstream(); err != nil {
+		return err
+	}
+	if err = entity.checkRetry(); err != nil {
+		return err
+	}
+	if err = entity.checkCron(); err != nil {
+		return err
+	}
+	if err = entity.checkMisfire(); err != nil {
+		return err
+	}
+	if err = entity.checkExec(); err != nil {
+		return err
+	}
+	if err = entity.checkDbBackup(); err != nil {
+		return err
+	}
 	// 更新数据库中的任务信息
 	if len(columns) > 0 {
 		// 如果指定了列，只更新指定列
@@ -99,7 +171,11 @@ func (schTask SchTask) Update(entity *SchTask, columns ...string) (err error) {
 	if err != nil {
 		return err
 	}
-	return cron.UpdateJobTask(job)
+	if err = cron.UpdateJobTask(job); err != nil {
+		return err
+	}
+	updateNextRunTime(entity.ID)
+	return nil
 }
 
 // Enable 启用指定的计划任务
@@ -123,6 +199,7 @@ func (entity SchTask) Enable(id any) error {
 		return err
 	}
 	cron.AddJobTask(job)
+	updateNextRunTime(entity.ID)
 	return nil
 }
 
@@ -137,6 +214,7 @@ func (entity SchTask) Disable(id any) error {
 
 	// 从调度器中移除任务
 	cron.RemoveJobTask(id)
+	global.DB.Model(&entity).Where(db.ID_FIELD+" =?", id).Update("next_run_time", "")
 	return nil
 }
 
@@ -151,6 +229,7 @@ func (entity SchTask) Delete(id any) error {
 
 	// 从调度器中移除任务
 	cron.RemoveJobTask(id)
+	forgetTriggered(utils.ToUint(fmt.Sprint(id)))
 	return nil
 }
 
@@ -158,9 +237,14 @@ func (entity SchTask) Delete(id any) error {
 // 在系统启动时调用，用于初始化并启动所有有效的计划任务
 func Start() {
 	// 修复异常退出的任务日志，将未完成的任务标记为异常终止
+	// 启用集群调度时只修复本节点的日志，其他节点的任务可能还在执行
 	fix := log.SchLog{}
 	fix.Status = -2 // -2表示异常终止
-	global.DB.Model(fix).Where("status=0").Updates(fix)
+	fixDb := global.DB.Model(fix).Where("status=0")
+	if cluster.Enabled() {
+		fixDb = fixDb.Where("node = ? or node = '' or node IS NULL", cluster.NodeID())
+	}
+	fixDb.Updates(fix)
 
 	// 查询需要启动的定时任务
 	// 条件：未禁用、未删除、有有效的Cron表达式
@@ -171,6 +255,16 @@ func Start() {
 		logger.LOG.Errorf("获取定时任务列表失败:%s", err.Error())
 	}
 
+	// 任务执行结束后清理超出保留数量的日志，并触发依赖该任务的下游任务
+	log.OnFinished = finished
+	// 调度执行时获取集群锁，并更新上次和下次执行时间
+	cron.OnRun = func(id string, scheduled time.Time, run func()) { runScheduled(utils.ToUint(id), scheduled, run) }
+	// 按保留天数和日志文件总大小定期清理日志，流程执行日志一起清理，文件大小按两者合计
+	retention.StartCleanup(&log.SchLog{}, &sflow.SFlowLog{})
+
+	// 启动调度前记录时间，之前错过的调度按任务的策略补执行
+	now := time.Now()
+
 	// 将数据库中的任务转换为调度器任务
 	jobs := make([]cron.SchJob, 0)
 	for _, item := range list {
@@ -185,6 +279,27 @@ func Start() {
 	if err != nil {
 		logger.LOG.Errorf("启动定时任务失败:%s", err.Error())
 	}
+	for _, item := range list {
+		updateNextRunTime(item.ID)
+	}
+	catchUp(list, now)
+}
+
+// finished 任务执行结束后的处理
+func finished(job job.SchJob, entity log.SchLog) {
+	pruneLogs(job.TaskId)
+	triggerDownstream(job, entity)
+}
+
+// pruneLogs 清理任务超出保留数量的日志
+func pruneLogs(taskID uint) {
+	task, err := SchTask{}.Load(taskID)
+	if err != nil || task.LogKeepNum == 0 {
+		return
+	}
+	if _, err := (log.SchLog{}).Prune(taskID, int(task.LogKeepNum)); err != nil {
+		logger.LOG.Errorf("清理任务 %d 的日志失败:%s", taskID, err.Error())
+	}
 }
 
 // Exec 手动执行任务
@@ -196,11 +311,164 @@ func (entity SchTask) Exec() error {
 		return err
 	}
 
+	// 获取执行锁，任务正在其他节点上执行时不能手动执行
+	lease, err := cluster.AcquireRun(entity.ID)
+	if err != nil {
+		return err
+	}
+
 	// 在新的goroutine中异步执行任务
-	go job.Job.Run()
+	markRun(entity.ID)
+	go func() {
+		defer lease.Release()
+		job.Job.Run()
+	}()
 	return nil
 }
 
+// markRun 记录任务开始执行，更新上次执行时间和调度器中的下次执行时间
+func markRun(taskID uint) {
+	next, _, ok := cron.Next(taskID)
+	values := map[string]any{"last_run_time": time.Now().Format(db.TimeFormat), "next_run_time": formatRunTime(next, ok)}
+	if err := global.DB.Model(&SchTask{}).Where(db.ID_FIELD+" =?", taskID).Updates(values).Error; err != nil {
+		logger.LOG.Errorf("更新任务 %d 的执行时间失败:%s", taskID, err.Error())
+	}
+}
+
+// updateNextRunTime 按调度器中的任务更新下次执行时间，任务未调度时清空
+func updateNextRunTime(taskID uint) {
+	next, _, ok := cron.Next(taskID)
+	if err := global.DB.Model(&SchTask{}).Where(db.ID_FIELD+" =?", taskID).Update("next_run_time", formatRunTime(next, ok)).Error; err != nil {
+		logger.LOG.Errorf("更新任务 %d 的下次执行时间失败:%s", taskID, err.Error())
+	}
+}
+
+// formatRunTime 将调度时间格式化为服务器时区的时间字符串，未调度时为空
+func formatRunTime(t time.Time, ok bool) string {
+	if !ok || t.IsZero() {
+		return ""
+	}
+	return t.Local().Format(db.TimeFormat)
+}
+
+// checkCron 校验调度表达式和时区，表达式为空时只能手动或由上游任务触发执行
+func (entity SchTask) checkCron() error {
+	if strings.TrimSpace(entity.Cron) == "" {
+		return nil
+	}
+	_, err := cron.Parse(entity.Cron, entity.Timezone)
+	return err
+}
+
+// Preview 校验调度表达式，返回中英文描述和后n次执行时间
+func (entity SchTask) Preview(expr, timezone string, n int) (cron.Preview, error) {
+	return cron.NewPreview(expr, timezone, n)
+}
+
+// checkRetry 校验重试条件
+func (entity SchTask) checkRetry() error {
+	switch entity.RetryOn {
+	case "", job.RetryOnAll, job.RetryOnTimeout:
+		return nil
+	}
+	return fmt.Errorf("不支持的重试条件: %s", entity.RetryOn)
+}
+
+// checkExec 校验执行环境设置：解释器、执行用户、环境变量和资源限制
+func (entity SchTask) checkExec() error {
+	if err := cmd.CheckShell(entity.Interpreter); err != nil {
+		return err
+	}
+	if entity.RunAs != "" {
+		if err := cmd.CheckUser(entity.RunAs); err != nil {
+			return err
+		}
+	}
+	if _, err := parseEnv(entity.Env); err != nil {
+		return err
+	}
+	if limits := entity.limits(); limits != nil {
+		return limits.Check()
+	}
+	return nil
+}
+
+// limits 构建Shell任务的资源限制，没有设置任何限制时为nil
+// 最长执行时间使用任务的超时时间
+func (entity SchTask) limits() *cmd.Limits {
+	limits := cmd.Limits{
+		Nice:       entity.LimitNice,
+		IOClass:    int(entity.LimitIOClass),
+		IOLevel:    int(entity.LimitIOLevel),
+		MemoryMB:   int(entity.LimitMemory),
+		NoFile:     int(entity.LimitNoFile),
+		CPUPercent: int(entity.LimitCPU),
+	}
+	if limits.IsZero() {
+		return nil
+	}
+	return &limits
+}
+
+// parseEnv 解析每行一个KEY=VALUE的环境变量，忽略空行和#开头的注释行
+func parseEnv(text string) ([]string, error) {
+	env := make([]string, 0)
+	for _, line := range strings.Split(text, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		key, _, ok := strings.Cut(line, "=")
+		if !ok || key == "" || strings.ContainsAny(key, " \t") {
+			return nil, fmt.Errorf("环境变量格式错误，应为KEY=VALUE: %s", line)
+		}
+		env = append(env, line)
+	}
+	return env, nil
+}
+
+// Running 获取正在执行的计划任务
+func (entity SchTask) Running() []log.Running {
+	return log.ListRunning()
+}
+
+// Cancel 取消日志ID对应的正在执行的任务，结束其启动的整个进程组
+func (entity SchTask) Cancel(logID uint) error {
+	return log.Cancel(logID)
+}
+
+// CancelTask 取消任务所有正在执行的实例，返回取消的数量
+func (entity SchTask) CancelTask(id any) int {
+	taskID, _ := convertor.ToInt(id)
+	return log.CancelTask(uint(taskID))
+}
+
+// schJob 构建任务的基础信息
+func (entity SchTask) schJob() job.SchJob {
+	return job.SchJob{
+		TaskId:   entity.ID,
+		TaskName: entity.Name,
+		TaskType: entity.Type,
+		Trigger:  entity.trigger,
+		Misfire:  entity.misfire,
+		Timeout:  time.Duration(entity.Timeout) * time.Second,
+		Retry:    entity.retry(),
+	}
+}
+
+// retry 构建任务的重试策略，最多执行次数不超过1时不重试
+func (entity SchTask) retry() *job.Retry {
+	if entity.RetryMax <= 1 {
+		return nil
+	}
+	return &job.Retry{
+		MaxAttempts: int(entity.RetryMax),
+		Delay:       time.Duration(entity.RetryDelay) * time.Second,
+		Backoff:     entity.RetryBackoff == 1,
+		On:          entity.RetryOn,
+	}
+}
+
 // toShellJob 将计划任务转换为Shell脚本任务
 // 解析Script字段中的JSON数据，提取shell脚本内容
 func (entity SchTask) toShellJob() (shell.ShellJob, error) {
@@ -210,15 +478,23 @@ func (entity SchTask) toShellJob() (shell.ShellJob, error) {
 	if err != nil {
 		return shell.ShellJob{}, err
 	}
+	env, err := parseEnv(entity.Env)
+	if err != nil {
+		return shell.ShellJob{}, err
+	}
 
 	// 创建Shell脚本任务
 	mjob := shell.ShellJob{
-		SchJob: job.SchJob{
-			TaskId:   entity.ID,
-			TaskName: entity.Name,
-			TaskType: entity.Type,
-		},
-		Script: fmt.Sprint(obj["shell"]), // 提取shell脚本内容
+		SchJob:       entity.schJob(),
+		Script:       fmt.Sprint(obj["shell"]),                                                      // 提取shell脚本内容
+		Secrets:      strings.Fields(strings.ReplaceAll(utils.GetString(obj, "secrets"), ",", " ")), // 以环境变量注入的密钥名称
+		ProjectDirID: entity.ProjectDirID,                                                           // 所属项目目录，限定可访问的密钥
+		WorkDir:      entity.WorkDir,                                                                // 工作目录
+		IsolateDir:   entity.IsolateDir == 1,                                                        // 是否使用独立的临时目录
+		Env:          env,                                                                           // 任务配置的环境变量
+		Shell:        entity.Interpreter,                                                            // 解释器
+		User:         entity.RunAs,                                                                  // 执行用户
+		Limits:       entity.limits(),                                                               // 资源限制
 	}
 	return mjob, nil
 }
@@ -249,11 +525,7 @@ func (entity SchTask) toFileBackupJob() (filebackup.FileBackupJob, error) {
 
 	// 创建文件备份任务
 	mjob := filebackup.FileBackupJob{
-		SchJob: job.SchJob{
-			TaskId:   entity.ID,
-			TaskName: entity.Name,
-			TaskType: entity.Type,
-		},
+		SchJob:             entity.schJob(),
 		Type:               utils.GetUint(obj, "type", 0),                         // 备份类型（1备份 2镜像 3双向同步 4完整备份）
 		Src:                src,                                                   // 源路径
 		Dst:                dst,                                                   // 目标路径
@@ -286,11 +558,7 @@ func (entity SchTask) toFileCleanJob() (fileclean.FileCleanJob, error) {
 
 	// 创建文件清理任务
 	mjob := fileclean.FileCleanJob{
-		SchJob: job.SchJob{
-			TaskId:   entity.ID,
-			TaskName: entity.Name,
-			TaskType: entity.Type,
-		},
+		SchJob:    entity.schJob(),
 		WorkDir:   workDir,                                               // 工作目录
 		Includes:  strings.Split(utils.GetString(obj, "includes"), "\n"), // 包含的文件模式
 		Excludes:  strings.Split(utils.GetString(obj, "excludes"), "\n"), // 排除的文件模式
@@ -314,11 +582,7 @@ func (entity SchTask) toTaskJob() (task.TaskJob, error) {
 
 	// 创建作业任务
 	mjob := task.TaskJob{
-		SchJob: job.SchJob{
-			TaskId:   entity.ID,
-			TaskName: entity.Name,
-			TaskType: entity.Type,
-		},
+		SchJob: entity.schJob(),
 		Script: fmt.Sprint(obj["script"]), // 作业脚本内容
 	}
 	return mjob, nil
@@ -334,28 +598,35 @@ func (entity SchTask) toJob() (cron.SchJob, error) {
 		if err != nil {
 			return cron.SchJob{}, err
 		}
-		return cron.SchJob{Id: fmt.Sprint(entity.ID), Cron: entity.Cron, Job: mjob}, nil
+		return cron.SchJob{Id: fmt.Sprint(entity.ID), Cron: entity.Cron, Timezone: entity.Timezone, Job: mjob}, nil
 
 	case "FILE_BACKUP": // 文件备份任务
 		mjob, err := entity.toFileBackupJob()
 		if err != nil {
 			return cron.SchJob{}, err
 		}
-		return cron.SchJob{Id: fmt.Sprint(entity.ID), Cron: entity.Cron, Job: mjob}, nil
+		return cron.SchJob{Id: fmt.Sprint(entity.ID), Cron: entity.Cron, Timezone: entity.Timezone, Job: mjob}, nil
 
 	case "FILE_CLEAN": // 文件清理任务
 		mjob, err := entity.toFileCleanJob()
 		if err != nil {
 			return cron.SchJob{}, err
 		}
-		return cron.SchJob{Id: fmt.Sprint(entity.ID), Cron: entity.Cron, Job: mjob}, nil
+		return cron.SchJob{Id: fmt.Sprint(entity.ID), Cron: entity.Cron, Timezone: entity.Timezone, Job: mjob}, nil
 
 	case "JOB_TASK": // 作业任务
 		mjob, err := entity.toTaskJob()
 		if err != nil {
 			return cron.SchJob{}, err
 		}
-		return cron.SchJob{Id: fmt.Sprint(entity.ID), Cron: entity.Cron, Job: mjob}, nil
+		return cron.SchJob{Id: fmt.Sprint(entity.ID), Cron: entity.Cron, Timezone: entity.Timezone, Job: mjob}, nil
+
+	case "DB_BACKUP": // 数据库备份任务
+		mjob, err := entity.toDbBackupJob()
+		if err != nil {
+			return cron.SchJob{}, err
+		}
+		return cron.SchJob{Id: fmt.Sprint(entity.ID), Cron: entity.Cron, Timezone: entity.Timezone, Job: mjob}, nil
 
 	default: // 未知的任务类型
 		return cron.SchJob{}, fmt.Errorf("未知的任务类型:%s", entity.Type)
@@ -0,0 +1,38 @@
+package scheduled
+
+// SchTaskCluster.go
+// 该文件实现了多个实例共用数据库时的调度协调
+// 每次调度先获取该计划时间的调度锁，同一次调度只在一个节点上执行，执行期间持有任务执行锁
+
+import (
+	"server/service/scheduled/cluster"
+	"server/utils/logger"
+	"time"
+)
+
+// runScheduled 调度器触发任务时获取集群锁后执行
+// 本次调度已由其他节点执行或任务正在执行时跳过
+func runScheduled(taskID uint, scheduled time.Time, run func()) {
+	ok, err := cluster.TryFire(taskID, scheduled)
+	if err != nil {
+		logger.LOG.Errorf("获取任务 %d 的调度锁失败:%s", taskID, err.Error())
+		return
+	}
+	if !ok {
+		logger.LOG.Debugf("任务 %d 在 %s 的调度已由其他节点执行", taskID, scheduled.Format(time.RFC3339))
+		return
+	}
+	lease, err := cluster.AcquireRun(taskID)
+	if err != nil {
+		logger.LOG.Warnf("跳过任务 %d 在 %s 的调度:%s", taskID, scheduled.Format(time.RFC3339), err.Error())
+		return
+	}
+	defer lease.Release()
+	markRun(taskID)
+	run()
+}
+
+// Cluster 获取集群调度状态
+func (entity SchTask) Cluster() cluster.Info {
+	return cluster.GetInfo()
+}
@@ -0,0 +1,133 @@
+package scheduled
+
+// SchTaskDbBackup.go
+// 该文件实现了数据库备份任务的配置解析、备份列表和SQLite备份的恢复
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"server/data"
+	"server/service/scheduled/cluster"
+	"server/service/scheduled/job/dbbackup"
+	"server/service/scheduled/log"
+	"server/utils"
+	"server/utils/config"
+	"strings"
+)
+
+// toDbBackupJob 将计划任务转换为数据库备份任务
+// 解析Script字段中的JSON数据，use_system为true时备份系统正在使用的数据库
+func (entity SchTask) toDbBackupJob() (dbbackup.DbBackupJob, error) {
+	// 解析JSON数据
+	var obj = make(map[string]any)
+	err := json.Unmarshal([]byte(entity.Script), &obj)
+	if err != nil {
+		return dbbackup.DbBackupJob{}, err
+	}
+
+	// 创建数据库备份任务
+	mjob := dbbackup.DbBackupJob{
+		SchJob:       entity.schJob(),
+		DbType:       utils.GetString(obj, "db_type"),                 // 数据库类型
+		Source:       utils.GetString(obj, "source"),                  // SQLite数据库文件路径
+		Host:         utils.GetString(obj, "host"),                    // 服务器地址
+		Port:         utils.GetString(obj, "port"),                    // 服务端口
+		Username:     utils.GetString(obj, "username"),                // 用户名
+		Password:     utils.GetString(obj, "password"),                // 密码，可引用密钥
+		Database:     utils.GetString(obj, "database"),                // 数据库名称
+		Options:      strings.Fields(utils.GetString(obj, "options")), // 导出工具的附加参数
+		Target:       utils.GetString(obj, "target"),                  // 保存目录
+		TargetNas:    utils.GetString(obj, "target_nas_id"),           // 外部存储标识
+		Prefix:       utils.GetString(obj, "prefix"),                  // 文件名前缀
+		KeepNum:      utils.GetInt(obj, "keep_num", 7),                // 保留的备份数量
+		KeepDays:     utils.GetInt(obj, "keep_days", 0),               // 保留的天数
+		ProjectDirID: entity.ProjectDirID,                             // 所属项目目录，限定可访问的密钥
+	}
+	if utils.GetBool(obj, "use_system", false) {
+		mjob.DbType = dbbackup.TypeSqlite
+		mjob.Source = data.DbPath
+		if config.CONF.Db.Type == dbbackup.TypeMysql || config.CONF.Db.Type == dbbackup.TypePostgres {
+			mjob.DbType = config.CONF.Db.Type
+			mjob.Host = config.CONF.Db.Host
+			mjob.Port = config.CONF.Db.Port
+			mjob.Username = config.CONF.Db.Username
+			mjob.Password = config.CONF.Db.Password
+			mjob.Database = config.CONF.Db.DbName
+		}
+	}
+
+	// 校验配置
+	switch mjob.DbType {
+	case dbbackup.TypeSqlite:
+		if mjob.Source == "" {
+			return mjob, errors.New("SQLite数据库文件路径不能为空")
+		}
+	case dbbackup.TypeMysql, dbbackup.TypePostgres:
+		if mjob.Database == "" {
+			return mjob, errors.New("数据库名称不能为空")
+		}
+	default:
+		return mjob, fmt.Errorf("不支持的数据库类型: %s", mjob.DbType)
+	}
+	if mjob.Target == "" && mjob.TargetNas == "" {
+		return mjob, errors.New("备份保存目录不能为空")
+	}
+	if strings.ContainsAny(mjob.Prefix, `/\`) {
+		return mjob, errors.New("备份文件名前缀不能包含路径分隔符")
+	}
+	return mjob, nil
+}
+
+// checkDbBackup 校验数据库备份任务的配置，其他类型的任务不校验
+func (entity SchTask) checkDbBackup() error {
+	if entity.Type != "DB_BACKUP" {
+		return nil
+	}
+	_, err := entity.toDbBackupJob()
+	return err
+}
+
+// ListBackups 列出数据库备份任务保存的备份文件，按时间倒序
+func (entity SchTask) ListBackups(id any) ([]dbbackup.Backup, error) {
+	mjob, err := loadDbBackupJob(id)
+	if err != nil {
+		return nil, err
+	}
+	return mjob.List()
+}
+
+// RestoreBackup 将数据库备份任务的指定备份恢复到SQLite数据库
+// 恢复期间持有任务执行锁，任务正在执行时不能恢复
+func (entity SchTask) RestoreBackup(id any, name string) (dbbackup.RestoreResult, error) {
+	mjob, err := loadDbBackupJob(id)
+	if err != nil {
+		return dbbackup.RestoreResult{}, err
+	}
+	for _, running := range log.ListRunning() {
+		if running.TaskId == mjob.TaskId {
+			return dbbackup.RestoreResult{}, errors.New("任务正在执行，请等待备份结束后再恢复")
+		}
+	}
+	lease, err := cluster.AcquireRun(mjob.TaskId)
+	if err != nil {
+		return dbbackup.RestoreResult{}, err
+	}
+	defer lease.Release()
+	return mjob.Restore(name)
+}
+
+// loadDbBackupJob 加载数据库备份任务
+func loadDbBackupJob(id any) (dbbackup.DbBackupJob, error) {
+	task, err := SchTask{}.Load(id)
+	if err != nil {
+		return dbbackup.DbBackupJob{}, err
+	}
+	if task.ID == 0 {
+		return dbbackup.DbBackupJob{}, errors.New("任务不存在")
+	}
+	if task.Type != "DB_BACKUP" {
+		return dbbackup.DbBackupJob{}, errors.New("不是数据库备份任务")
+	}
+	return task.toDbBackupJob()
+}
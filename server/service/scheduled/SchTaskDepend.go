@@ -0,0 +1,322 @@
+package scheduled
+
+// SchTaskDepend.go
+// 该文件实现了计划任务之间的依赖链
+// 任务可以声明上游任务，上游任务都执行结束后自动触发执行，保存时检测循环依赖
+
+import (
+	"errors"
+	"fmt"
+	"server/service/scheduled/cluster"
+	"server/service/scheduled/job"
+	"server/service/scheduled/log"
+	"server/utils/global"
+	"server/utils/logger"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// 依赖条件
+const (
+	DependSuccess = "success" // 上游任务都执行成功后触发
+	DependAny     = "any"     // 上游任务都执行结束后触发，不论成功或失败
+)
+
+var (
+	dependLock    sync.Mutex                 // 保证同一时间只有一个上游任务在判断下游是否触发
+	lastTriggered = make(map[uint]time.Time) // 下游任务最近一次被触发的时间，避免日志写入前被重复触发
+)
+
+// DependNode 依赖图中的任务节点
+type DependNode struct {
+	ID         uint   `json:"id"`          // 任务ID
+	Name       string `json:"name"`        // 任务名称
+	Type       string `json:"type"`        // 任务类型
+	Cron       string `json:"cron"`        // Cron表达式
+	IsDisable  uint   `json:"is_disable"`  // 是否禁用
+	LastStatus int    `json:"last_status"` // 最近一次执行状态：0执行中或未执行 1完成 -1失败 -2异常终止
+}
+
+// DependEdge 依赖图中的连线，从上游任务指向下游任务
+type DependEdge struct {
+	Source uint   `json:"source"` // 上游任务ID
+	Target uint   `json:"target"` // 下游任务ID
+	Mode   string `json:"mode"`   // 依赖条件
+}
+
+// DependGraph 计划任务的依赖图
+type DependGraph struct {
+	Nodes []DependNode `json:"nodes"` // 任务节点
+	Edges []DependEdge `json:"edges"` // 依赖连线
+}
+
+// parseUpstream 解析逗号分隔的上游任务ID
+func parseUpstream(upstream string) ([]uint, error) {
+	ids := make([]uint, 0)
+	for _, item := range strings.Split(upstream, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		id, err := strconv.ParseUint(item, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("上游任务ID格式错误: %s", item)
+		}
+		ids = append(ids, uint(id))
+	}
+	return ids, nil
+}
+
+// upstreamIDs 获取任务的上游任务ID，格式错误的ID忽略
+func (entity SchTask) upstreamIDs() []uint {
+	ids, _ := parseUpstream(entity.Upstream)
+	return ids
+}
+
+// dependMode 获取任务的依赖条件，为空时为success
+func (entity SchTask) dependMode() string {
+	if entity.DependMode == "" {
+		return DependSuccess
+	}
+	return entity.DependMode
+}
+
+// checkUpstream 校验并规范化上游任务
+// 上游任务ID去重排序，上游任务必须存在，不能依赖自身，不能形成循环依赖
+func (entity *SchTask) checkUpstream() error {
+	switch entity.DependMode {
+	case "", DependSuccess, DependAny:
+	default:
+		return fmt.Errorf("不支持的依赖条件: %s", entity.DependMode)
+	}
+	ids, err := parseUpstream(entity.Upstream)
+	if err != nil {
+		return err
+	}
+	if len(ids) == 0 {
+		entity.Upstream = ""
+		return nil
+	}
+
+	// 去重排序
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	unique := make([]string, 0, len(ids))
+	for i, id := range ids {
+		if i > 0 && id == ids[i-1] {
+			continue
+		}
+		if entity.ID != 0 && id == entity.ID {
+			return errors.New("任务不能依赖自身")
+		}
+		unique = append(unique, fmt.Sprint(id))
+	}
+
+	// 加载所有任务的依赖关系，当前任务使用新的上游任务
+	var list []SchTask
+	if err := global.DB.Model(SchTask{}).Select("id", "name", "upstream").Find(&list).Error; err != nil {
+		return err
+	}
+	graph := make(map[uint][]uint, len(list))
+	names := make(map[uint]string, len(list))
+	for _, item := range list {
+		graph[item.ID] = item.upstreamIDs()
+		names[item.ID] = item.Name
+	}
+	for _, id := range ids {
+		if _, ok := graph[id]; !ok {
+			return fmt.Errorf("上游任务 %d 不存在", id)
+		}
+	}
+	// 新建的任务还没有ID，不会被其他任务依赖，不会形成循环
+	if entity.ID == 0 {
+		entity.Upstream = strings.Join(unique, ",")
+		return nil
+	}
+	graph[entity.ID] = ids
+
+	// 从当前任务沿上游方向深度优先搜索，回到当前任务即为循环依赖
+	// 搜索返回时从最上游的任务开始记录路径，反转后按依赖方向显示
+	visited := make(map[uint]bool)
+	var path []uint
+	var walk func(id uint) bool
+	walk = func(id uint) bool {
+		for _, up := range graph[id] {
+			if up == entity.ID {
+				path = append(path, id)
+				return true
+			}
+			if visited[up] {
+				continue
+			}
+			visited[up] = true
+			if walk(up) {
+				path = append(path, id)
+				return true
+			}
+		}
+		return false
+	}
+	if walk(entity.ID) {
+		// 依次为当前任务、其上游任务、上游任务的上游任务……，最后回到当前任务
+		chain := make([]string, 0, len(path)+1)
+		for i := len(path) - 1; i >= 0; i-- {
+			chain = append(chain, names[path[i]])
+		}
+		chain = append(chain, names[entity.ID])
+		return fmt.Errorf("存在循环依赖: %s", strings.Join(chain, " -> "))
+	}
+	entity.Upstream = strings.Join(unique, ",")
+	return nil
+}
+
+// triggerDownstream 任务执行结束后触发依赖该任务的下游任务
+// 下游任务的所有上游任务在其上次执行之后都已执行结束（success模式要求都执行成功）时才触发
+func triggerDownstream(finished job.SchJob, entity log.SchLog) {
+	dependLock.Lock()
+	defer dependLock.Unlock()
+
+	var list []SchTask
+	err := global.DB.Model(SchTask{}).Where("is_disable=0 and upstream IS NOT NULL and upstream <> ''").Find(&list).Error
+	if err != nil {
+		logger.LOG.Errorf("获取下游任务失败:%s", err.Error())
+		return
+	}
+	for _, item := range list {
+		upstream := item.upstreamIDs()
+		if !containsID(upstream, finished.TaskId) {
+			continue
+		}
+		ready, err := item.upstreamReady(upstream)
+		if err != nil {
+			logger.LOG.Errorf("检查任务[%s]的上游任务失败:%s", item.Name, err.Error())
+			continue
+		}
+		if !ready {
+			continue
+		}
+
+		item.trigger = &job.Trigger{TaskId: finished.TaskId, LogId: entity.ID}
+		mjob, err := item.toJob()
+		if err != nil {
+			logger.LOG.Errorf("触发任务[%s]失败:%s", item.Name, err.Error())
+			continue
+		}
+		lease, err := cluster.AcquireRun(item.ID)
+		if err != nil {
+			logger.LOG.Warnf("触发任务[%s]失败:%s", item.Name, err.Error())
+			continue
+		}
+		lastTriggered[item.ID] = time.Now()
+		markRun(item.ID)
+		logger.LOG.Infof("上游任务[%s]执行结束，触发下游任务[%s]", finished.TaskName, item.Name)
+		go func() {
+			defer lease.Release()
+			mjob.Job.Run()
+		}()
+	}
+}
+
+// upstreamReady 判断上游任务是否都已在本任务上次执行之后执行结束
+// 已删除的上游任务不参与判断
+func (entity SchTask) upstreamReady(upstream []uint) (bool, error) {
+	last, err := log.SchLog{}.Latest(entity.ID)
+	if err != nil {
+		return false, err
+	}
+	baseline := last.StartTime.Time
+	if t, ok := lastTriggered[entity.ID]; ok && t.After(baseline) {
+		baseline = t
+	}
+
+	var tasks []SchTask
+	if err := global.DB.Model(SchTask{}).Select("id").Where("id IN ?", upstream).Find(&tasks).Error; err != nil {
+		return false, err
+	}
+	ids := make([]uint, 0, len(tasks))
+	for _, task := range tasks {
+		ids = append(ids, task.ID)
+	}
+	latest, err := log.SchLog{}.LatestOfTasks(ids)
+	if err != nil {
+		return false, err
+	}
+	for _, id := range ids {
+		upLog := latest[id]
+		// 执行中或失败后等待重试时，上游任务还没有执行结束
+		if upLog.ID == 0 || upLog.Status == 0 || upLog.Status == -3 || upLog.EndTime.Before(baseline) {
+			return false, nil
+		}
+		if entity.dependMode() == DependSuccess && upLog.Status != 1 {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// Graph 获取计划任务的依赖图，只包含有依赖关系的任务
+func (entity SchTask) Graph() (DependGraph, error) {
+	var list []SchTask
+	if err := global.DB.Model(SchTask{}).Find(&list).Error; err != nil {
+		return DependGraph{}, err
+	}
+	exists := make(map[uint]bool, len(list))
+	for _, item := range list {
+		exists[item.ID] = true
+	}
+
+	graph := DependGraph{Nodes: make([]DependNode, 0), Edges: make([]DependEdge, 0)}
+	linked := make(map[uint]bool)
+	for _, item := range list {
+		for _, up := range item.upstreamIDs() {
+			if !exists[up] {
+				continue
+			}
+			graph.Edges = append(graph.Edges, DependEdge{Source: up, Target: item.ID, Mode: item.dependMode()})
+			linked[up] = true
+			linked[item.ID] = true
+		}
+	}
+	// 一次查询所有有依赖关系的任务最近一次的执行状态
+	ids := make([]uint, 0, len(linked))
+	for id := range linked {
+		ids = append(ids, id)
+	}
+	latest, err := log.SchLog{}.LatestOfTasks(ids)
+	if err != nil {
+		return DependGraph{}, err
+	}
+	for _, item := range list {
+		if !linked[item.ID] {
+			continue
+		}
+		graph.Nodes = append(graph.Nodes, DependNode{
+			ID:         item.ID,
+			Name:       item.Name,
+			Type:       item.Type,
+			Cron:       item.Cron,
+			IsDisable:  item.IsDisable,
+			LastStatus: latest[item.ID].Status,
+		})
+	}
+	return graph, nil
+}
+
+// forgetTriggered 删除任务时移除记录的触发时间
+func forgetTriggered(taskID uint) {
+	dependLock.Lock()
+	defer dependLock.Unlock()
+	delete(lastTriggered, taskID)
+}
+
+// containsID 判断ID列表中是否包含指定ID
+func containsID(ids []uint, id uint) bool {
+	for _, item := range ids {
+		if item == id {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,256 @@
+package scheduled
+
+import (
+	"fmt"
+	"server/core/db"
+	"server/service/scheduled/log"
+	"server/utils/global"
+	"testing"
+	"time"
+
+	"github.com/glebarez/sqlite"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+	gormlogger "gorm.io/gorm/logger"
+)
+
+// setupDependDB 为每个测试创建独立的内存数据库，测试结束后恢复全局数据库连接
+func setupDependDB(t *testing.T) {
+	t.Helper()
+	dsn := fmt.Sprintf("file:depend_%d?mode=memory&cache=shared", time.Now().UnixNano())
+	conn, err := gorm.Open(sqlite.Open(dsn), &gorm.Config{Logger: gormlogger.Discard})
+	require.NoError(t, err)
+	require.NoError(t, conn.AutoMigrate(&SchTask{}, &log.SchLog{}))
+	old := global.DB
+	global.DB = conn
+	t.Cleanup(func() {
+		global.DB = old
+		if sqlDB, err := conn.DB(); err == nil {
+			sqlDB.Close()
+		}
+	})
+}
+
+// createDependTask 创建指定ID和上游任务的计划任务
+func createDependTask(t *testing.T, id uint, name, upstream string) {
+	t.Helper()
+	task := SchTask{Name: name, Upstream: upstream}
+	task.ID = id
+	require.NoError(t, global.DB.Create(&task).Error)
+}
+
+// createDependLog 创建任务的执行日志，开始和结束时间为相对当前的偏移
+func createDependLog(t *testing.T, taskID uint, status int, start, end time.Duration) {
+	t.Helper()
+	now := time.Now()
+	entity := log.SchLog{
+		TaskId:    taskID,
+		Status:    status,
+		StartTime: db.LocalTime{Time: now.Add(start)},
+		EndTime:   db.LocalTime{Time: now.Add(end)},
+	}
+	require.NoError(t, global.DB.Create(&entity).Error)
+}
+
+// TestCheckUpstream 测试上游任务的校验、规范化和循环依赖检测
+func TestCheckUpstream(t *testing.T) {
+	tests := []struct {
+		name         string
+		id           uint
+		upstream     string
+		mode         string
+		wantUpstream string
+		wantErr      string
+	}{
+		{name: "没有上游任务", id: 1, upstream: " , ", wantUpstream: ""},
+		{name: "去重排序", id: 0, upstream: "3, 2,2", wantUpstream: "2,3"},
+		{name: "不形成循环", id: 3, upstream: "1", mode: DependAny, wantUpstream: "1"},
+		{name: "依赖自身", id: 1, upstream: "2,1", wantErr: "任务不能依赖自身"},
+		{name: "上游任务不存在", id: 1, upstream: "99", wantErr: "上游任务 99 不存在"},
+		{name: "ID格式错误", id: 1, upstream: "a", wantErr: "上游任务ID格式错误: a"},
+		{name: "不支持的依赖条件", id: 1, upstream: "2", mode: "all", wantErr: "不支持的依赖条件: all"},
+		{name: "直接循环", id: 1, upstream: "2", wantErr: "存在循环依赖: A -> B -> A"},
+		{name: "间接循环按上游顺序显示", id: 1, upstream: "3", wantErr: "存在循环依赖: A -> C -> B -> A"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setupDependDB(t)
+			// C依赖B，B依赖A
+			createDependTask(t, 1, "A", "")
+			createDependTask(t, 2, "B", "1")
+			createDependTask(t, 3, "C", "2")
+
+			task := SchTask{Upstream: tt.upstream, DependMode: tt.mode}
+			task.ID = tt.id
+			err := task.checkUpstream()
+			if tt.wantErr != "" {
+				assert.EqualError(t, err, tt.wantErr)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.wantUpstream, task.Upstream)
+		})
+	}
+}
+
+// TestUpstreamReady 测试上游任务是否都已在下游任务上次执行之后执行结束
+func TestUpstreamReady(t *testing.T) {
+	type upLog struct {
+		taskID uint
+		status int
+		start  time.Duration
+		end    time.Duration
+	}
+	tests := []struct {
+		name      string
+		mode      string
+		logs      []upLog       // 上游任务的执行日志
+		lastRun   time.Duration // 下游任务上次执行的开始时间，为0时没有执行过
+		triggered time.Duration // 下游任务上次被触发的时间，为0时没有被触发过
+		want      bool
+	}{
+		{
+			name: "上游任务都执行成功",
+			logs: []upLog{{1, 1, -3 * time.Minute, -2 * time.Minute}, {2, 1, -3 * time.Minute, -time.Minute}},
+			want: true,
+		},
+		{
+			name: "上游任务没有执行过",
+			logs: []upLog{{1, 1, -3 * time.Minute, -2 * time.Minute}},
+			want: false,
+		},
+		{
+			name: "上游任务执行中",
+			logs: []upLog{{1, 1, -3 * time.Minute, -2 * time.Minute}, {2, 0, -time.Minute, 0}},
+			want: false,
+		},
+		{
+			name: "success模式上游任务失败",
+			logs: []upLog{{1, 1, -3 * time.Minute, -2 * time.Minute}, {2, -1, -3 * time.Minute, -time.Minute}},
+			want: false,
+		},
+		{
+			name: "any模式上游任务失败",
+			mode: DependAny,
+			logs: []upLog{{1, 1, -3 * time.Minute, -2 * time.Minute}, {2, -1, -3 * time.Minute, -time.Minute}},
+			want: true,
+		},
+		{
+			name: "any模式上游任务异常终止",
+			mode: DependAny,
+			logs: []upLog{{1, -2, -3 * time.Minute, -2 * time.Minute}, {2, 1, -3 * time.Minute, -time.Minute}},
+			want: true,
+		},
+		{
+			name: "success模式上游任务等待重试",
+			logs: []upLog{{1, 1, -3 * time.Minute, -2 * time.Minute}, {2, -3, -3 * time.Minute, -time.Minute}},
+			want: false,
+		},
+		{
+			name: "any模式上游任务等待重试",
+			mode: DependAny,
+			logs: []upLog{{1, 1, -3 * time.Minute, -2 * time.Minute}, {2, -3, -3 * time.Minute, -time.Minute}},
+			want: false,
+		},
+		{
+			name: "重试成功后就绪",
+			logs: []upLog{
+				{1, 1, -3 * time.Minute, -2 * time.Minute},
+				{2, -1, -3 * time.Minute, -150 * time.Second},
+				{2, 1, -2 * time.Minute, -time.Minute},
+			},
+			want: true,
+		},
+		{
+			name:    "上游任务在下游任务上次执行之前结束",
+			logs:    []upLog{{1, 1, -10 * time.Minute, -9 * time.Minute}, {2, 1, -3 * time.Minute, -time.Minute}},
+			lastRun: -5 * time.Minute,
+			want:    false,
+		},
+		{
+			name:    "上游任务都在下游任务上次执行之后结束",
+			logs:    []upLog{{1, 1, -4 * time.Minute, -3 * time.Minute}, {2, 1, -3 * time.Minute, -time.Minute}},
+			lastRun: -5 * time.Minute,
+			want:    true,
+		},
+		{
+			name:      "上游任务在下游任务上次被触发之前结束",
+			logs:      []upLog{{1, 1, -4 * time.Minute, -3 * time.Minute}, {2, 1, -3 * time.Minute, -time.Minute}},
+			lastRun:   -5 * time.Minute,
+			triggered: -2 * time.Minute,
+			want:      false,
+		},
+		{
+			name:      "早于上次执行的触发时间不作为基准",
+			logs:      []upLog{{1, 1, -4 * time.Minute, -3 * time.Minute}, {2, 1, -3 * time.Minute, -time.Minute}},
+			lastRun:   -5 * time.Minute,
+			triggered: -10 * time.Minute,
+			want:      true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setupDependDB(t)
+			createDependTask(t, 1, "A", "")
+			createDependTask(t, 2, "B", "")
+			createDependTask(t, 4, "D", "1,2,3")
+			for _, item := range tt.logs {
+				createDependLog(t, item.taskID, item.status, item.start, item.end)
+			}
+			if tt.lastRun != 0 {
+				createDependLog(t, 4, 1, tt.lastRun, tt.lastRun+time.Second)
+			}
+			delete(lastTriggered, 4)
+			if tt.triggered != 0 {
+				lastTriggered[4] = time.Now().Add(tt.triggered)
+			}
+			t.Cleanup(func() { delete(lastTriggered, 4) })
+
+			// 已删除的上游任务3不参与判断
+			task := SchTask{Upstream: "1,2,3", DependMode: tt.mode}
+			task.ID = 4
+			ready, err := task.upstreamReady(task.upstreamIDs())
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, ready)
+		})
+	}
+}
+
+// TestGraph 测试依赖图只包含有依赖关系的任务，并带有最近一次的执行状态
+func TestGraph(t *testing.T) {
+	setupDependDB(t)
+	createDependTask(t, 1, "A", "")
+	createDependTask(t, 2, "B", "1")
+	createDependTask(t, 3, "C", "")
+	createDependTask(t, 4, "D", "2,5")
+	createDependLog(t, 1, -1, -5*time.Minute, -4*time.Minute)
+	createDependLog(t, 1, 1, -3*time.Minute, -2*time.Minute)
+	createDependLog(t, 2, -1, -time.Minute, 0)
+	createDependLog(t, 3, 1, -time.Minute, 0)
+
+	graph, err := SchTask{}.Graph()
+	require.NoError(t, err)
+	status := make(map[uint]int)
+	for _, node := range graph.Nodes {
+		status[node.ID] = node.LastStatus
+	}
+	// 不存在的上游任务5不生成连线，没有依赖关系的任务3不在图中
+	assert.Equal(t, map[uint]int{1: 1, 2: -1, 4: 0}, status)
+	assert.Equal(t, []DependEdge{
+		{Source: 1, Target: 2, Mode: DependSuccess},
+		{Source: 2, Target: 4, Mode: DependSuccess},
+	}, graph.Edges)
+}
+
+// TestDeleteForgetsTriggered 测试删除任务时移除记录的触发时间
+func TestDeleteForgetsTriggered(t *testing.T) {
+	setupDependDB(t)
+	createDependTask(t, 1, "A", "")
+	lastTriggered[1] = time.Now()
+	t.Cleanup(func() { delete(lastTriggered, 1) })
+
+	require.NoError(t, SchTask{}.Delete("1"))
+	_, ok := lastTriggered[1]
+	assert.False(t, ok)
+}
@@ -0,0 +1,147 @@
+package scheduled
+
+// SchTaskMisfire.go
+// 该文件实现了服务停止期间错过调度的补执行
+// 启动时对比任务最近一次执行成功的时间和调度表达式，按任务的策略补执行错过的调度
+
+import (
+	"fmt"
+	"server/service/scheduled/cluster"
+	"server/service/scheduled/job"
+	"server/service/scheduled/log"
+	"server/utils/cron"
+	"server/utils/logger"
+	"time"
+)
+
+// 错过调度的处理策略
+const (
+	MisfireIgnore = "ignore" // 忽略错过的调度
+	MisfireOnce   = "once"   // 只补执行一次
+	MisfireAll    = "all"    // 逐个补执行错过的调度，不超过最多补执行次数
+)
+
+const (
+	defaultMisfireMax = 10    // all策略默认最多补执行的次数
+	misfireScanLimit  = 10000 // 统计错过的调度时最多计算的次数，避免秒级调度停机较久时计算过多
+
+	missedRunWait     = 30 * time.Minute // 补执行时任务正在执行，最多等待其结束的时间
+	missedRunInterval = 10 * time.Second // 等待任务执行结束时重试获取执行锁的间隔
+)
+
+// misfirePolicy 获取任务错过调度的处理策略，为空时为ignore
+func (entity SchTask) misfirePolicy() string {
+	if entity.MisfirePolicy == "" {
+		return MisfireIgnore
+	}
+	return entity.MisfirePolicy
+}
+
+// checkMisfire 校验错过调度的处理策略
+func (entity SchTask) checkMisfire() error {
+	switch entity.MisfirePolicy {
+	case "", MisfireIgnore, MisfireOnce, MisfireAll:
+		return nil
+	}
+	return fmt.Errorf("不支持的错过调度策略: %s", entity.MisfirePolicy)
+}
+
+// missedTimes 计算任务最近一次执行成功之后到now之间错过的调度时间，按时间先后排列
+// 没有执行成功过时从任务创建时间开始计算，返回的数量不超过misfireScanLimit
+func (entity SchTask) missedTimes(now time.Time) ([]time.Time, error) {
+	schedule, err := cron.Parse(entity.Cron, entity.Timezone)
+	if err != nil {
+		return nil, err
+	}
+	latest, err := log.SchLog{}.LatestSuccess(entity.ID)
+	if err != nil {
+		return nil, err
+	}
+	baseline := entity.CreatedAt.Time
+	if latest.ID != 0 {
+		baseline = latest.StartTime.Time
+	}
+	if baseline.IsZero() {
+		return nil, nil
+	}
+	if entity.Timezone != "" {
+		loc, _ := time.LoadLocation(entity.Timezone)
+		baseline = baseline.In(loc)
+	}
+	missed := make([]time.Time, 0)
+	for next := schedule.Next(baseline); !next.IsZero() && !next.After(now); next = schedule.Next(next) {
+		missed = append(missed, next)
+		if len(missed) >= misfireScanLimit {
+			break
+		}
+	}
+	return missed, nil
+}
+
+// catchUp 按任务的策略补执行服务停止期间错过的调度
+// 每个任务在单独的goroutine中依次补执行，不阻塞启动
+func catchUp(list []SchTask, now time.Time) {
+	for _, item := range list {
+		policy := item.misfirePolicy()
+		missed, err := item.missedTimes(now)
+		if err != nil {
+			logger.LOG.Errorf("计算任务[%s]错过的调度失败:%s", item.Name, err.Error())
+			continue
+		}
+		if len(missed) == 0 {
+			continue
+		}
+		switch policy {
+		case MisfireIgnore:
+			logger.LOG.Infof("任务[%s]错过了%d次调度，最早为%s，按策略忽略", item.Name, len(missed), missed[0].Format(time.RFC3339))
+			continue
+		case MisfireOnce:
+			// 只补执行最近一次错过的调度
+			missed = missed[len(missed)-1:]
+		case MisfireAll:
+			limit := int(item.MisfireMax)
+			if limit == 0 {
+				limit = defaultMisfireMax
+			}
+			if len(missed) > limit {
+				logger.LOG.Warnf("任务[%s]错过了%d次调度，超过最多补执行次数，只补执行最近的%d次", item.Name, len(missed), limit)
+				missed = missed[len(missed)-limit:]
+			}
+		}
+		go item.runMissed(missed)
+	}
+}
+
+// runMissed 依次补执行错过的调度，补执行的日志记录原计划执行时间
+func (entity SchTask) runMissed(missed []time.Time) {
+	for i, scheduled := range missed {
+		entity.misfire = &job.Misfire{ScheduledTime: scheduled, Index: i + 1, Total: len(missed)}
+		mjob, err := entity.toJob()
+		if err != nil {
+			logger.LOG.Errorf("补执行任务[%s]失败:%s", entity.Name, err.Error())
+			return
+		}
+		// 多个节点同时启动时，同一次错过的调度只补执行一次
+		ok, err := cluster.TryFire(entity.ID, scheduled)
+		if err != nil {
+			logger.LOG.Errorf("获取任务[%s]的调度锁失败:%s", entity.Name, err.Error())
+			continue
+		}
+		if !ok {
+			continue
+		}
+		// 任务正在执行时等待其结束；等待超时则放弃调度锁，使其他节点可以补执行
+		lease, err := cluster.WaitRun(entity.ID, missedRunWait, missedRunInterval)
+		if err != nil {
+			logger.LOG.Warnf("补执行任务[%s]失败:%s", entity.Name, err.Error())
+			if err := cluster.ReleaseFire(entity.ID, scheduled); err != nil {
+				logger.LOG.Warnf("释放任务[%s]的调度锁失败:%s", entity.Name, err.Error())
+			}
+			continue
+		}
+		logger.LOG.Infof("补执行任务[%s]错过的调度 %d/%d，原计划执行时间:%s", entity.Name, i+1, len(missed), scheduled.Format(time.RFC3339))
+		markRun(entity.ID)
+		mjob.Job.Run()
+		lease.Release()
+	}
+}
@@ -0,0 +1,189 @@
+// Package cluster 多个实例共用同一个数据库时的计划任务协调
+// 使用MySQL/PostgreSQL或启用Redis时自动启用，只使用本地SQLite时不需要协调
+// 每次调度和每次执行都需要先获取锁：启用Redis时使用Redis，否则使用数据库中的锁记录
+// 同一次调度只会在一个节点上执行，执行期间定期续期，节点退出后锁过期由其他节点接管
+package cluster
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"server/utils/cache"
+	"server/utils/config"
+	"server/utils/logger"
+	"sync"
+	"time"
+)
+
+const (
+	RunLockTTL    = 60 * time.Second // 执行锁有效期，执行期间定期续期
+	FireLockTTL   = time.Hour        // 调度锁有效期，同一次调度在有效期内只会执行一次
+	renewInterval = RunLockTTL / 3   // 执行锁续期间隔
+)
+
+// ErrLocked 任务正在其他节点或本节点上执行
+var ErrLocked = errors.New("任务正在执行")
+
+// Locker 带有效期的锁，锁的持有者为当前节点
+type Locker interface {
+	// TryLock 尝试获取锁，锁已被其他节点持有且未过期时返回false
+	TryLock(key string, ttl time.Duration) (bool, error)
+	// Renew 续期当前节点持有的锁，锁已丢失时返回false
+	Renew(key string, ttl time.Duration) (bool, error)
+	// Unlock 释放当前节点持有的锁
+	Unlock(key string) error
+	// Owner 获取锁的持有者，锁不存在或已过期时为空
+	Owner(key string) (string, error)
+}
+
+// Info 集群调度状态
+type Info struct {
+	Enable  bool   `json:"enable"`  // 是否启用集群调度
+	Node    string `json:"node"`    // 当前节点标识
+	Backend string `json:"backend"` // 锁的实现：redis或database
+}
+
+var (
+	nodeOnce sync.Once
+	nodeID   string
+)
+
+// Enabled 是否启用集群调度，未启用时不获取锁
+// 多个实例可能共用的数据库或Redis已配置时自动启用，也可通过配置强制启用
+func Enabled() bool {
+	if config.CONF.Cluster.Enable || cache.SupportRedis() {
+		return true
+	}
+	return config.CONF.Db.Type == "mysql" || config.CONF.Db.Type == "postgres"
+}
+
+// NodeID 当前节点标识，未配置时使用主机名
+// 同一主机运行多个实例时需要分别配置，节点重启后标识不变才能正确修复其异常终止的日志
+func NodeID() string {
+	nodeOnce.Do(func() {
+		nodeID = config.CONF.Cluster.NodeId
+		if nodeID == "" {
+			nodeID, _ = os.Hostname()
+		}
+		if nodeID == "" {
+			nodeID = fmt.Sprintf("node-%d", os.Getpid())
+		}
+	})
+	return nodeID
+}
+
+// GetInfo 获取集群调度状态
+func GetInfo() Info {
+	info := Info{Enable: Enabled(), Node: NodeID(), Backend: "database"}
+	if cache.SupportRedis() {
+		info.Backend = "redis"
+	}
+	return info
+}
+
+// locker 启用Redis时使用Redis锁，否则使用数据库锁
+func locker() Locker {
+	if cache.SupportRedis() {
+		return redisLocker{}
+	}
+	return dbLocker{}
+}
+
+// fireKey 一次调度的锁标识
+func fireKey(taskID uint, scheduled time.Time) string {
+	return fmt.Sprintf("sch:fire:%d:%d", taskID, scheduled.Unix())
+}
+
+// runKey 任务执行锁标识
+func runKey(taskID uint) string {
+	return fmt.Sprintf("sch:run:%d", taskID)
+}
+
+// TryFire 获取任务一次调度的执行权，同一计划时间只有一个节点能获取成功
+// 未启用集群调度时总是返回true
+func TryFire(taskID uint, scheduled time.Time) (bool, error) {
+	if !Enabled() {
+		return true, nil
+	}
+	return locker().TryLock(fireKey(taskID, scheduled), FireLockTTL)
+}
+
+// ReleaseFire 放弃已获取的一次调度的执行权，使其他节点可以执行该调度
+func ReleaseFire(taskID uint, scheduled time.Time) error {
+	if !Enabled() {
+		return nil
+	}
+	return locker().Unlock(fireKey(taskID, scheduled))
+}
+
+// Lease 持有的任务执行锁，执行结束后调用Release释放
+type Lease struct {
+	key  string
+	stop chan struct{}
+	once sync.Once
+}
+
+// AcquireRun 获取任务执行锁，任务正在执行时返回包装了ErrLocked的错误
+// 获取成功后定期续期直到释放；未启用集群调度时返回不持有锁的Lease
+func AcquireRun(taskID uint) (*Lease, error) {
+	if !Enabled() {
+		return &Lease{}, nil
+	}
+	key := runKey(taskID)
+	l := locker()
+	ok, err := l.TryLock(key, RunLockTTL)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		owner, _ := l.Owner(key)
+		return nil, fmt.Errorf("%w: 任务 %d 正在节点 %s 上执行", ErrLocked, taskID, owner)
+	}
+	lease := &Lease{key: key, stop: make(chan struct{})}
+	go lease.renew(l)
+	return lease, nil
+}
+
+// WaitRun 获取任务执行锁，任务正在执行时每隔interval重试，最多等待wait
+// 超过等待时间仍未获取时返回包装了ErrLocked的错误
+func WaitRun(taskID uint, wait, interval time.Duration) (*Lease, error) {
+	deadline := time.Now().Add(wait)
+	for {
+		lease, err := AcquireRun(taskID)
+		if err == nil || !errors.Is(err, ErrLocked) || time.Now().Add(interval).After(deadline) {
+			return lease, err
+		}
+		time.Sleep(interval)
+	}
+}
+
+// renew 定期续期执行锁
+func (lease *Lease) renew(l Locker) {
+	ticker := time.NewTicker(renewInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			if ok, err := l.Renew(lease.key, RunLockTTL); err != nil {
+				logger.LOG.Warnf("续期执行锁 %s 失败: %v", lease.key, err)
+			} else if !ok {
+				logger.LOG.Warnf("执行锁 %s 已丢失，可能被其他节点接管", lease.key)
+			}
+		case <-lease.stop:
+			return
+		}
+	}
+}
+
+// Release 释放执行锁
+func (lease *Lease) Release() {
+	if lease == nil || lease.key == "" {
+		return
+	}
+	lease.once.Do(func() {
+		close(lease.stop)
+		if err := locker().Unlock(lease.key); err != nil {
+			logger.LOG.Warnf("释放执行锁 %s 失败: %v", lease.key, err)
+		}
+	})
+}
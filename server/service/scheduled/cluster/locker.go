@@ -0,0 +1,129 @@
+package cluster
+
+import (
+	"context"
+	"errors"
+	"server/utils/cache"
+	"server/utils/global"
+	"sync"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"gorm.io/gorm/clause"
+)
+
+// SchLock 数据库中的锁记录
+type SchLock struct {
+	LockKey   string    `gorm:"primaryKey;size:191;comment:'锁标识'" json:"lock_key"` // 锁标识
+	Owner     string    `gorm:"size:128;comment:'持有节点'" json:"owner"`              // 持有锁的节点
+	ExpiresAt time.Time `gorm:"index;comment:'过期时间'" json:"expires_at"`            // 过期时间
+}
+
+// TableName 返回数据库表名
+func (SchLock) TableName() string {
+	return "sch_task_lock"
+}
+
+// dbLocker 基于数据库行的锁，通过条件更新和主键冲突保证同一时间只有一个节点持有
+type dbLocker struct{}
+
+// lockCleanInterval 清理过期锁记录的间隔
+const lockCleanInterval = 10 * time.Minute
+
+var (
+	lockCleanMu   sync.Mutex
+	lockCleanLast time.Time
+)
+
+func (dbLocker) TryLock(key string, ttl time.Duration) (bool, error) {
+	cleanExpired()
+	now := time.Now()
+	// 锁已过期时直接接管，当前节点持有的锁也不能重复获取
+	res := global.DB.Model(&SchLock{}).Where("lock_key = ? and expires_at < ?", key, now).
+		Updates(map[string]any{"owner": NodeID(), "expires_at": now.Add(ttl)})
+	if res.Error != nil {
+		return false, res.Error
+	}
+	if res.RowsAffected > 0 {
+		return true, nil
+	}
+	// 锁不存在时插入，多个节点同时插入只有一个成功
+	res = global.DB.Clauses(clause.OnConflict{DoNothing: true}).Create(&SchLock{LockKey: key, Owner: NodeID(), ExpiresAt: now.Add(ttl)})
+	return res.RowsAffected > 0, res.Error
+}
+
+func (dbLocker) Renew(key string, ttl time.Duration) (bool, error) {
+	res := global.DB.Model(&SchLock{}).Where("lock_key = ? and owner = ?", key, NodeID()).Update("expires_at", time.Now().Add(ttl))
+	return res.RowsAffected > 0, res.Error
+}
+
+func (dbLocker) Unlock(key string) error {
+	return global.DB.Where("lock_key = ? and owner = ?", key, NodeID()).Delete(&SchLock{}).Error
+}
+
+func (dbLocker) Owner(key string) (string, error) {
+	var lock SchLock
+	err := global.DB.Model(&SchLock{}).Where("lock_key = ? and expires_at >= ?", key, time.Now()).Limit(1).Find(&lock).Error
+	return lock.Owner, err
+}
+
+// cleanExpired 定期删除过期的锁记录，调度锁不会主动释放，需要清理
+func cleanExpired() {
+	lockCleanMu.Lock()
+	if time.Since(lockCleanLast) < lockCleanInterval {
+		lockCleanMu.Unlock()
+		return
+	}
+	lockCleanLast = time.Now()
+	lockCleanMu.Unlock()
+	global.DB.Where("expires_at < ?", time.Now()).Delete(&SchLock{})
+}
+
+// redisLocker 基于Redis的锁，键的值为持有锁的节点
+type redisLocker struct{}
+
+// redisKeyPrefix Redis中锁的键前缀
+const redisKeyPrefix = "minas:lock:"
+
+// 只有持有者才能续期和释放锁
+const (
+	renewScript  = `if redis.call("get", KEYS[1]) == ARGV[1] then return redis.call("pexpire", KEYS[1], ARGV[2]) else return 0 end`
+	unlockScript = `if redis.call("get", KEYS[1]) == ARGV[1] then return redis.call("del", KEYS[1]) else return 0 end`
+)
+
+func (redisLocker) TryLock(key string, ttl time.Duration) (bool, error) {
+	rdb, err := cache.GetRedisClient()
+	if err != nil {
+		return false, err
+	}
+	return rdb.SetNX(context.Background(), redisKeyPrefix+key, NodeID(), ttl).Result()
+}
+
+func (redisLocker) Renew(key string, ttl time.Duration) (bool, error) {
+	rdb, err := cache.GetRedisClient()
+	if err != nil {
+		return false, err
+	}
+	n, err := rdb.Eval(context.Background(), renewScript, []string{redisKeyPrefix + key}, NodeID(), ttl.Milliseconds()).Int()
+	return n > 0, err
+}
+
+func (redisLocker) Unlock(key string) error {
+	rdb, err := cache.GetRedisClient()
+	if err != nil {
+		return err
+	}
+	return rdb.Eval(context.Background(), unlockScript, []string{redisKeyPrefix + key}, NodeID()).Err()
+}
+
+func (redisLocker) Owner(key string) (string, error) {
+	rdb, err := cache.GetRedisClient()
+	if err != nil {
+		return "", err
+	}
+	owner, err := rdb.Get(context.Background(), redisKeyPrefix+key).Result()
+	if errors.Is(err, redis.Nil) {
+		return "", nil
+	}
+	return owner, err
+}
@@ -0,0 +1,397 @@
+package dbbackup
+
+import (
+	"compress/gzip"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"server/data"
+	"server/service/scheduled/job"
+	"server/service/scheduled/log"
+	"server/service/secret"
+	"server/utils/cmd"
+	"server/utils/logger"
+	"server/utils/rclone"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/glebarez/sqlite"
+	"gorm.io/gorm"
+	gormLogger "gorm.io/gorm/logger"
+)
+
+// 支持的数据库类型
+const (
+	TypeSqlite   = "sqlite"   // SQLite数据库文件
+	TypeMysql    = "mysql"    // MySQL数据库，使用mysqldump导出
+	TypePostgres = "postgres" // PostgreSQL数据库，使用pg_dump导出
+)
+
+// timeLayout 备份文件名中的时间格式
+const timeLayout = "20060102_150405"
+
+// DbBackupJob 数据库备份任务结构体
+// 导出数据库的一致性快照并压缩为带时间戳的文件，保存到本地目录或外部存储，按数量和天数清理旧备份
+// 通过嵌入SchJob获得计划任务的基本属性和行为
+type DbBackupJob struct {
+	job.SchJob            // 嵌入基础计划任务结构体，继承其属性和方法
+	DbType       string   // 数据库类型：sqlite、mysql、postgres
+	Source       string   // SQLite数据库文件路径
+	Host         string   // MySQL/PostgreSQL服务器地址
+	Port         string   // MySQL/PostgreSQL服务端口，为空时使用导出工具的默认端口
+	Username     string   // 数据库用户名
+	Password     string   // 数据库密码，可使用${secret:NAME}引用密钥
+	Database     string   // 数据库名称
+	Options      []string // 导出工具的附加参数
+	Target       string   // 备份保存目录，本地路径或外部存储中的路径
+	TargetNas    string   // 外部存储标识，为空时保存到本地目录
+	Prefix       string   // 备份文件名前缀
+	KeepNum      int      // 最多保留的备份数量，0不限制
+	KeepDays     int      // 备份最多保留的天数，0不限制
+	ProjectDirID string   // 任务所属项目目录ID，用于限定可访问的密钥
+}
+
+// Backup 一个备份文件
+type Backup struct {
+	Name string    `json:"name"` // 文件名
+	Time time.Time `json:"time"` // 备份时间，取自文件名
+	Size int64     `json:"size"` // 文件大小（字节）
+}
+
+// Run 执行数据库备份任务
+// 导出、压缩并保存备份后清理旧备份，记录任务执行日志，失败时按重试策略重试
+func (job DbBackupJob) Run() {
+	log.Retry(job.SchJob, job.run)
+}
+
+// run 执行一次数据库备份，失败时由Fail判断是否重试
+func (job DbBackupJob) run(schLog *log.SchLog, attempt job.SchJob) {
+	job.SchJob = attempt  // 本次执行的任务信息
+	var logs = []string{} // 日志内容数组
+
+	// 创建并启动日志文件
+	_, logPath, err := schLog.StartLogFile(job.SchJob)
+	if err != nil {
+		logger.LOG.Errorf("发生错误无法创建任务数据: %s\n", err.Error())
+		return
+	}
+	file, err := os.OpenFile(logPath, os.O_WRONLY|os.O_CREATE, 0666)
+	if err != nil {
+		logs = append(logs, "ERROR: "+err.Error())
+		schLog.Fail(job.SchJob, logs, err)
+		return
+	}
+	masker := secret.NewMasker()
+	output := masker.Writer(file)
+
+	// 设置了任务超时时间时限制整个备份过程
+	ctx := schLog.Context()
+	if job.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, job.Timeout)
+		defer cancel()
+	}
+	name, err := job.backup(ctx, output, masker)
+	if err == nil {
+		logs = append(logs, "备份文件: "+name)
+		var removed []string
+		removed, err = job.clean(name)
+		for _, item := range removed {
+			fmt.Fprintln(output, "删除旧备份: "+item)
+		}
+		logs = append(logs, fmt.Sprintf("清理旧备份%d个", len(removed)))
+	}
+	output.Close()
+	file.Close()
+	if err != nil {
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			err = errors.New(cmd.ERR_CMD_TIMEOUT)
+		}
+		logs = append(logs, "ERROR: "+err.Error())
+		schLog.Fail(job.SchJob, masker.MaskAll(logs), err)
+		return
+	}
+	logs = append(logs, "SUCCESS!")
+	schLog.Success(job.SchJob, masker.MaskAll(logs))
+}
+
+// backup 导出数据库并保存压缩后的备份文件，返回备份文件名
+func (job DbBackupJob) backup(ctx context.Context, output io.Writer, masker *secret.Masker) (string, error) {
+	name := job.fileName(time.Now())
+	tmpDir, err := tempDir()
+	if err != nil {
+		return "", err
+	}
+	// 本地目录先写入临时文件，完成后重命名，避免保留不完整的备份
+	gzPath := filepath.Join(tmpDir, name)
+	if job.TargetNas == "" {
+		if err = os.MkdirAll(job.Target, os.ModePerm); err != nil {
+			return "", err
+		}
+		gzPath = filepath.Join(job.Target, name+".part")
+	}
+	defer os.Remove(gzPath)
+
+	fmt.Fprintf(output, "导出%s数据库...\n", job.DbType)
+	switch job.DbType {
+	case TypeSqlite:
+		err = job.dumpSqlite(ctx, tmpDir, gzPath)
+	case TypeMysql, TypePostgres:
+		err = job.dumpTool(ctx, gzPath, output, masker)
+	default:
+		err = fmt.Errorf("不支持的数据库类型: %s", job.DbType)
+	}
+	if err != nil {
+		return "", err
+	}
+	if info, err := os.Stat(gzPath); err == nil {
+		fmt.Fprintf(output, "压缩后大小: %d字节\n", info.Size())
+	}
+
+	if job.TargetNas == "" {
+		fmt.Fprintln(output, "保存到: "+filepath.Join(job.Target, name))
+		return name, os.Rename(gzPath, filepath.Join(job.Target, name))
+	}
+	fmt.Fprintf(output, "上传到: %s:%s\n", job.TargetNas, job.remotePath(name))
+	return name, rclone.CopyFile(tmpDir, name, job.TargetNas+":", job.remotePath(name))
+}
+
+// dumpSqlite 使用VACUUM INTO导出SQLite数据库的一致性快照并压缩
+// VACUUM INTO在读事务中执行，导出期间其他连接仍可正常读写
+func (job DbBackupJob) dumpSqlite(ctx context.Context, tmpDir, gzPath string) error {
+	db, err := openSqlite(job.Source, true)
+	if err != nil {
+		return err
+	}
+	defer closeDb(db)
+
+	snapshot, err := os.CreateTemp(tmpDir, "snapshot-*.db")
+	if err != nil {
+		return err
+	}
+	snapshot.Close()
+	// VACUUM INTO要求目标文件不存在
+	os.Remove(snapshot.Name())
+	defer os.Remove(snapshot.Name())
+	if err = db.WithContext(ctx).Exec("VACUUM INTO ?", snapshot.Name()).Error; err != nil {
+		return err
+	}
+
+	src, err := os.Open(snapshot.Name())
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+	return writeGzip(gzPath, func(w io.Writer) error {
+		_, err := io.Copy(w, src)
+		return err
+	})
+}
+
+// dumpTool 使用mysqldump或pg_dump导出数据库并压缩，导出工具的错误输出写入日志
+// 密码通过环境变量传递，不出现在进程参数中
+func (job DbBackupJob) dumpTool(ctx context.Context, gzPath string, output io.Writer, masker *secret.Masker) error {
+	password, err := secret.Expand(job.Password, &secret.Scope{
+		ProjectDirID: job.ProjectDirID,
+		EntityType:   secret.EntitySchTask,
+		EntityID:     job.TaskId,
+		EntityName:   job.TaskName,
+		Masker:       masker,
+	})
+	if err != nil {
+		return err
+	}
+	masker.Add(password)
+
+	var name string
+	var args, env []string
+	if job.DbType == TypeMysql {
+		// 事务中导出InnoDB表的一致性快照，逐行读取以减少内存占用
+		name = "mysqldump"
+		args = []string{"--single-transaction", "--quick", "--routines", "--triggers", "-h", job.Host, "-u", job.Username}
+		if job.Port != "" {
+			args = append(args, "-P", job.Port)
+		}
+		env = []string{"MYSQL_PWD=" + password}
+	} else {
+		// pg_dump本身在一个可重复读事务中导出一致性快照
+		name = "pg_dump"
+		args = []string{"--no-password", "-h", job.Host, "-U", job.Username}
+		if job.Port != "" {
+			args = append(args, "-p", job.Port)
+		}
+		env = []string{"PGPASSWORD=" + password}
+	}
+	args = append(append(args, job.Options...), job.Database)
+
+	return writeGzip(gzPath, func(w io.Writer) error {
+		return cmd.ExecCronjobArgs(name, args, w, output, cmd.CronjobOptions{
+			Env:     env,
+			Context: ctx,
+		})
+	})
+}
+
+// writeGzip 创建压缩文件并写入内容，失败时删除文件
+func writeGzip(gzPath string, write func(w io.Writer) error) error {
+	file, err := os.Create(gzPath)
+	if err != nil {
+		return err
+	}
+	gz := gzip.NewWriter(file)
+	err = write(gz)
+	if err == nil {
+		err = gz.Close()
+	}
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(gzPath)
+	}
+	return err
+}
+
+// fileName 生成备份文件名，格式为 前缀_时间.db.gz 或 前缀_时间.sql.gz
+func (job DbBackupJob) fileName(t time.Time) string {
+	return fmt.Sprintf("%s_%s.%s.gz", job.prefix(), t.Format(timeLayout), job.ext())
+}
+
+// prefix 备份文件名前缀，未设置时为数据库名称或SQLite文件名
+func (job DbBackupJob) prefix() string {
+	if job.Prefix != "" {
+		return job.Prefix
+	}
+	if job.DbType == TypeSqlite {
+		return strings.TrimSuffix(filepath.Base(job.Source), filepath.Ext(job.Source))
+	}
+	return job.Database
+}
+
+// ext 备份内容的扩展名，SQLite为数据库文件，其他为SQL语句
+func (job DbBackupJob) ext() string {
+	if job.DbType == TypeSqlite {
+		return "db"
+	}
+	return "sql"
+}
+
+// pattern 匹配本任务备份文件名的正则表达式，只有匹配的文件才会被清理或恢复
+func (job DbBackupJob) pattern() *regexp.Regexp {
+	return regexp.MustCompile(`^` + regexp.QuoteMeta(job.prefix()) + `_(\d{8}_\d{6})\.` + job.ext() + `\.gz$`)
+}
+
+// remotePath 备份文件在外部存储中的路径
+func (job DbBackupJob) remotePath(name string) string {
+	return path.Join(strings.Trim(job.Target, "/"), name)
+}
+
+// List 列出保存位置中本任务的备份文件，按时间倒序
+func (job DbBackupJob) List() ([]Backup, error) {
+	pattern := job.pattern()
+	var list []Backup
+	add := func(name string, size int64) {
+		match := pattern.FindStringSubmatch(name)
+		if match == nil {
+			return
+		}
+		t, err := time.ParseInLocation(timeLayout, match[1], time.Local)
+		if err != nil {
+			return
+		}
+		list = append(list, Backup{Name: name, Time: t, Size: size})
+	}
+
+	if job.TargetNas == "" {
+		entries, err := os.ReadDir(job.Target)
+		if err != nil {
+			if os.IsNotExist(err) {
+				return list, nil
+			}
+			return nil, err
+		}
+		for _, entry := range entries {
+			if info, err := entry.Info(); err == nil && !entry.IsDir() {
+				add(entry.Name(), info.Size())
+			}
+		}
+	} else {
+		files, err := rclone.ListFile(job.TargetNas+":"+strings.Trim(job.Target, "/"), nil, nil, 0, 0)
+		if err != nil {
+			return nil, err
+		}
+		for _, file := range files {
+			add(file.Name, file.Size)
+		}
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].Time.After(list[j].Time)
+	})
+	return list, nil
+}
+
+// clean 按保留数量和天数删除旧备份，始终保留本次的备份，返回删除的文件名
+// 超过保留数量或超过保留天数的备份都会被删除
+func (job DbBackupJob) clean(current string) ([]string, error) {
+	if job.KeepNum <= 0 && job.KeepDays <= 0 {
+		return nil, nil
+	}
+	list, err := job.List()
+	if err != nil {
+		return nil, err
+	}
+	deadline := time.Now().AddDate(0, 0, -job.KeepDays)
+	var removed []string
+	for i, backup := range list {
+		if backup.Name == current {
+			continue
+		}
+		if (job.KeepNum <= 0 || i < job.KeepNum) && (job.KeepDays <= 0 || backup.Time.After(deadline)) {
+			continue
+		}
+		if job.TargetNas == "" {
+			err = os.Remove(filepath.Join(job.Target, backup.Name))
+		} else {
+			err = rclone.DeleteFile(job.TargetNas+":", job.remotePath(backup.Name))
+		}
+		if err != nil {
+			return removed, err
+		}
+		removed = append(removed, backup.Name)
+	}
+	return removed, nil
+}
+
+// tempDir 导出和恢复时的临时文件目录
+func tempDir() (string, error) {
+	dir, err := filepath.Abs(filepath.Join(data.WorkDir, "dbbackup", ".tmp"))
+	if err != nil {
+		return "", err
+	}
+	return dir, os.MkdirAll(dir, os.ModePerm)
+}
+
+// openSqlite 打开SQLite数据库文件，must为true时文件不存在返回错误，避免创建空数据库
+func openSqlite(file string, must bool) (*gorm.DB, error) {
+	if must {
+		if _, err := os.Stat(file); err != nil {
+			return nil, err
+		}
+	}
+	return gorm.Open(sqlite.Open(file+"?_pragma=busy_timeout(10000)"), &gorm.Config{
+		Logger: gormLogger.Default.LogMode(gormLogger.Silent),
+	})
+}
+
+// closeDb 关闭数据库连接
+func closeDb(db *gorm.DB) {
+	if sqlDB, err := db.DB(); err == nil {
+		sqlDB.Close()
+	}
+}
@@ -0,0 +1,330 @@
+package dbbackup
+
+// restore.go
+// 该文件实现了SQLite备份的恢复
+// 在同一个连接中附加备份数据库，在一个事务中逐表替换数据，恢复正在使用的数据库时无需停止服务
+
+import (
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"server/data"
+	"server/utils/config"
+	"server/utils/global"
+	"server/utils/rclone"
+	"sort"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// RestoreResult 恢复备份的结果
+type RestoreResult struct {
+	File            string   `json:"file"`             // 恢复的备份文件名
+	Target          string   `json:"target"`           // 恢复到的数据库文件
+	SafetyCopy      string   `json:"safety_copy"`      // 恢复前数据库的副本，恢复有误时可用于还原，目标不存在时为空
+	Tables          []string `json:"tables"`           // 恢复了数据的表
+	Skipped         []string `json:"skipped"`          // 备份中不存在而保留原数据的表
+	RestartRequired bool     `json:"restart_required"` // 恢复的是系统数据库，需要重启服务使计划任务等缓存生效
+}
+
+// Restore 将指定的备份恢复到SQLite数据库文件
+// 恢复前校验备份的完整性，并将当前数据库保存为副本；目标文件不存在时直接使用备份文件
+func (job DbBackupJob) Restore(name string) (RestoreResult, error) {
+	result := RestoreResult{File: name, Target: job.Source}
+	if job.DbType != TypeSqlite {
+		return result, errors.New("仅支持恢复SQLite数据库的备份")
+	}
+	if !job.pattern().MatchString(name) {
+		return result, fmt.Errorf("不是本任务的备份文件: %s", name)
+	}
+	tmpDir, err := tempDir()
+	if err != nil {
+		return result, err
+	}
+
+	// 下载并解压备份
+	file, err := job.fetch(tmpDir, name)
+	if err != nil {
+		return result, err
+	}
+	defer os.Remove(file)
+	if err = checkIntegrity(file); err != nil {
+		return result, err
+	}
+
+	// 目标文件不存在时直接移动到目标位置
+	if _, err = os.Stat(job.Source); os.IsNotExist(err) {
+		if err = os.MkdirAll(filepath.Dir(job.Source), os.ModePerm); err != nil {
+			return result, err
+		}
+		return result, moveFile(file, job.Source)
+	}
+
+	// 正在使用的系统数据库使用全局连接恢复，其他数据库打开新的连接
+	db := global.DB
+	result.RestartRequired = isSystemDb(job.Source)
+	if !result.RestartRequired {
+		if db, err = openSqlite(job.Source, true); err != nil {
+			return result, err
+		}
+		defer closeDb(db)
+	}
+
+	// 保存恢复前的数据库副本
+	result.SafetyCopy = fmt.Sprintf("%s.before-restore-%s", job.Source, time.Now().Format(timeLayout))
+	if err = db.Exec("VACUUM INTO ?", result.SafetyCopy).Error; err != nil {
+		return result, fmt.Errorf("保存恢复前的数据库副本失败: %w", err)
+	}
+
+	result.Tables, result.Skipped, err = restoreTables(db, file)
+	return result, err
+}
+
+// fetch 获取备份文件并解压到临时目录，返回解压后的数据库文件路径
+func (job DbBackupJob) fetch(tmpDir, name string) (string, error) {
+	gzPath := filepath.Join(job.Target, name)
+	if job.TargetNas != "" {
+		temp, err := os.CreateTemp(tmpDir, "download-*.gz")
+		if err != nil {
+			return "", err
+		}
+		temp.Close()
+		defer os.Remove(temp.Name())
+		if err = rclone.CopyFile(job.TargetNas+":", job.remotePath(name), tmpDir, filepath.Base(temp.Name())); err != nil {
+			return "", err
+		}
+		gzPath = temp.Name()
+	}
+
+	src, err := os.Open(gzPath)
+	if err != nil {
+		return "", err
+	}
+	defer src.Close()
+	gz, err := gzip.NewReader(src)
+	if err != nil {
+		return "", err
+	}
+	defer gz.Close()
+	dst, err := os.CreateTemp(tmpDir, "restore-*.db")
+	if err != nil {
+		return "", err
+	}
+	_, err = io.Copy(dst, gz)
+	if closeErr := dst.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(dst.Name())
+		return "", err
+	}
+	return dst.Name(), nil
+}
+
+// checkIntegrity 校验备份数据库的完整性
+func checkIntegrity(file string) error {
+	db, err := openSqlite(file, true)
+	if err != nil {
+		return err
+	}
+	defer closeDb(db)
+	var result string
+	if err = db.Raw("PRAGMA integrity_check").Scan(&result).Error; err != nil {
+		return fmt.Errorf("备份文件不是有效的SQLite数据库: %w", err)
+	}
+	if result != "ok" {
+		return fmt.Errorf("备份文件校验失败: %s", result)
+	}
+	return nil
+}
+
+// restoreTables 在一个事务中用备份的数据替换目标数据库中各表的数据
+// 目标中不存在的表按备份中的结构创建，两边都有的表只复制共同的列，备份中不存在的表保留原数据
+func restoreTables(db *gorm.DB, file string) (tables, skipped []string, err error) {
+	// ATTACH只对当前连接有效，需要在同一个连接中完成
+	err = db.Connection(func(conn *gorm.DB) error {
+		if err := conn.Exec("ATTACH DATABASE ? AS bak", file).Error; err != nil {
+			return err
+		}
+		defer conn.Exec("DETACH DATABASE bak")
+		return conn.Transaction(func(tx *gorm.DB) error {
+			backupTables, err := listTables(tx, "bak")
+			if err != nil {
+				return err
+			}
+			mainTables, err := listTables(tx, "main")
+			if err != nil {
+				return err
+			}
+			for _, name := range tableNames(backupTables) {
+				if _, ok := mainTables[name]; !ok {
+					if name == "sqlite_sequence" {
+						// 自增序号表在创建带AUTOINCREMENT的表时自动创建，不能手动创建
+						if mainTables, err = listTables(tx, "main"); err != nil {
+							return err
+						}
+						if _, ok = mainTables[name]; !ok {
+							continue
+						}
+					} else if err := createTable(tx, name, backupTables[name]); err != nil {
+						return err
+					}
+				}
+				if err := copyTable(tx, name); err != nil {
+					return err
+				}
+				tables = append(tables, name)
+			}
+			for _, name := range tableNames(mainTables) {
+				if _, ok := backupTables[name]; !ok && name != "sqlite_sequence" {
+					skipped = append(skipped, name)
+				}
+			}
+			return nil
+		})
+	})
+	return tables, skipped, err
+}
+
+// listTables 获取数据库中的表及其建表语句，包括记录自增序号的sqlite_sequence
+func listTables(tx *gorm.DB, schema string) (map[string]string, error) {
+	var rows []struct {
+		Name string
+		Sql  string
+	}
+	err := tx.Raw(fmt.Sprintf("SELECT name, sql FROM %s.sqlite_master WHERE type = 'table' AND (name NOT LIKE 'sqlite_%%' OR name = 'sqlite_sequence')", schema)).Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+	tables := make(map[string]string, len(rows))
+	for _, row := range rows {
+		tables[row.Name] = row.Sql
+	}
+	return tables, nil
+}
+
+// tableNames 获取排序后的表名，sqlite_sequence排在最后，在其他表创建后处理
+func tableNames(tables map[string]string) []string {
+	names := make([]string, 0, len(tables))
+	for name := range tables {
+		names = append(names, name)
+	}
+	sort.Slice(names, func(i, j int) bool {
+		if (names[i] == "sqlite_sequence") != (names[j] == "sqlite_sequence") {
+			return names[j] == "sqlite_sequence"
+		}
+		return names[i] < names[j]
+	})
+	return names
+}
+
+// createTable 按备份中的建表语句创建表及其索引
+func createTable(tx *gorm.DB, name, sql string) error {
+	if err := tx.Exec(sql).Error; err != nil {
+		return err
+	}
+	var indexes []string
+	err := tx.Raw("SELECT sql FROM bak.sqlite_master WHERE type = 'index' AND tbl_name = ? AND sql IS NOT NULL", name).Scan(&indexes).Error
+	if err != nil {
+		return err
+	}
+	for _, index := range indexes {
+		if err := tx.Exec(index).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// copyTable 清空目标表后复制备份表中两边共同的列
+func copyTable(tx *gorm.DB, name string) error {
+	backupColumns, err := listColumns(tx, "bak", name)
+	if err != nil {
+		return err
+	}
+	mainColumns, err := listColumns(tx, "main", name)
+	if err != nil {
+		return err
+	}
+	exists := make(map[string]bool, len(mainColumns))
+	for _, column := range mainColumns {
+		exists[column] = true
+	}
+	columns := make([]string, 0, len(backupColumns))
+	for _, column := range backupColumns {
+		if exists[column] {
+			columns = append(columns, quote(column))
+		}
+	}
+	if err = tx.Exec("DELETE FROM main." + quote(name)).Error; err != nil {
+		return err
+	}
+	if len(columns) == 0 {
+		return nil
+	}
+	list := strings.Join(columns, ", ")
+	return tx.Exec(fmt.Sprintf("INSERT INTO main.%s (%s) SELECT %s FROM bak.%s", quote(name), list, list, quote(name))).Error
+}
+
+// listColumns 获取表的列名
+func listColumns(tx *gorm.DB, schema, table string) ([]string, error) {
+	var rows []struct {
+		Name string
+	}
+	err := tx.Raw(fmt.Sprintf("PRAGMA %s.table_info(%s)", schema, quote(table))).Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+	columns := make([]string, 0, len(rows))
+	for _, row := range rows {
+		columns = append(columns, row.Name)
+	}
+	return columns, nil
+}
+
+// quote 为SQLite标识符加引号
+func quote(name string) string {
+	return `"` + strings.ReplaceAll(name, `"`, `""`) + `"`
+}
+
+// isSystemDb 判断数据库文件是否为系统正在使用的SQLite数据库
+func isSystemDb(file string) bool {
+	if config.CONF.Db.Type == TypeMysql || config.CONF.Db.Type == TypePostgres {
+		return false
+	}
+	return samePath(file, data.DbPath)
+}
+
+// samePath 判断两个路径是否指向同一个文件
+func samePath(a, b string) bool {
+	absA, errA := filepath.Abs(a)
+	absB, errB := filepath.Abs(b)
+	return errA == nil && errB == nil && absA == absB
+}
+
+// moveFile 移动文件，不在同一个文件系统时复制后删除源文件
+func moveFile(src, dst string) error {
+	if err := os.Rename(src, dst); err == nil {
+		return nil
+	}
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	out, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+	if _, err = io.Copy(out, in); err != nil {
+		out.Close()
+		os.Remove(dst)
+		return err
+	}
+	return out.Close()
+}
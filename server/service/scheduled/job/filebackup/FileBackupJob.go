@@ -1,6 +1,7 @@
 package filebackup
 
 import (
+	"context"
 	"fmt"
 	"server/service/scheduled/job"
 	"server/service/scheduled/log"
@@ -26,10 +27,15 @@ type FileBackupJob struct {
 
 // Run 执行文件备份任务
 // 根据配置的备份类型选择不同的备份策略
-// 记录任务执行日志和错误信息
+// 记录任务执行日志和错误信息，失败时按重试策略重试
 func (job FileBackupJob) Run() {
-	schLog := log.SchLog{} // 初始化日志对象
-	var logs = []string{}  // 日志内容数组
+	log.Retry(job.SchJob, job.run)
+}
+
+// run 执行一次文件备份，失败时由Fail判断是否重试
+func (job FileBackupJob) run(schLog *log.SchLog, attempt job.SchJob) {
+	job.SchJob = attempt  // 本次执行的任务信息
+	var logs = []string{} // 日志内容数组
 
 	// 创建并启动日志文件
 	workDir, logPath, err := schLog.StartLogFile(job.SchJob)
@@ -39,20 +45,28 @@ func (job FileBackupJob) Run() {
 		return
 	}
 
+	// 设置了任务超时时间时覆盖rclone配置的超时时间
+	ctx := schLog.Context()
+	if job.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, job.Timeout)
+		defer cancel()
+	}
+
 	// 根据配置的备份类型执行不同的备份操作
 	switch job.Type {
 	case 1:
 		// 仅复制文件，不删除目标位置已有文件
-		err = rclone.CmdCopy(workDir, logPath, job.Src, job.Dst, job.CreateEmptySrcDirs, job.ShowDebug, job.Includes, job.Excludes)
+		err = rclone.CmdCopy(ctx, workDir, logPath, job.Src, job.Dst, job.CreateEmptySrcDirs, job.ShowDebug, job.Includes, job.Excludes)
 	case 2:
 		// 镜像复制，会删除目标位置有而源位置没有的文件
-		err = rclone.CmdSync(workDir, logPath, job.Src, job.Dst, job.CreateEmptySrcDirs, job.ShowDebug, job.Includes, job.Excludes)
+		err = rclone.CmdSync(ctx, workDir, logPath, job.Src, job.Dst, job.CreateEmptySrcDirs, job.ShowDebug, job.Includes, job.Excludes)
 	case 3:
 		// 双向同步，使源和目标位置的文件保持一致
-		err = rclone.CmdBisync(workDir, logPath, job.Src, job.Dst, job.CreateEmptySrcDirs, job.RemoveEmptyDirs, job.ShowDebug, job.Includes, job.Excludes)
+		err = rclone.CmdBisync(ctx, workDir, logPath, job.Src, job.Dst, job.CreateEmptySrcDirs, job.RemoveEmptyDirs, job.ShowDebug, job.Includes, job.Excludes)
 	case 4:
 		// 完整备份，创建带有时间戳的新目录进行完整复制
-		err = rclone.CmdCopy(workDir, logPath, job.Src, fmt.Sprintf("%s/%s", job.Dst, time.Now().Format("20060102_150405")), job.CreateEmptySrcDirs, job.ShowDebug, job.Includes, job.Excludes)
+		err = rclone.CmdCopy(ctx, workDir, logPath, job.Src, fmt.Sprintf("%s/%s", job.Dst, time.Now().Format("20060102_150405")), job.CreateEmptySrcDirs, job.ShowDebug, job.Includes, job.Excludes)
 	default:
 		// 未知的备份类型，不执行任何操作
 	}
@@ -60,9 +74,9 @@ func (job FileBackupJob) Run() {
 	// 根据执行结果记录日志
 	if err != nil {
 		logs = append(logs, "ERROR: "+err.Error()) // 记录错误信息
-		schLog.Error(job.SchJob, logs)             // 将错误日志写入日志文件
-	} else {
-		logs = append(logs, "SUCCESS!")  // 记录成功信息
-		schLog.Success(job.SchJob, logs) // 将成功日志写入日志文件
+		schLog.Fail(job.SchJob, logs, err)         // 将错误日志写入日志文件，满足条件时重试
+		return
 	}
+	logs = append(logs, "SUCCESS!")  // 记录成功信息
+	schLog.Success(job.SchJob, logs) // 将成功日志写入日志文件
 }
@@ -75,7 +75,7 @@ func (job FileCleanJob) Run() {
 	}
 
 	// 执行文件删除命令
-	err = rclone.CmdDelete(workDir, logPath, job.WorkDir, job.Includes, job.Excludes, minTime, job.IsTest, job.ShowDebug)
+	err = rclone.CmdDelete(schLog.Context(), workDir, logPath, job.WorkDir, job.Includes, job.Excludes, minTime, job.IsTest, job.ShowDebug)
 	if err != nil {
 		logs = append(logs, "ERROR: "+err.Error())
 		schLog.Error(job.SchJob, logs)
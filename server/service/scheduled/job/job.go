@@ -1,10 +1,30 @@
 package job
 
+import "time"
+
 // SchJob 计划任务基础结构体
 // 定义了所有计划任务共有的基础属性
 // 被各种具体任务类型结构体嵌入，提供通用字段
 type SchJob struct {
-	TaskId   uint   `gorm:"comment:'任务编号'" json:"task_id"`  // 任务唯一标识ID
-	TaskName string `gorm:"comment:'名称'" json:"task_name"`  // 任务名称，用于显示
-	TaskType string `gorm:"comment:'类型' " json:"task_type"` // 任务类型，用于区分不同种类的任务
+	TaskId   uint          `gorm:"comment:'任务编号'" json:"task_id"`  // 任务唯一标识ID
+	TaskName string        `gorm:"comment:'名称'" json:"task_name"`  // 任务名称，用于显示
+	TaskType string        `gorm:"comment:'类型' " json:"task_type"` // 任务类型，用于区分不同种类的任务
+	Trigger  *Trigger      `gorm:"-" json:"trigger"`               // 触发本次执行的上游任务，定时或手动执行时为nil
+	Retry    *Retry        `gorm:"-" json:"retry"`                 // 失败后的重试策略，为nil时不重试
+	Attempt  int           `gorm:"-" json:"attempt"`               // 当前是第几次执行，从1开始
+	Misfire  *Misfire      `gorm:"-" json:"misfire"`               // 补执行的错过的调度，正常调度执行时为nil
+	Timeout  time.Duration `gorm:"-" json:"timeout"`               // 执行超时时间，为0时使用任务类型的默认超时
+}
+
+// Trigger 触发下游任务执行的上游任务执行记录
+type Trigger struct {
+	TaskId uint `json:"task_id"` // 上游任务ID
+	LogId  uint `json:"log_id"`  // 上游任务的执行日志ID
+}
+
+// Misfire 服务停止期间错过的一次调度
+type Misfire struct {
+	ScheduledTime time.Time `json:"scheduled_time"` // 原计划执行时间
+	Index         int       `json:"index"`          // 本次是第几个补执行的调度，从1开始
+	Total         int       `json:"total"`          // 本次启动补执行的调度数量
 }
@@ -0,0 +1,67 @@
+package job
+
+import (
+	"context"
+	"errors"
+	"server/utils/cmd"
+	"strings"
+	"time"
+)
+
+// 重试条件
+const (
+	RetryOnAll     = "all"     // 所有错误都重试
+	RetryOnTimeout = "timeout" // 只重试超时错误
+)
+
+// maxRetryDelay 指数退避时单次等待的最长时间
+const maxRetryDelay = time.Hour
+
+// Retry 任务失败后的重试策略
+type Retry struct {
+	MaxAttempts int           `json:"max_attempts"` // 最多执行次数，包含第一次执行
+	Delay       time.Duration `json:"delay"`        // 第一次重试前的等待时间
+	Backoff     bool          `json:"backoff"`      // 指数退避，每次重试的等待时间翻倍
+	On          string        `json:"on"`           // 重试条件：all所有错误，timeout只重试超时错误，为空时为all
+}
+
+// ShouldRetry 判断第attempt次执行失败后是否需要重试
+func (r *Retry) ShouldRetry(attempt int, err error) bool {
+	if r == nil || err == nil || attempt >= r.MaxAttempts {
+		return false
+	}
+	if r.On == RetryOnTimeout {
+		return IsTimeout(err)
+	}
+	return true
+}
+
+// Wait 获取第attempt次执行失败后到下次重试的等待时间
+func (r *Retry) Wait(attempt int) time.Duration {
+	delay := r.Delay
+	if r.Backoff {
+		for i := 1; i < attempt && delay < maxRetryDelay; i++ {
+			delay *= 2
+		}
+		if delay > maxRetryDelay {
+			delay = maxRetryDelay
+		}
+	}
+	return delay
+}
+
+// IsTimeout 判断是否为超时错误，包括命令执行超时和网络超时
+func IsTimeout(err error) bool {
+	if err == nil {
+		return false
+	}
+	if errors.Is(err, context.DeadlineExceeded) {
+		return true
+	}
+	var timeout interface{ Timeout() bool }
+	if errors.As(err, &timeout) && timeout.Timeout() {
+		return true
+	}
+	message := strings.ToLower(err.Error())
+	return strings.Contains(message, strings.ToLower(cmd.ERR_CMD_TIMEOUT)) || strings.Contains(message, "timeout") || strings.Contains(message, "timed out")
+}
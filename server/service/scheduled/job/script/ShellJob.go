@@ -1,8 +1,11 @@
 package shell
 
 import (
+	"fmt"
+	"os"
 	"server/service/scheduled/job"
 	"server/service/scheduled/log"
+	"server/service/secret"
 	"server/utils/cmd"
 	"server/utils/logger"
 	"time"
@@ -12,16 +15,63 @@ import (
 // 定义了Shell脚本任务的配置和行为，用于执行自定义Shell命令或脚本
 // 通过嵌入SchJob获得计划任务的基本属性和行为
 type ShellJob struct {
-	job.SchJob        // 嵌入基础计划任务结构体，继承其属性和方法
-	Script     string `gorm:"comment:'脚本'" json:"script"` // 要执行的Shell脚本内容
+	job.SchJob               // 嵌入基础计划任务结构体，继承其属性和方法
+	Script       string      `gorm:"comment:'脚本'" json:"script"` // 要执行的Shell脚本内容
+	Secrets      []string    `json:"secrets"`                    // 以环境变量方式注入的密钥名称
+	ProjectDirID string      `json:"project_dir_id"`             // 任务所属项目目录ID，用于限定可访问的密钥
+	WorkDir      string      `json:"work_dir"`                   // 工作目录，为空时使用数据目录下的job目录
+	IsolateDir   bool        `json:"isolate_dir"`                // 是否每次执行在工作目录下创建独立的临时目录作为工作目录，执行后删除
+	Env          []string    `json:"env"`                        // 追加的环境变量，格式为KEY=VALUE，同名时密钥优先
+	Shell        string      `json:"shell"`                      // 解释器：sh、bash、python，为空时为sh
+	User         string      `json:"user"`                       // 执行脚本的系统用户，仅Linux支持，为空时为当前用户
+	Limits       *cmd.Limits `json:"limits"`                     // 资源限制，仅Linux支持，为nil时不限制
+}
+
+// defaultTimeout 未设置超时时间时的默认超时
+const defaultTimeout = time.Hour * 5
+
+// prepareDir 准备本次执行的工作目录
+// 隔离时在工作目录下创建本次执行的临时目录，并通过TMPDIR等环境变量指向该目录，返回的cleanup在执行后删除临时目录
+func (job ShellJob) prepareDir(defaultDir string) (dir string, env []string, cleanup func(), err error) {
+	dir = defaultDir
+	if job.WorkDir != "" {
+		dir = job.WorkDir
+	}
+	if err = os.MkdirAll(dir, 0755); err != nil {
+		return "", nil, nil, err
+	}
+	if !job.IsolateDir {
+		return dir, nil, func() {}, nil
+	}
+	tmp, err := os.MkdirTemp(dir, fmt.Sprintf("run_%d_", job.TaskId))
+	if err != nil {
+		return "", nil, nil, err
+	}
+	cleanup = func() {
+		if err := os.RemoveAll(tmp); err != nil {
+			logger.LOG.Warnf("删除临时目录 %s 失败: %v", tmp, err)
+		}
+	}
+	if job.User != "" {
+		if err = cmd.ChownUser(tmp, job.User); err != nil {
+			cleanup()
+			return "", nil, nil, err
+		}
+	}
+	return tmp, []string{"TMPDIR=" + tmp, "TMP=" + tmp, "TEMP=" + tmp}, cleanup, nil
 }
 
 // Run 执行Shell脚本任务
 // 在指定的工作目录下执行配置的Shell脚本
-// 设置超时时间并记录执行日志和错误信息
+// 设置超时时间并记录执行日志和错误信息，输出中的密钥值会被脱敏，失败时按重试策略重试
 func (job ShellJob) Run() {
-	schLog := log.SchLog{} // 初始化日志对象
-	var logs = []string{}  // 日志内容数组
+	log.Retry(job.SchJob, job.run)
+}
+
+// run 执行一次Shell脚本，失败时由Fail判断是否重试
+func (job ShellJob) run(schLog *log.SchLog, attempt job.SchJob) {
+	job.SchJob = attempt  // 本次执行的任务信息
+	var logs = []string{} // 日志内容数组
 
 	// 创建并启动日志文件
 	workDir, logPath, err := schLog.StartLogFile(job.SchJob)
@@ -30,13 +80,71 @@ func (job ShellJob) Run() {
 		return
 	}
 
-	// 执行Shell脚本，设置5小时超时
-	err = cmd.ExecCronjobWithTimeOut(job.Script, workDir, logPath, time.Hour*5)
+	// 解析密钥并注入环境变量，每次执行时读取以使用轮换后的密钥
+	masker := secret.NewMasker()
+	env, err := secret.Env(job.Secrets, &secret.Scope{
+		ProjectDirID: job.ProjectDirID,
+		EntityType:   secret.EntitySchTask,
+		EntityID:     job.TaskId,
+		EntityName:   job.TaskName,
+		Masker:       masker,
+	})
 	if err != nil {
 		logs = append(logs, "ERROR: "+err.Error())
 		schLog.Error(job.SchJob, logs)
-	} else {
-		logs = append(logs, "SUCCESS!")
-		schLog.Success(job.SchJob, logs)
+		return
+	}
+
+	file, err := os.OpenFile(logPath, os.O_WRONLY|os.O_CREATE, 0666)
+	if err != nil {
+		logs = append(logs, "ERROR: "+err.Error())
+		schLog.Fail(job.SchJob, logs, err)
+		return
+	}
+	output := masker.Writer(file)
+
+	// 准备工作目录，隔离时使用本次执行独立的临时目录
+	dir, dirEnv, cleanup, err := job.prepareDir(workDir)
+	if err != nil {
+		output.Close()
+		file.Close()
+		logs = append(logs, "ERROR: "+err.Error())
+		schLog.Fail(job.SchJob, masker.MaskAll(logs), err)
+		return
+	}
+	defer cleanup()
+
+	// 执行Shell脚本，未设置超时时间时默认5小时超时
+	timeout := job.Timeout
+	if timeout <= 0 {
+		timeout = defaultTimeout
+	}
+	// 环境变量依次为临时目录、任务配置和密钥，同名时后者优先
+	env = append(append(dirEnv, job.Env...), env...)
+	var usage cmd.Usage
+	err = cmd.ExecCronjob(job.Script, dir, output, cmd.CronjobOptions{
+		Timeout: timeout,
+		Env:     env,
+		Context: schLog.Context(),
+		Shell:   job.Shell,
+		User:    job.User,
+		Limits:  job.Limits,
+		Usage:   &usage,
+	})
+	// 未能应用的资源限制作为警告写入日志
+	for _, warning := range usage.Warnings {
+		logger.LOG.Warnf("任务[%s]资源限制: %s", job.TaskName, warning)
+		fmt.Fprintln(output, "WARN: "+warning)
+		logs = append(logs, "WARN: "+warning)
+	}
+	output.Close()
+	file.Close()
+	schLog.SetUsage(usage.PeakMemory, usage.CPUTime)
+	if err != nil {
+		logs = append(logs, "ERROR: "+err.Error())
+		schLog.Fail(job.SchJob, masker.MaskAll(logs), err)
+		return
 	}
+	logs = append(logs, "SUCCESS!")
+	schLog.Success(job.SchJob, masker.MaskAll(logs))
 }
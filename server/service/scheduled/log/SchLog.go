@@ -2,6 +2,7 @@
 package log
 
 import (
+	"context"
 	"fmt"
 	"log"
 	"os"
@@ -9,6 +10,9 @@ import (
 	"server/core/app/request"
 	"server/core/db"
 	"server/data"
+	"server/service/notify"
+	"server/service/retention"
+	"server/service/scheduled/cluster"
 	"server/service/scheduled/job"
 	"server/utils"
 	"server/utils/global"
@@ -22,7 +26,7 @@ import (
 )
 
 // SchLog 计划任务日志结构体
-// status 0 执行中 1完成 -1失败
+// status 0 执行中 1完成 -1失败 -2异常终止 -3失败后已重试 -4已取消
 type SchLog struct {
 	ID        uint         `gorm:"primary_key" json:"id" mapstructure:"id"`   // 主键ID
 	TaskId    uint         `gorm:"comment:'任务ID'" json:"task_id"`             // 关联的任务ID
@@ -31,8 +35,24 @@ type SchLog struct {
 	LogText   string       `gorm:"comment:'日志内容' default:''" json:"log_text"` // 日志文本内容
 	StartTime db.LocalTime `gorm:"comment:'开始时间'" json:"start_time"`          // 任务开始时间
 	EndTime   db.LocalTime `gorm:"comment:'结束时间'" json:"end_time"`            // 任务结束时间
+	watch     func()       `gorm:"-"`                                         // 停止执行时间过长监视
+
+	TriggerTaskId uint   `gorm:"default:0;comment:'触发的上游任务ID'" json:"trigger_task_id"` // 触发本次执行的上游任务ID，定时或手动执行时为0
+	TriggerLogId  uint   `gorm:"default:0;comment:'触发的上游日志ID'" json:"trigger_log_id"`  // 触发本次执行的上游任务日志ID
+	Attempt       int    `gorm:"default:1;comment:'第几次执行'" json:"attempt"`             // 第几次执行，失败重试时递增
+	RetryOf       uint   `gorm:"default:0;comment:'重试的日志ID'" json:"retry_of"`          // 重试时为上一次执行的日志ID
+	MisfireTime   string `gorm:"comment:'补执行的计划时间' size:32" json:"misfire_time"`       // 补执行错过的调度时为原计划执行时间，否则为空
+	Node          string `gorm:"comment:'执行节点' size:128" json:"node"`                  // 执行任务的节点标识
+	PeakMemory    int64  `gorm:"default:0;comment:'内存峰值'" json:"peak_memory"`          // Shell任务的内存峰值（字节），无法获取时为0
+	CpuTime       int64  `gorm:"default:0;comment:'CPU时间'" json:"cpu_time"`            // Shell任务的CPU时间（毫秒）
+
+	run    *Running `gorm:"-"` // 登记的正在执行的任务，用于取消执行
+	ownRun bool     `gorm:"-"` // 是否由本条日志登记，为true时本次执行结束后移出
 }
 
+// OnFinished 任务执行结束后的回调，用于触发依赖该任务的下游任务
+var OnFinished func(job job.SchJob, entity SchLog)
+
 // TableName 指定数据库表名
 func (SchLog) TableName() string {
 	return "sch_task_log" // 返回数据库中对应的表名
@@ -42,10 +62,13 @@ func (SchLog) TableName() string {
 // 创建一条新的日志记录，标记任务开始执行
 func (entity *SchLog) Start(job job.SchJob) error {
 	logger.LOG.Infof("RUN: taskId:%d, taskName:%s, taskType:%s", job.TaskId, job.TaskName, job.TaskType)
-	entity.TaskId = job.TaskId                          // 设置任务ID
-	entity.StartTime = db.LocalTime{}.Now()             // 设置开始时间为当前时间
-	entity.Status = 0                                   // 设置状态为执行中(0)
-	return global.DB.Model(entity).Create(entity).Error // 创建日志记录并返回可能的错误
+	entity.TaskId = job.TaskId              // 设置任务ID
+	entity.setTrigger(job)                  // 记录触发的上游任务
+	entity.Attempt = max(entity.Attempt, 1) // 未通过Retry执行时为第1次执行
+	entity.StartTime = db.LocalTime{}.Now() // 设置开始时间为当前时间
+	entity.Status = 0                       // 设置状态为执行中(0)
+	entity.startWatch(job)                  // 监视任务执行时间
+	return entity.create(job)               // 创建日志记录并登记为正在执行
 }
 
 // StartLogFile 开始记录任务执行日志并创建日志文件
@@ -54,17 +77,61 @@ func (entity *SchLog) Start(job job.SchJob) error {
 func (entity *SchLog) StartLogFile(job job.SchJob) (string, string, error) {
 	// 构建工作目录和日志文件路径
 	workDir := filepath.Join(data.WorkDir, "job")
-	logPath := filepath.Join(workDir, "logs", fmt.Sprintf("%d", job.TaskId), fmt.Sprintf("%s.log", time.Now().Format("20060102_150405")))
+	name := time.Now().Format("20060102_150405")
+	if entity.Attempt > 1 {
+		// 重试的日志文件加上执行次数，避免同一秒内重试时覆盖上一次的日志
+		name = fmt.Sprintf("%s_%d", name, entity.Attempt)
+	}
+	logPath := filepath.Join(workDir, "logs", fmt.Sprintf("%d", job.TaskId), name+".log")
 	// 确保日志目录存在
 	os.MkdirAll(filepath.Dir(logPath), 0777)
 	logger.LOG.Infof("RUN: taskId:%d, taskName:%s, taskType:%s", job.TaskId, job.TaskName, job.TaskType)
 	// 设置日志记录的基本信息
 	entity.TaskId = job.TaskId
+	entity.setTrigger(job)
+	entity.Attempt = max(entity.Attempt, 1)
 	entity.StartTime = db.LocalTime{}.Now()
 	entity.Status = 0
 	entity.LogPath = logPath
+	// 监视任务执行时间
+	entity.startWatch(job)
 	// 创建日志记录并返回工作目录、日志路径和可能的错误
-	return workDir, logPath, global.DB.Model(entity).Create(entity).Error
+	return workDir, logPath, entity.create(job)
+}
+
+// create 创建日志记录并登记为正在执行的任务
+func (entity *SchLog) create(job job.SchJob) error {
+	if entity.run == nil {
+		entity.run = newRunning(job)
+		entity.ownRun = true
+	}
+	entity.Node = cluster.NodeID()
+	if err := global.DB.Model(entity).Create(entity).Error; err != nil {
+		entity.detach()
+		return err
+	}
+	entity.run.attempt(entity.ID, entity.Attempt)
+	return nil
+}
+
+// detach 本条日志登记的执行结束时移出正在执行的任务列表
+func (entity *SchLog) detach() {
+	if entity.run != nil && entity.ownRun {
+		entity.run.remove()
+	}
+}
+
+// Context 获取本次执行的上下文，任务被取消时结束，用于结束执行中的命令
+func (entity *SchLog) Context() context.Context {
+	if entity.run == nil {
+		return context.Background()
+	}
+	return entity.run.ctx
+}
+
+// Canceled 本次执行是否已被取消
+func (entity *SchLog) Canceled() bool {
+	return entity.run != nil && entity.run.canceled()
 }
 
 // Log 写入日志内容
@@ -76,6 +143,13 @@ func (entity *SchLog) Log(logText []string) error {
 	return global.DB.Model(entity).Select("log_text").Updates(entity).Error
 }
 
+// SetUsage 记录本次执行的内存峰值和CPU时间
+func (entity *SchLog) SetUsage(peakMemory int64, cpuTime time.Duration) error {
+	entity.PeakMemory = peakMemory
+	entity.CpuTime = cpuTime.Milliseconds()
+	return global.DB.Model(entity).Select("peak_memory", "cpu_time").Updates(entity).Error
+}
+
 // Success 标记任务执行成功
 // 更新日志记录，设置状态为成功(1)，记录结束时间和日志内容
 func (entity *SchLog) Success(job job.SchJob, logText []string) error {
@@ -86,13 +160,21 @@ func (entity *SchLog) Success(job job.SchJob, logText []string) error {
 	// 设置状态为成功(1)
 	entity.Status = 1
 	logger.LOG.Infof("SUCCESS: taskId:%d, taskName:%s, taskType:%s", job.TaskId, job.TaskName, job.TaskType)
+	// 发送执行成功通知
+	entity.publish(job, notify.EventSchTaskSuccess)
 	// 更新数据库中的日志记录
-	return global.DB.Model(entity).Select("log_text", "end_time", "status").Updates(entity).Error
+	err := global.DB.Model(entity).Select("log_text", "end_time", "status").Updates(entity).Error
+	entity.detach()
+	entity.finished(job)
+	return err
 }
 
 // Error 标记任务执行失败
-// 更新日志记录，设置状态为失败(-1)，记录结束时间和日志内容
+// 更新日志记录，设置状态为失败(-1)，记录结束时间和日志内容；任务已被取消时标记为已取消
 func (entity *SchLog) Error(job job.SchJob, logText []string) error {
+	if entity.Canceled() {
+		return entity.markCanceled(job, logText)
+	}
 	// 合并日志文本
 	entity.LogText = strings.Join(logText, "\n")
 	// 设置结束时间为当前时间
@@ -100,10 +182,201 @@ func (entity *SchLog) Error(job job.SchJob, logText []string) error {
 	// 设置状态为失败(-1)
 	entity.Status = -1
 	logger.LOG.Errorf("ERROR: taskId:%d, taskName:%s, taskType:%s", job.TaskId, job.TaskName, job.TaskType)
+	// 发送执行失败通知
+	entity.publish(job, notify.EventSchTaskFailed)
 	// 更新数据库中的日志记录
+	err := global.DB.Model(entity).Select("log_text", "end_time", "status").Updates(entity).Error
+	entity.detach()
+	entity.finished(job)
+	return err
+}
+
+// markCanceled 标记任务已取消(-4)，不发送失败通知也不触发下游任务
+func (entity *SchLog) markCanceled(job job.SchJob, logText []string) error {
+	logText = append(logText, "CANCELED: 任务已取消")
+	entity.LogText = strings.Join(logText, "\n")
+	entity.EndTime = db.LocalTime{}.Now()
+	entity.Status = -4
+	logger.LOG.Warnf("CANCELED: taskId:%d, taskName:%s, taskType:%s", job.TaskId, job.TaskName, job.TaskType)
+	if entity.watch != nil {
+		entity.watch()
+		entity.watch = nil
+	}
+	err := global.DB.Model(entity).Select("log_text", "end_time", "status").Updates(entity).Error
+	entity.detach()
+	return err
+}
+
+// Fail 标记本次执行失败
+// 满足任务的重试条件时标记为已重试(-3)，不发送失败通知也不触发下游任务，否则同Error
+func (entity *SchLog) Fail(job job.SchJob, logText []string, err error) error {
+	if entity.Canceled() || !job.Retry.ShouldRetry(job.Attempt, err) {
+		return entity.Error(job, logText)
+	}
+	wait := job.Retry.Wait(job.Attempt)
+	logText = append(logText, fmt.Sprintf("RETRY: 第%d次执行失败，%s后重试", job.Attempt, wait))
+	entity.LogText = strings.Join(logText, "\n")
+	entity.EndTime = db.LocalTime{}.Now()
+	entity.Status = -3
+	logger.LOG.Warnf("RETRY: taskId:%d, taskName:%s, taskType:%s, attempt:%d", job.TaskId, job.TaskName, job.TaskType, job.Attempt)
+	if entity.watch != nil {
+		entity.watch()
+		entity.watch = nil
+	}
 	return global.DB.Model(entity).Select("log_text", "end_time", "status").Updates(entity).Error
 }
 
+// Retry 按任务的重试策略执行任务，每次执行都记录一条日志，重试的日志关联上一次执行的日志
+// run 中通过 Fail 标记失败，满足重试条件时标记为已重试，等待后再次执行，最终状态为最后一次执行的状态
+// 所有重试登记为同一个正在执行的任务，取消时不再重试
+func Retry(job job.SchJob, run func(schLog *SchLog, job job.SchJob)) {
+	r := newRunning(job)
+	defer r.remove()
+	var retryOf uint
+	for job.Attempt = 1; ; job.Attempt++ {
+		schLog := SchLog{Attempt: job.Attempt, RetryOf: retryOf, run: r}
+		run(&schLog, job)
+		if schLog.ID == 0 || schLog.Status != -3 {
+			return
+		}
+		// 等待重试期间被取消时，将最后一次执行标记为已取消
+		r.wait()
+		select {
+		case <-time.After(job.Retry.Wait(job.Attempt)):
+		case <-r.ctx.Done():
+			schLog.markCanceled(job, strings.Split(schLog.LogText, "\n"))
+			return
+		}
+		retryOf = schLog.ID
+	}
+}
+
+// setTrigger 记录触发本次执行的上游任务，补执行错过的调度时记录原计划执行时间
+func (entity *SchLog) setTrigger(job job.SchJob) {
+	if job.Trigger != nil {
+		entity.TriggerTaskId = job.Trigger.TaskId
+		entity.TriggerLogId = job.Trigger.LogId
+	}
+	if job.Misfire != nil {
+		entity.MisfireTime = job.Misfire.ScheduledTime.Local().Format(db.TimeFormat)
+	}
+}
+
+// finished 执行结束后异步调用结束回调
+func (entity *SchLog) finished(job job.SchJob) {
+	if OnFinished != nil {
+		go OnFinished(job, *entity)
+	}
+}
+
+// Latest 查询任务最近一次的执行日志，没有执行过时返回ID为0的记录
+func (entity SchLog) Latest(taskID uint) (SchLog, error) {
+	err := global.DB.Model(&SchLog{}).Where("task_id = ?", taskID).Order("id desc").Limit(1).Find(&entity).Error
+	return entity, err
+}
+
+// LatestOfTasks 查询多个任务最近一次的执行日志，按任务ID返回，没有执行过的任务不在结果中
+// 只查询状态和执行时间，不加载日志内容
+func (entity SchLog) LatestOfTasks(taskIDs []uint) (map[uint]SchLog, error) {
+	result := make(map[uint]SchLog, len(taskIDs))
+	if len(taskIDs) == 0 {
+		return result, nil
+	}
+	latest := global.DB.Model(&SchLog{}).Select("MAX(id)").Where("task_id IN ?", taskIDs).Group("task_id")
+	var list []SchLog
+	err := global.DB.Model(&SchLog{}).Select("id", "task_id", "status", "start_time", "end_time").
+		Where("id IN (?)", latest).Find(&list).Error
+	if err != nil {
+		return nil, err
+	}
+	for _, item := range list {
+		result[item.TaskId] = item
+	}
+	return result, nil
+}
+
+// LatestSuccess 查询任务最近一次执行成功的日志，没有执行成功过时返回ID为0的记录
+func (entity SchLog) LatestSuccess(taskID uint) (SchLog, error) {
+	err := global.DB.Model(&SchLog{}).Where("task_id = ? and status = 1", taskID).Order("id desc").Limit(1).Find(&entity).Error
+	return entity, err
+}
+
+// Prune 只保留任务最近的keep条日志，同时删除日志文件，keep不大于0时不清理
+func (entity SchLog) Prune(taskID uint, keep int) (int, error) {
+	return retention.Prune(&SchLog{}, func(tx *gorm.DB) *gorm.DB {
+		return tx.Where("task_id = ?", taskID)
+	}, keep)
+}
+
+// Purge 删除任务所有已执行结束的日志和日志文件
+func (entity SchLog) Purge(taskID uint) (int, error) {
+	return retention.Purge(&SchLog{}, func(tx *gorm.DB) *gorm.DB {
+		return tx.Where("task_id = ?", taskID)
+	})
+}
+
+// message 构建任务通知消息
+func (entity *SchLog) message(job job.SchJob, event string) notify.Message {
+	msg := notify.Message{
+		Event: event,
+		Level: "info",
+		Time:  time.Now(),
+		Data: map[string]any{
+			"taskId":    job.TaskId,
+			"taskName":  job.TaskName,
+			"taskType":  job.TaskType,
+			"logId":     entity.ID,
+			"startTime": entity.StartTime.Format(db.TimeFormat),
+		},
+	}
+	switch event {
+	case notify.EventSchTaskSuccess:
+		msg.Title = fmt.Sprintf("计划任务执行成功: %s", job.TaskName)
+	case notify.EventSchTaskFailed:
+		msg.Level = "error"
+		msg.Title = fmt.Sprintf("计划任务执行失败: %s", job.TaskName)
+	case notify.EventSchTaskLongRunning:
+		msg.Level = "warn"
+		msg.Title = fmt.Sprintf("计划任务执行时间过长: %s", job.TaskName)
+	}
+	msg.Content = fmt.Sprintf("任务: %s(%d)\n类型: %s\n开始时间: %s", job.TaskName, job.TaskId, job.TaskType, entity.StartTime.Format(db.TimeFormat))
+	return msg
+}
+
+// startWatch 开始监视任务执行时间
+func (entity *SchLog) startWatch(job job.SchJob) {
+	entity.watch = notify.WatchLongRunning(job.TaskId, entity.message(job, notify.EventSchTaskLongRunning))
+}
+
+// publish 停止执行时间监视并发布任务结束事件
+func (entity *SchLog) publish(job job.SchJob, event string) {
+	if entity.watch != nil {
+		entity.watch()
+		entity.watch = nil
+	}
+	msg := entity.message(job, event)
+	msg.Data["endTime"] = entity.EndTime.Format(db.TimeFormat)
+	if event == notify.EventSchTaskFailed && entity.LogText != "" {
+		msg.Content += "\n" + tail(entity.LogText, 2000)
+	}
+	notify.Publish(job.TaskId, msg)
+}
+
+// tail 截取文本末尾的内容
+func tail(text string, size int) string {
+	runes := []rune(text)
+	if len(runes) <= size {
+		return text
+	}
+	return "..." + string(runes[len(runes)-size:])
+}
+
+// State 查询日志的所属、状态、日志文件路径和日志内容，不读取日志文件，用于跟踪执行中的日志
+func (entity SchLog) State(id any) (SchLog, error) {
+	err := global.DB.Model(entity).Select("id", "task_id", "status", "log_path", "log_text").Take(&entity, id).Error
+	return entity, err
+}
+
 // Load 按主键查询日志记录
 // 根据ID加载日志记录，并尝试读取关联的日志文件内容
 func (entity SchLog) Load(id any) (SchLog, error) {
@@ -0,0 +1,130 @@
+package log
+
+import (
+	"context"
+	"fmt"
+	"server/core/db"
+	"server/service/scheduled/job"
+	"server/utils/cmd"
+	"sort"
+	"sync"
+)
+
+// Running 正在执行的计划任务
+// 一次执行包含失败后的所有重试，取消时结束当前执行的整个进程组并不再重试
+type Running struct {
+	TaskId    uint         `json:"task_id"`    // 任务ID
+	TaskName  string       `json:"task_name"`  // 任务名称
+	TaskType  string       `json:"task_type"`  // 任务类型
+	LogId     uint         `json:"log_id"`     // 当前执行的日志ID
+	Attempt   int          `json:"attempt"`    // 当前是第几次执行
+	Waiting   bool         `json:"waiting"`    // 是否在等待重试
+	StartTime db.LocalTime `json:"start_time"` // 开始执行时间
+	PIDs      []int        `json:"pids"`       // 已启动命令的进程ID
+
+	ctx    context.Context    // 取消时结束命令的上下文
+	cancel context.CancelFunc // 取消执行
+}
+
+var (
+	runningLock sync.Mutex                    // 保护正在执行的任务列表
+	runningMap  = make(map[*Running]struct{}) // 正在执行的任务
+)
+
+// newRunning 登记一次任务执行
+func newRunning(job job.SchJob) *Running {
+	r := &Running{
+		TaskId:    job.TaskId,
+		TaskName:  job.TaskName,
+		TaskType:  job.TaskType,
+		Attempt:   max(job.Attempt, 1),
+		StartTime: db.LocalTime{}.Now(),
+		PIDs:      make([]int, 0),
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	r.ctx = cmd.WithStartHook(ctx, r.addPID)
+	r.cancel = cancel
+	runningLock.Lock()
+	runningMap[r] = struct{}{}
+	runningLock.Unlock()
+	return r
+}
+
+// addPID 记录执行中启动的命令进程ID
+func (r *Running) addPID(pid int) {
+	runningLock.Lock()
+	defer runningLock.Unlock()
+	r.PIDs = append(r.PIDs, pid)
+}
+
+// attempt 开始新一次执行时更新日志ID和执行次数
+func (r *Running) attempt(logID uint, attempt int) {
+	runningLock.Lock()
+	defer runningLock.Unlock()
+	r.LogId = logID
+	r.Attempt = attempt
+	r.Waiting = false
+}
+
+// wait 标记为等待重试
+func (r *Running) wait() {
+	runningLock.Lock()
+	defer runningLock.Unlock()
+	r.Waiting = true
+}
+
+// remove 执行结束后移出正在执行的任务列表
+func (r *Running) remove() {
+	r.cancel()
+	runningLock.Lock()
+	defer runningLock.Unlock()
+	delete(runningMap, r)
+}
+
+// canceled 是否已被取消
+func (r *Running) canceled() bool {
+	return r.ctx.Err() != nil
+}
+
+// ListRunning 获取正在执行的计划任务，按开始时间排序
+func ListRunning() []Running {
+	runningLock.Lock()
+	list := make([]Running, 0, len(runningMap))
+	for r := range runningMap {
+		item := *r
+		item.PIDs = append([]int(nil), r.PIDs...)
+		list = append(list, item)
+	}
+	runningLock.Unlock()
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].StartTime.Before(list[j].StartTime.Time)
+	})
+	return list
+}
+
+// Cancel 取消日志ID对应的正在执行的任务，结束其启动的整个进程组
+func Cancel(logID uint) error {
+	runningLock.Lock()
+	defer runningLock.Unlock()
+	for r := range runningMap {
+		if r.LogId == logID {
+			r.cancel()
+			return nil
+		}
+	}
+	return fmt.Errorf("日志 %d 对应的任务没有在执行", logID)
+}
+
+// CancelTask 取消任务所有正在执行的实例，返回取消的数量
+func CancelTask(taskID uint) int {
+	runningLock.Lock()
+	defer runningLock.Unlock()
+	count := 0
+	for r := range runningMap {
+		if r.TaskId == taskID {
+			r.cancel()
+			count++
+		}
+	}
+	return count
+}
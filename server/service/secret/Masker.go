@@ -0,0 +1,124 @@
+package secret
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// maskText 脱敏后的替换文本
+const maskText = "******"
+
+// minMaskLength 参与脱敏的密钥值最小长度，过短的值容易误伤正常输出
+const minMaskLength = 4
+
+// Masker 密钥脱敏器
+// 收集执行过程中解析出的密钥值，在日志和执行结果中将其替换为******
+// 零值和nil均可直接使用
+type Masker struct {
+	mu     sync.RWMutex
+	values []string
+}
+
+// NewMasker 创建脱敏器
+func NewMasker() *Masker {
+	return &Masker{}
+}
+
+// Add 添加需要脱敏的密钥值，同时添加其JSON转义形式
+func (m *Masker) Add(value string) {
+	if m == nil || len(value) < minMaskLength {
+		return
+	}
+	variants := []string{value}
+	if escaped, err := json.Marshal(value); err == nil {
+		if s := string(escaped[1 : len(escaped)-1]); s != value {
+			variants = append(variants, s)
+		}
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, v := range variants {
+		exists := false
+		for _, old := range m.values {
+			if old == v {
+				exists = true
+				break
+			}
+		}
+		if !exists {
+			m.values = append(m.values, v)
+		}
+	}
+	// 优先替换较长的值，避免包含关系导致部分泄露
+	sort.Slice(m.values, func(i, j int) bool { return len(m.values[i]) > len(m.values[j]) })
+}
+
+// Mask 替换文本中的密钥值
+func (m *Masker) Mask(text string) string {
+	if m == nil {
+		return text
+	}
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	for _, v := range m.values {
+		text = strings.ReplaceAll(text, v, maskText)
+	}
+	return text
+}
+
+// MaskAll 替换多行文本中的密钥值
+func (m *Masker) MaskAll(lines []string) []string {
+	masked := make([]string, len(lines))
+	for i, line := range lines {
+		masked[i] = m.Mask(line)
+	}
+	return masked
+}
+
+// Writer 创建按行脱敏的写入器，使用完毕后需调用Close输出剩余内容
+func (m *Masker) Writer(w io.Writer) *MaskWriter {
+	return &MaskWriter{masker: m, w: w}
+}
+
+// MaskWriter 按行脱敏的写入器
+type MaskWriter struct {
+	masker *Masker
+	w      io.Writer
+	mu     sync.Mutex
+	buf    bytes.Buffer
+}
+
+// Write 缓存输入内容，每遇到完整的一行脱敏后写入
+func (mw *MaskWriter) Write(p []byte) (int, error) {
+	mw.mu.Lock()
+	defer mw.mu.Unlock()
+	mw.buf.Write(p)
+	for {
+		idx := bytes.IndexByte(mw.buf.Bytes(), '\n')
+		if idx < 0 {
+			break
+		}
+		line := string(mw.buf.Next(idx + 1))
+		if _, err := io.WriteString(mw.w, mw.masker.Mask(line)); err != nil {
+			return len(p), err
+		}
+	}
+	return len(p), nil
+}
+
+// Close 输出缓存中剩余的内容
+func (mw *MaskWriter) Close() error {
+	mw.mu.Lock()
+	defer mw.mu.Unlock()
+	if mw.buf.Len() == 0 {
+		return nil
+	}
+	_, err := io.WriteString(mw.w, mw.masker.Mask(mw.buf.String()))
+	mw.buf.Reset()
+	return err
+}
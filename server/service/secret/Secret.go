@@ -0,0 +1,208 @@
+// Package secret 提供密钥的加密存储、按项目目录的访问范围控制、轮换和使用审计
+package secret
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"server/core/app/request"
+	"server/core/db"
+	"server/service/basic"
+	"server/utils/config"
+	"server/utils/global"
+	"server/utils/xxtea"
+	"strings"
+
+	"github.com/duke-git/lancet/v2/slice"
+	"gorm.io/gorm"
+)
+
+// Secret 密钥模型
+// 密钥值使用数据加密秘钥加密存储，只能被所属项目目录及其子目录下的流程和任务使用
+type Secret struct {
+	db.BaseModel[Secret]              // 嵌入基础模型，提供ID、创建时间等公共字段
+	Name                 string       `gorm:"comment:'名称' size:128" json:"name"`                // 密钥名称，在EL表达式和环境变量中引用
+	Value                string       `gorm:"comment:'密钥值' size:4096" json:"value"`             // 密钥值，加密存储，查询接口不返回
+	ProjectDirID         string       `gorm:"comment:'项目目录ID';default:0" json:"project_dir_id"` // 所属项目目录ID，0表示全局可用
+	Version              uint         `gorm:"default:1;comment:'版本'" json:"version"`            // 密钥版本，每次轮换加1
+	RotatedAt            db.LocalTime `gorm:"comment:'轮换时间'" json:"rotated_at"`                 // 最近一次轮换时间
+	Remark               string       `gorm:"comment:'备注'" json:"remark"`                       // 备注说明
+}
+
+// TableName 指定数据库表名
+func (Secret) TableName() string {
+	return "secret"
+}
+
+// namePattern 密钥名称格式，需能直接作为环境变量名使用
+var namePattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// placeholderPattern 配置文本中引用密钥的占位符，格式为${secret:NAME}
+var placeholderPattern = regexp.MustCompile(`\$\{secret:([A-Za-z_][A-Za-z0-9_]*)\}`)
+
+// AfterFind GORM钩子，查询后解密密钥值
+func (u *Secret) AfterFind(tx *gorm.DB) (err error) {
+	u.SupperAfterFind()
+	u.Value = xxtea.DecryptAuto(u.Value, config.CONF.Db.DataKey)
+	return
+}
+
+// BeforeSave GORM钩子，保存前加密密钥值
+func (u *Secret) BeforeSave(tx *gorm.DB) (err error) {
+	if u.Value != "" {
+		u.Value = xxtea.EncryptAuto(u.Value, config.CONF.Db.DataKey)
+	}
+	return
+}
+
+// List 查询密钥列表，不返回密钥值
+func (entity Secret) List(query request.PageQuery) (list []Secret, count int64, err error) {
+	list, count, err = entity.BaseModel.List(query)
+	for i := range list {
+		list[i].Value = ""
+	}
+	return list, count, err
+}
+
+// Save 保存密钥
+// 更新时密钥值为空表示不修改密钥值，修改密钥值请使用轮换
+func (entity Secret) Save(data *Secret, columns ...string) error {
+	if !namePattern.MatchString(data.Name) {
+		return errors.New("密钥名称只能包含字母、数字和下划线，且不能以数字开头")
+	}
+	if data.ProjectDirID == "" {
+		data.ProjectDirID = "0"
+	}
+	var count int64
+	global.DB.Model(&Secret{}).Where("name = ? and project_dir_id = ? and id <> ?", data.Name, data.ProjectDirID, data.ID).Count(&count)
+	if count > 0 {
+		return fmt.Errorf("同一项目目录下已存在密钥: %s", data.Name)
+	}
+	if data.ID == 0 {
+		if data.Value == "" {
+			return errors.New("密钥值不能为空")
+		}
+		data.Version = 1
+		data.RotatedAt = db.LocalTime{}.Now()
+		return entity.BaseModel.Save(data)
+	}
+	columns = slice.Filter(columns, func(_ int, column string) bool {
+		return column != "value" && column != "version" && column != "rotated_at"
+	})
+	data.Value = ""
+	return entity.BaseModel.Save(data, columns...)
+}
+
+// Rotate 轮换密钥值，返回使用该密钥的实体，便于确认轮换的影响范围
+func (entity Secret) Rotate(id any, value string) ([]SecretUsage, error) {
+	if value == "" {
+		return nil, errors.New("密钥值不能为空")
+	}
+	secret, err := entity.Load(id)
+	if err != nil {
+		return nil, err
+	}
+	if secret.ID == 0 {
+		return nil, errors.New("密钥不存在")
+	}
+	secret.Value = value
+	secret.Version++
+	secret.RotatedAt = db.LocalTime{}.Now()
+	err = global.DB.Model(&secret).Select("value", "version", "rotated_at").Updates(&secret).Error
+	if err != nil {
+		return nil, err
+	}
+	return SecretUsage{}.ListBySecret(secret.ID)
+}
+
+// Resolve 按使用范围解析密钥值
+// 优先使用与使用者项目目录最接近的密钥，依次查找上级目录，最后查找全局密钥；
+// 解析成功后记录使用审计，并将密钥值加入脱敏器
+func Resolve(name string, scope *Scope) (string, error) {
+	if name == "" {
+		return "", errors.New("密钥名称不能为空")
+	}
+	if scope == nil {
+		scope = &Scope{}
+	}
+	dirs := projectDirChain(scope.ProjectDirID)
+
+	var list []Secret
+	err := global.DB.Model(&Secret{}).Where("name = ? and is_disable = 0 and project_dir_id in ?", name, dirs).Find(&list).Error
+	if err != nil {
+		return "", err
+	}
+	var found *Secret
+	for _, dir := range dirs {
+		for i := range list {
+			if list[i].ProjectDirID == dir {
+				found = &list[i]
+				break
+			}
+		}
+		if found != nil {
+			break
+		}
+	}
+	if found == nil {
+		return "", fmt.Errorf("密钥不存在或无权访问: %s", name)
+	}
+
+	scope.Masker.Add(found.Value)
+	SecretUsage{}.Record(*found, scope)
+	return found.Value, nil
+}
+
+// Env 将密钥解析为环境变量，变量名为密钥名称
+func Env(names []string, scope *Scope) ([]string, error) {
+	env := make([]string, 0, len(names))
+	for _, name := range names {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		value, err := Resolve(name, scope)
+		if err != nil {
+			return nil, err
+		}
+		env = append(env, name+"="+value)
+	}
+	return env, nil
+}
+
+// Expand 替换文本中的${secret:NAME}占位符为密钥值
+func Expand(text string, scope *Scope) (string, error) {
+	var resolveErr error
+	result := placeholderPattern.ReplaceAllStringFunc(text, func(match string) string {
+		if resolveErr != nil {
+			return match
+		}
+		name := placeholderPattern.FindStringSubmatch(match)[1]
+		value, err := Resolve(name, scope)
+		if err != nil {
+			resolveErr = err
+			return match
+		}
+		return value
+	})
+	if resolveErr != nil {
+		return "", resolveErr
+	}
+	return result, nil
+}
+
+// projectDirChain 获取项目目录及其所有上级目录ID，最后为全局目录
+func projectDirChain(dirID string) []string {
+	chain := make([]string, 0)
+	visited := make(map[string]bool)
+	for dirID != "" && dirID != "0" && !visited[dirID] {
+		visited[dirID] = true
+		chain = append(chain, dirID)
+		var dir basic.ProjectDir
+		if err := global.DB.Model(&basic.ProjectDir{}).Select("id", "parent_id").Take(&dir, dirID).Error; err != nil {
+			break
+		}
+		dirID = fmt.Sprint(dir.ParentID)
+	}
+	return append(chain, "0", "")
+}
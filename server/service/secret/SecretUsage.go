@@ -0,0 +1,76 @@
+package secret
+
+import (
+	"server/core/db"
+	"server/utils/global"
+	"server/utils/logger"
+)
+
+// 密钥使用者类型
+const (
+	EntitySFlow       = "sflow"        // 作业流程
+	EntitySchTask     = "sch_task"     // 计划任务
+	EntityExternalNas = "external_nas" // 外部存储配置
+)
+
+// Scope 密钥的使用范围
+// 标识使用密钥的实体及其所属项目目录，并收集已解析的密钥值用于脱敏
+type Scope struct {
+	ProjectDirID string  // 使用者所属项目目录ID
+	EntityType   string  // 使用者类型
+	EntityID     uint    // 使用者ID
+	EntityName   string  // 使用者名称
+	Masker       *Masker // 脱敏器，解析出的密钥值会加入其中
+}
+
+// SecretUsage 密钥使用审计记录
+// 每个密钥与使用实体的组合保留一条记录，记录使用次数和最近使用时间
+type SecretUsage struct {
+	ID         uint         `gorm:"primary_key" json:"id"`                      // 主键ID
+	SecretID   uint         `gorm:"index;comment:'密钥ID'" json:"secret_id"`      // 密钥ID
+	SecretName string       `gorm:"comment:'密钥名称'" json:"secret_name"`          // 密钥名称
+	Version    uint         `gorm:"comment:'使用的密钥版本'" json:"version"`           // 最近使用的密钥版本
+	EntityType string       `gorm:"comment:'使用者类型' size:20" json:"entity_type"` // 使用者类型：sflow、sch_task
+	EntityID   uint         `gorm:"comment:'使用者ID'" json:"entity_id"`           // 使用者ID
+	EntityName string       `gorm:"comment:'使用者名称'" json:"entity_name"`         // 使用者名称
+	UseCount   uint         `gorm:"default:0;comment:'使用次数'" json:"use_count"`  // 使用次数
+	LastUsedAt db.LocalTime `gorm:"comment:'最近使用时间'" json:"last_used_at"`       // 最近使用时间
+}
+
+// TableName 指定数据库表名
+func (SecretUsage) TableName() string {
+	return "secret_usage"
+}
+
+// Record 记录密钥的一次使用
+func (SecretUsage) Record(secret Secret, scope *Scope) {
+	if scope.EntityType == "" {
+		return
+	}
+	var usage SecretUsage
+	err := global.DB.Model(&SecretUsage{}).
+		Where("secret_id = ? and entity_type = ? and entity_id = ?", secret.ID, scope.EntityType, scope.EntityID).
+		Limit(1).Find(&usage).Error
+	if err != nil {
+		logger.LOG.Errorf("查询密钥使用记录失败: %s", err.Error())
+		return
+	}
+	usage.SecretID = secret.ID
+	usage.SecretName = secret.Name
+	usage.Version = secret.Version
+	usage.EntityType = scope.EntityType
+	usage.EntityID = scope.EntityID
+	usage.EntityName = scope.EntityName
+	usage.UseCount++
+	usage.LastUsedAt = db.LocalTime{}.Now()
+	if err := global.DB.Save(&usage).Error; err != nil {
+		logger.LOG.Errorf("记录密钥使用失败: %s", err.Error())
+	}
+}
+
+// ListBySecret 查询使用指定密钥的实体
+func (SecretUsage) ListBySecret(secretID uint) ([]SecretUsage, error) {
+	list := make([]SecretUsage, 0)
+	err := global.DB.Model(&SecretUsage{}).Where("secret_id = ?", secretID).Order("last_used_at desc").Find(&list).Error
+	return list, err
+}
@@ -15,14 +15,19 @@ import (
 // 用于存储SFlow
 type SFlow struct {
 	db.BaseModel[SFlow]        // 继承基础模型，提供通用字段和方法
-	Name                string `gorm:"comment:'名称' size:128" json:"name"`                  // 名称
-	Content             string `gorm:"comment:'脚本' size:102400 default:''" json:"content"` // 脚本（JSON格式）
-	Type                string `gorm:"comment:'类型' size:20 default:''" json:"type"`        // 类型
-	LogLevel            int    `gorm:"comment:'日志级别' default:0" json:"log_level"`          // 日志级别
-	LastStatus          int    `gorm:"default:0;comment:'最近状态'" json:"last_status"`        // 最近执行状态
-	LastRunTime         string `gorm:"comment:'上次执行时间'" json:"last_run_time"`              // 上次执行时间
-	ProjectDirID        string `gorm:"comment:'项目目录ID';default:0" json:"project_dir_id"`   // 项目目录ID，关联到项目目录
-	Remark              string `gorm:"comment:'备注'" json:"remark"`                         // 备注说明
+	Name                string `gorm:"comment:'名称' size:128" json:"name"`                    // 名称
+	Content             string `gorm:"comment:'脚本' size:102400 default:''" json:"content"`   // 脚本（JSON格式）
+	Type                string `gorm:"comment:'类型' size:20 default:''" json:"type"`          // 类型
+	LogLevel            int    `gorm:"comment:'日志级别' default:0" json:"log_level"`            // 日志级别
+	Timeout             int    `gorm:"default:0;comment:'超时时间(秒)'" json:"timeout"`           // 流程执行超时时间(秒)，0表示不限制
+	Workers             int    `gorm:"default:0;comment:'并发节点数'" json:"workers"`             // 单次执行的并发节点数，0表示使用默认值
+	Concurrency         string `gorm:"comment:'并发策略' size:20 default:''" json:"concurrency"` // 并发策略：parallel、skip、queue、replace
+	MaxQueue            int    `gorm:"default:0;comment:'最大排队数'" json:"max_queue"`           // 排队策略下的最大等待数量
+	LogKeepNum          int    `gorm:"default:0;comment:'保留日志数量'" json:"log_keep_num"`       // 保留的执行日志数量，0表示不限制
+	LastStatus          int    `gorm:"default:0;comment:'最近状态'" json:"last_status"`          // 最近执行状态
+	LastRunTime         string `gorm:"comment:'上次执行时间'" json:"last_run_time"`                // 上次执行时间
+	ProjectDirID        string `gorm:"comment:'项目目录ID';default:0" json:"project_dir_id"`     // 项目目录ID，关联到项目目录
+	Remark              string `gorm:"comment:'备注'" json:"remark"`                           // 备注说明
 }
 
 // TableName 返回SFlow表名
@@ -0,0 +1,73 @@
+package sflow
+
+import (
+	"server/core/db"
+	"server/utils/global"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// SFlowArtifact 流程制品记录
+// 节点保存的文件或自动转存的大结果，内容保存在制品存储中，记录保存位置和元数据
+type SFlowArtifact struct {
+	ID          uint         `gorm:"primary_key" json:"id"`                                // 主键ID
+	SFlowId     uint         `gorm:"column:sflow_id;index;comment:'流程ID'" json:"sflow_id"` // 流程ID
+	ExecutionID string       `gorm:"index;comment:'执行ID' size:64" json:"execution_id"`     // 执行ID
+	NodeID      string       `gorm:"comment:'节点ID' size:64" json:"node_id"`                // 节点ID
+	Name        string       `gorm:"comment:'名称'" json:"name"`                             // 制品名称
+	URI         string       `gorm:"index;comment:'URI' size:512" json:"uri"`              // 制品URI
+	Store       string       `gorm:"comment:'存储位置' size:128" json:"store"`                 // 存储位置，为空表示工作目录，否则为外部存储的rclone标识
+	Key         string       `gorm:"comment:'存储路径' size:1024" json:"-"`                    // 在存储中的路径
+	Size        int64        `gorm:"comment:'大小'" json:"size"`                             // 大小(字节)
+	MimeType    string       `gorm:"comment:'MIME类型' size:128" json:"mime_type"`           // MIME类型
+	Checksum    string       `gorm:"comment:'校验和' size:80" json:"checksum"`                // 校验和
+	CreatedAt   db.LocalTime `gorm:"index;comment:'创建时间'" json:"created_at"`               // 创建时间
+}
+
+// TableName 指定数据库表名
+func (SFlowArtifact) TableName() string {
+	return "sflow_artifact"
+}
+
+// Create 创建制品记录，同一URI的旧记录会被替换
+func (entity *SFlowArtifact) Create() error {
+	entity.CreatedAt = db.LocalTime{}.Now()
+	return global.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("uri = ?", entity.URI).Delete(&SFlowArtifact{}).Error; err != nil {
+			return err
+		}
+		return tx.Model(entity).Create(entity).Error
+	})
+}
+
+// Load 按主键查询制品记录
+func (entity SFlowArtifact) Load(id any) (SFlowArtifact, error) {
+	err := global.DB.Model(&SFlowArtifact{}).Take(&entity, id).Error
+	return entity, err
+}
+
+// FindByURI 按URI查询制品记录
+func (entity SFlowArtifact) FindByURI(uri string) (SFlowArtifact, error) {
+	err := global.DB.Model(&SFlowArtifact{}).Where("uri = ?", uri).Take(&entity).Error
+	return entity, err
+}
+
+// ListByExecution 查询一次执行的所有制品
+func (entity SFlowArtifact) ListByExecution(executionID string) ([]SFlowArtifact, error) {
+	list := make([]SFlowArtifact, 0)
+	err := global.DB.Model(&SFlowArtifact{}).Where("execution_id = ?", executionID).Order("id").Find(&list).Error
+	return list, err
+}
+
+// ListExpired 查询创建时间早于指定时间的制品
+func (entity SFlowArtifact) ListExpired(before time.Time, limit int) ([]SFlowArtifact, error) {
+	list := make([]SFlowArtifact, 0)
+	err := global.DB.Model(&SFlowArtifact{}).Where("created_at < ?", before).Order("id").Limit(limit).Find(&list).Error
+	return list, err
+}
+
+// Delete 删除制品记录
+func (entity SFlowArtifact) Delete(id uint) error {
+	return global.DB.Delete(&SFlowArtifact{}, id).Error
+}
@@ -9,6 +9,7 @@ import (
 	"server/core/app/request"
 	"server/core/db"
 	"server/data"
+	"server/service/retention"
 	"server/utils"
 	"server/utils/global"
 	"server/utils/logger"
@@ -30,6 +31,14 @@ type SFlowLog struct {
 	LogText   string       `gorm:"comment:'日志内容' default:''" json:"log_text"` // 日志文本内容
 	StartTime db.LocalTime `gorm:"comment:'开始时间'" json:"start_time"`          // 任务开始时间
 	EndTime   db.LocalTime `gorm:"comment:'结束时间'" json:"end_time"`            // 任务结束时间
+
+	ExecutionID string `gorm:"index;comment:'执行ID' size:64" json:"execution_id"` // DAGFlow执行ID
+	Version     string `gorm:"comment:'流程版本' size:64" json:"version"`            // 执行时的流程版本
+	Params      string `gorm:"comment:'输入参数' size:65535" json:"params"`          // 输入参数(JSON)
+	Results     string `gorm:"comment:'节点结果' size:1048576" json:"-"`             // 节点结果和错误(JSON)，已脱敏，用于演练回放
+	NodeStates  string `gorm:"comment:'节点状态' size:65535" json:"node_states"`     // 节点状态和耗时(JSON)，用于在流程图上叠加显示
+	ReplayOf    string `gorm:"index;comment:'回放的执行ID' size:64" json:"replay_of"` // 回放的原执行ID
+	Dry         bool   `gorm:"default:false;comment:'演练回放'" json:"dry"`          // 是否为演练回放
 }
 
 // TableName 指定数据库表名
@@ -51,9 +60,14 @@ func (entity *SFlowLog) Start(sflow SFlow) error {
 // 创建一条新的日志记录，并创建对应的日志文件
 // 返回工作目录、日志文件路径和可能的错误
 func (entity *SFlowLog) StartLogFile(sflow SFlow) (string, string, error) {
-	// 构建工作目录和日志文件路径
-	workDir := filepath.Join(data.WorkDir, "job")
-	logPath := filepath.Join(workDir, "logs", fmt.Sprintf("%d", sflow.ID), fmt.Sprintf("%s.log", time.Now().Format("20060102_150405")))
+	// 构建工作目录和日志文件路径，与计划任务的日志目录分开，避免任务ID和流程ID相同时混在一起
+	workDir := filepath.Join(data.WorkDir, "flow")
+	name := time.Now().Format("20060102_150405")
+	if entity.ExecutionID != "" {
+		// 同一流程可以并发执行，使用执行ID避免日志文件重名
+		name = entity.ExecutionID
+	}
+	logPath := filepath.Join(workDir, "logs", fmt.Sprintf("%d", sflow.ID), name+".log")
 	// 确保日志目录存在
 	os.MkdirAll(filepath.Dir(logPath), 0777)
 	logger.LOG.Infof("RUN: taskId:%d, taskName:%s, taskType:%s", sflow.ID, sflow.Name, sflow.Type)
@@ -103,6 +117,45 @@ func (entity *SFlowLog) Error(sflow SFlow, logText []string) error {
 	return global.DB.Model(entity).Select("log_text", "end_time", "status").Updates(entity).Error
 }
 
+// SaveNodes 保存节点结果和节点状态
+func (entity *SFlowLog) SaveNodes(results, states string) error {
+	entity.Results = results
+	entity.NodeStates = states
+	return global.DB.Model(entity).Select("results", "node_states").Updates(entity).Error
+}
+
+// FindByExecution 查询执行最近的日志记录，等待后恢复的执行有多条记录
+func (entity SFlowLog) FindByExecution(executionID string) (SFlowLog, error) {
+	err := global.DB.Model(&SFlowLog{}).Where("execution_id = ?", executionID).Order("id desc").Take(&entity).Error
+	return entity, err
+}
+
+// State 查询日志的所属、状态、日志文件路径和日志内容，不读取日志文件，用于跟踪执行中的日志
+func (entity SFlowLog) State(id any) (SFlowLog, error) {
+	err := global.DB.Model(entity).Select("id", "s_flow_id", "status", "log_path", "log_text").Take(&entity, id).Error
+	return entity, err
+}
+
+// Prune 只保留流程最近的keep条日志，同时删除日志文件，keep不大于0时不清理
+func (entity SFlowLog) Prune(sflowID uint, keep int) (int, error) {
+	if sflowID == 0 {
+		return 0, nil
+	}
+	return retention.Prune(&SFlowLog{}, func(tx *gorm.DB) *gorm.DB {
+		return tx.Where(&SFlowLog{SFlowId: sflowID})
+	}, keep)
+}
+
+// Purge 删除流程所有已执行结束的日志和日志文件
+func (entity SFlowLog) Purge(sflowID uint) (int, error) {
+	if sflowID == 0 {
+		return 0, nil
+	}
+	return retention.Purge(&SFlowLog{}, func(tx *gorm.DB) *gorm.DB {
+		return tx.Where(&SFlowLog{SFlowId: sflowID})
+	})
+}
+
 // Load 按主键查询日志记录
 // 根据ID加载日志记录，并尝试读取关联的日志文件内容
 func (entity SFlowLog) Load(id any) (SFlowLog, error) {
@@ -0,0 +1,76 @@
+package sflow
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"server/core/db"
+	"server/utils/global"
+	"time"
+)
+
+// SFlowTestCase 流程测试用例
+// 指定输入参数、模拟部分节点的结果或错误，并断言节点状态、最终数据和执行结果
+type SFlowTestCase struct {
+	db.BaseModel[SFlowTestCase]        // 继承基础模型，提供通用字段和方法
+	SFlowId                     uint   `gorm:"column:sflow_id;index;comment:'流程ID'" json:"sflow_id"` // 流程ID
+	Name                        string `gorm:"comment:'名称' size:128" json:"name"`                    // 用例名称
+	Params                      string `gorm:"comment:'输入参数' size:65535 default:''" json:"params"`   // 输入参数(JSON对象)
+	Mocks                       string `gorm:"comment:'模拟节点' size:65535 default:''" json:"mocks"`    // 模拟节点(JSON对象)，键为节点ID，值为{"result":结果,"error":错误信息}
+	Expect                      string `gorm:"comment:'断言' size:65535 default:''" json:"expect"`     // 断言(JSON对象)：status 流程状态、error 错误信息、nodeStatus 节点状态、assertions EL断言
+	LastStatus                  int    `gorm:"default:0;comment:'最近结果'" json:"last_status"`          // 最近一次运行结果：0-未运行，1-通过，-1-失败
+	LastRunTime                 string `gorm:"comment:'最近运行时间'" json:"last_run_time"`                // 最近一次运行时间
+	Remark                      string `gorm:"comment:'备注'" json:"remark"`                           // 备注说明
+}
+
+// TableName 指定数据库表名
+func (SFlowTestCase) TableName() string {
+	return "sflow_test_case"
+}
+
+// Save 保存测试用例，校验JSON格式
+func (entity SFlowTestCase) Save(data *SFlowTestCase, columns ...string) error {
+	if data.SFlowId == 0 {
+		return errors.New("流程ID不能为空")
+	}
+	for name, text := range map[string]string{"输入参数": data.Params, "模拟节点": data.Mocks, "断言": data.Expect} {
+		if text == "" {
+			continue
+		}
+		var value map[string]any
+		if err := json.Unmarshal([]byte(text), &value); err != nil {
+			return fmt.Errorf("%s格式错误，应为JSON对象: %v", name, err)
+		}
+	}
+	return entity.BaseModel.Save(data, columns...)
+}
+
+// ListByFlow 查询流程启用的测试用例
+func (entity SFlowTestCase) ListByFlow(sflowID uint) ([]SFlowTestCase, error) {
+	list := make([]SFlowTestCase, 0)
+	err := global.DB.Model(&SFlowTestCase{}).
+		Where("sflow_id = ? and is_disable = 0", sflowID).
+		Order("id").Find(&list).Error
+	return list, err
+}
+
+// ListFlowIDs 查询有启用测试用例的流程ID
+func (entity SFlowTestCase) ListFlowIDs() ([]uint, error) {
+	ids := make([]uint, 0)
+	err := global.DB.Model(&SFlowTestCase{}).
+		Where("is_disable = 0").
+		Distinct("sflow_id").Order("sflow_id").Pluck("sflow_id", &ids).Error
+	return ids, err
+}
+
+// UpdateResult 记录测试用例的运行结果
+func (entity SFlowTestCase) UpdateResult(id uint, passed bool) error {
+	status := -1
+	if passed {
+		status = 1
+	}
+	return global.DB.Model(&SFlowTestCase{}).Where("id = ?", id).Updates(map[string]any{
+		"last_status":   status,
+		"last_run_time": time.Now().Format(db.TimeFormat),
+	}).Error
+}
@@ -0,0 +1,47 @@
+package sflow
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"server/core/db"
+	"server/utils/global"
+)
+
+// SFlowVersion 流程版本
+// 保存执行时使用的流程定义快照，相同内容只保存一份，用于按原版本回放执行
+type SFlowVersion struct {
+	ID        uint         `gorm:"primary_key" json:"id"`                                // 主键ID
+	SFlowId   uint         `gorm:"column:sflow_id;index;comment:'流程ID'" json:"sflow_id"` // 流程ID
+	Version   string       `gorm:"index;comment:'版本' size:64" json:"version"`            // 版本，流程定义内容的摘要
+	Content   string       `gorm:"comment:'流程定义' size:1048576" json:"content"`           // 流程定义快照(JSON)
+	CreatedAt db.LocalTime `gorm:"comment:'创建时间'" json:"created_at"`                     // 创建时间
+}
+
+// TableName 指定数据库表名
+func (SFlowVersion) TableName() string {
+	return "sflow_version"
+}
+
+// VersionOf 计算流程定义内容的版本
+func VersionOf(content string) string {
+	sum := sha256.Sum256([]byte(content))
+	return hex.EncodeToString(sum[:8])
+}
+
+// Ensure 保存流程版本，内容相同的版本已存在时不重复保存，返回版本
+func (entity SFlowVersion) Ensure(sflowID uint, content string) (string, error) {
+	version := VersionOf(content)
+	var count int64
+	err := global.DB.Model(&SFlowVersion{}).Where("sflow_id = ? and version = ?", sflowID, version).Count(&count).Error
+	if err != nil || count > 0 {
+		return version, err
+	}
+	entity = SFlowVersion{SFlowId: sflowID, Version: version, Content: content, CreatedAt: db.LocalTime{}.Now()}
+	return version, global.DB.Model(&entity).Create(&entity).Error
+}
+
+// Find 查询流程的指定版本
+func (entity SFlowVersion) Find(sflowID uint, version string) (SFlowVersion, error) {
+	err := global.DB.Model(&SFlowVersion{}).Where("sflow_id = ? and version = ?", sflowID, version).Take(&entity).Error
+	return entity, err
+}
@@ -0,0 +1,144 @@
+package sflow
+
+import (
+	"errors"
+	"server/core/app/request"
+	"server/core/db"
+	"server/utils/global"
+	"time"
+)
+
+// 等待节点类型
+const (
+	WaitApproval = "approval" // 人工审批
+	WaitSignal   = "signal"   // 等待外部信号
+)
+
+// 等待状态
+const (
+	WaitPending   = "waiting"   // 等待中
+	WaitApproved  = "approved"  // 已批准
+	WaitRejected  = "rejected"  // 已拒绝
+	WaitSignaled  = "signaled"  // 已收到信号
+	WaitTimedOut  = "timeout"   // 等待超时，已按默认动作处理
+	WaitCancelled = "cancelled" // 流程被取消，不再等待
+)
+
+// SFlowWait 流程等待记录
+// 审批节点和等待信号节点暂停执行时创建，保存执行状态，服务重启后据此恢复执行
+type SFlowWait struct {
+	ID            uint         `gorm:"primary_key" json:"id"`                                // 主键ID
+	SFlowId       uint         `gorm:"column:sflow_id;index;comment:'流程ID'" json:"sflow_id"` // 流程ID
+	ExecutionID   string       `gorm:"index;comment:'执行ID' size:64" json:"execution_id"`     // 执行ID
+	NodeID        string       `gorm:"comment:'节点ID' size:64" json:"node_id"`                // 节点ID
+	NodeName      string       `gorm:"comment:'节点名称'" json:"node_name"`                      // 节点名称
+	Kind          string       `gorm:"comment:'类型' size:20" json:"kind"`                     // 类型：approval、signal
+	SignalName    string       `gorm:"comment:'信号名称' size:128" json:"signal_name"`           // 等待的信号名称
+	Approvers     string       `gorm:"comment:'审批人ID，逗号分隔'" json:"approvers"`                // 允许审批的用户ID，为空时任何用户均可审批
+	Message       string       `gorm:"comment:'提示信息' size:1024" json:"message"`              // 展示给审批人的提示信息
+	Status        string       `gorm:"index;comment:'状态' size:20" json:"status"`             // 状态
+	DefaultAction string       `gorm:"comment:'超时默认动作' size:20" json:"default_action"`       // 超时后的默认动作
+	DeadlineAt    db.LocalTime `gorm:"comment:'超时时间'" json:"deadline_at"`                    // 超时时间，为空表示一直等待
+	Payload       string       `gorm:"comment:'信号数据' size:65535" json:"payload"`             // 信号携带的数据(JSON)
+	DecidedBy     uint         `gorm:"default:0;comment:'处理人'" json:"decided_by"`            // 审批人ID
+	Comment       string       `gorm:"comment:'审批意见' size:1024" json:"comment"`              // 审批意见
+	DecidedAt     db.LocalTime `gorm:"comment:'处理时间'" json:"decided_at"`                     // 处理时间
+	State         string       `gorm:"comment:'执行状态' size:1048576" json:"-"`                 // 暂停时的执行上下文(JSON)，用于重启后恢复
+	CreatedAt     db.LocalTime `gorm:"comment:'创建时间'" json:"created_at"`                     // 创建时间
+}
+
+// TableName 指定数据库表名
+func (SFlowWait) TableName() string {
+	return "sflow_wait"
+}
+
+// Create 创建等待记录
+func (entity *SFlowWait) Create() error {
+	entity.Status = WaitPending
+	entity.CreatedAt = db.LocalTime{}.Now()
+	return global.DB.Model(entity).Create(entity).Error
+}
+
+// HasDeadline 是否设置了超时时间
+func (entity SFlowWait) HasDeadline() bool {
+	return !entity.DeadlineAt.IsZero()
+}
+
+// Remaining 距离超时的剩余时间
+func (entity SFlowWait) Remaining() time.Duration {
+	return time.Until(entity.DeadlineAt.Time)
+}
+
+// Load 按主键查询等待记录
+func (entity SFlowWait) Load(id any) (SFlowWait, error) {
+	err := global.DB.Model(&SFlowWait{}).Take(&entity, id).Error
+	return entity, err
+}
+
+// FindByNode 查询执行中指定节点最近的等待记录，不存在时返回ID为0的记录
+func (entity SFlowWait) FindByNode(executionID, nodeID string) (SFlowWait, error) {
+	err := global.DB.Model(&SFlowWait{}).
+		Where("execution_id = ? and node_id = ?", executionID, nodeID).
+		Order("id desc").Limit(1).Find(&entity).Error
+	return entity, err
+}
+
+// FindSignal 查询执行中等待指定信号的记录
+func (entity SFlowWait) FindSignal(executionID, signal string) ([]SFlowWait, error) {
+	list := make([]SFlowWait, 0)
+	err := global.DB.Model(&SFlowWait{}).
+		Where("execution_id = ? and kind = ? and signal_name = ? and status = ?", executionID, WaitSignal, signal, WaitPending).
+		Find(&list).Error
+	return list, err
+}
+
+// ListWaiting 查询所有等待中的记录
+func (entity SFlowWait) ListWaiting() ([]SFlowWait, error) {
+	list := make([]SFlowWait, 0)
+	err := global.DB.Model(&SFlowWait{}).Where("status = ?", WaitPending).Order("id").Find(&list).Error
+	return list, err
+}
+
+// List 分页查询等待记录，不查询执行状态
+func (entity SFlowWait) List(query request.PageQuery) (list []SFlowWait, count int64, err error) {
+	modelDb := db.QueryWhere(global.DB.Model(&SFlowWait{}), query.Filters)
+	if err = modelDb.Count(&count).Error; err != nil {
+		return nil, 0, err
+	}
+	if query.Page < 1 {
+		query.Page = 1
+	}
+	if query.Size > 0 {
+		modelDb = modelDb.Limit(query.Size).Offset((query.Page - 1) * query.Size)
+	}
+	err = modelDb.Omit("state").Order("id desc").Find(&list).Error
+	return list, count, err
+}
+
+// UpdateState 更新执行中所有等待中记录保存的执行状态
+func (entity SFlowWait) UpdateState(executionID, state string) error {
+	return global.DB.Model(&SFlowWait{}).
+		Where("execution_id = ? and status = ?", executionID, WaitPending).
+		Update("state", state).Error
+}
+
+// Resolve 处理等待中的记录，记录已被处理时返回错误
+// 使用状态条件更新，保证同一记录只会被处理一次
+func (entity SFlowWait) Resolve(id uint, status string, decidedBy uint, comment, payload string) error {
+	result := global.DB.Model(&SFlowWait{}).
+		Where("id = ? and status = ?", id, WaitPending).
+		Updates(map[string]any{
+			"status":     status,
+			"decided_by": decidedBy,
+			"comment":    comment,
+			"payload":    payload,
+			"decided_at": db.LocalTime{}.Now(),
+		})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return errors.New("等待记录不存在或已被处理")
+	}
+	return nil
+}
@@ -6,6 +6,7 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"io"
 	"log"
 	"os"
 	"os/exec"
@@ -27,6 +28,9 @@ func Exec(cmdStr string) (string, error) {
 // ERR_CMD_TIMEOUT 命令超时错误的常量标识符
 const ERR_CMD_TIMEOUT = "ErrCmdTimeout"
 
+// ERR_CMD_CANCELED 命令被取消错误的常量标识符
+const ERR_CMD_CANCELED = "ErrCmdCanceled"
+
 // handleErr 处理命令执行错误，格式化输出和错误信息
 // 参数:
 //   - stdout: 标准输出缓冲区
@@ -110,44 +114,173 @@ func ExecContainerScript(containerName, cmdStr string, timeout time.Duration) er
 	return nil
 }
 
+// CronjobOptions 定时任务命令的执行选项
+type CronjobOptions struct {
+	Timeout time.Duration   // 超时时间，为0时不限制
+	Env     []string        // 追加的环境变量，格式为KEY=VALUE
+	Context context.Context // 取消时结束命令的整个进程组，为nil时不可取消；超过截止时间时按超时处理
+	Shell   string          // 解释器：sh、bash、python，为空时为sh，Windows下为cmd
+	User    string          // 执行命令的系统用户，仅Linux支持，为空时为当前用户
+	Limits  *Limits         // 资源限制，仅Linux支持，为nil时不限制
+	Usage   *Usage          // 不为nil时在命令结束后写入资源使用情况和未能应用的限制
+}
+
+// waitDelay 命令结束后等待输出管道关闭的最长时间
+const waitDelay = 5 * time.Second
+
+// 支持的解释器及执行脚本内容的参数
+var shells = map[string][]string{
+	"sh":     {"sh", "-c"},
+	"bash":   {"bash", "-c"},
+	"python": {"python3", "-c"},
+}
+
+// CheckShell 校验解释器是否支持，为空时使用默认解释器
+func CheckShell(shell string) error {
+	if _, ok := shells[shell]; shell != "" && !ok {
+		return fmt.Errorf("不支持的解释器: %s", shell)
+	}
+	return nil
+}
+
+// cronjobCommand 按解释器创建执行脚本内容的命令
+func cronjobCommand(cmdStr string, shell string) (*exec.Cmd, error) {
+	if shell == "" {
+		if runtime.GOOS == "windows" {
+			return exec.Command("cmd", "/c", cmdStr), nil
+		}
+		shell = "sh"
+	}
+	if err := CheckShell(shell); err != nil {
+		return nil, err
+	}
+	args := shells[shell]
+	return exec.Command(args[0], append(args[1:], cmdStr)...), nil
+}
+
+// startHookKey 上下文中进程启动回调的键
+type startHookKey struct{}
+
+// WithStartHook 返回携带进程启动回调的上下文，定时任务命令启动后以进程ID调用
+func WithStartHook(ctx context.Context, hook func(pid int)) context.Context {
+	return context.WithValue(ctx, startHookKey{}, hook)
+}
+
 // ExecCronjobWithTimeOut 执行定时任务并将输出重定向到指定文件
 // 参数:
 //   - cmdStr: 要执行的命令
 //   - workdir: 工作目录
 //   - outPath: 输出文件路径
-//   - timeout: 超时时间
+//   - opts: 执行选项，包括超时时间、上下文、环境变量、解释器和执行用户
 //
 // 返回:
 //   - error: 执行过程中的错误
-func ExecCronjobWithTimeOut(cmdStr, workdir, outPath string, timeout time.Duration) error {
+func ExecCronjobWithTimeOut(cmdStr, workdir, outPath string, opts CronjobOptions) error {
 	file, err := os.OpenFile(outPath, os.O_WRONLY|os.O_CREATE, 0666)
 	if err != nil {
 		return err
 	}
 	defer file.Close()
-	var cmd *exec.Cmd
-	if runtime.GOOS == "windows" {
-		cmd = exec.Command("cmd", "/c", cmdStr)
-	} else {
-		cmd = exec.Command("sh", "-c", cmdStr)
+	return ExecCronjob(cmdStr, workdir, file, opts)
+}
+
+// ExecCronjob 执行定时任务并将输出写入指定的写入器
+// 参数:
+//   - cmdStr: 要执行的命令
+//   - workdir: 工作目录
+//   - output: 标准输出和标准错误的写入器
+//   - opts: 执行选项
+//
+// 命令在独立的进程组中执行，超时或取消时结束整个进程组，包括命令启动的子进程
+//
+// 返回:
+//   - error: 执行过程中的错误，超时返回ERR_CMD_TIMEOUT错误，取消返回ERR_CMD_CANCELED错误
+func ExecCronjob(cmdStr, workdir string, output io.Writer, opts CronjobOptions) error {
+	cmd, err := cronjobCommand(cmdStr, opts.Shell)
+	if err != nil {
+		return err
 	}
 	cmd.Dir = workdir
-	cmd.Stdout = file
-	cmd.Stderr = file
-	if err := cmd.Start(); err != nil {
+	cmd.Stdout = output
+	cmd.Stderr = output
+	return runCronjob(cmd, opts)
+}
+
+// ExecCronjobArgs 以给定的命令名和参数执行定时任务命令，标准输出和标准错误分别写入不同的写入器
+// 参数:
+//   - name: 命令名称
+//   - args: 命令参数，不经过解释器，无需转义
+//   - stdout: 标准输出的写入器，如数据库导出的内容
+//   - stderr: 标准错误的写入器
+//   - opts: 执行选项，忽略其中的解释器
+//
+// 返回:
+//   - error: 执行过程中的错误，超时返回ERR_CMD_TIMEOUT错误，取消返回ERR_CMD_CANCELED错误
+func ExecCronjobArgs(name string, args []string, stdout, stderr io.Writer, opts CronjobOptions) error {
+	cmd := exec.Command(name, args...)
+	cmd.Stdout = stdout
+	cmd.Stderr = stderr
+	return runCronjob(cmd, opts)
+}
+
+// runCronjob 在独立的进程组中启动定时任务命令并等待结束
+// 按执行选项设置执行用户、环境变量和资源限制，超时或取消时结束整个进程组
+func runCronjob(cmd *exec.Cmd, opts CronjobOptions) error {
+	setProcessGroup(cmd)
+	// 输出不是文件时由读取管道的协程写入，脱离进程组的后台进程仍持有管道时，命令结束后最多再等待waitDelay
+	cmd.WaitDelay = waitDelay
+	if opts.User != "" {
+		// 设置执行用户及其HOME等环境变量
+		if err := setUser(cmd, opts.User); err != nil {
+			return err
+		}
+	}
+	if len(opts.Env) > 0 {
+		if cmd.Env == nil {
+			cmd.Env = os.Environ()
+		}
+		cmd.Env = append(cmd.Env, opts.Env...)
+	}
+	// 启动前准备资源限制，命令结束后统计资源使用
+	limiter := newLimiter(opts.Limits)
+	limiter.prepare(cmd)
+	defer limiter.finish(cmd, opts.Usage)
+	err := cmd.Start()
+	limiter.started()
+	if err != nil {
 		return err
 	}
+	var canceled <-chan struct{}
+	if opts.Context != nil {
+		canceled = opts.Context.Done()
+		if hook, ok := opts.Context.Value(startHookKey{}).(func(pid int)); ok {
+			hook(cmd.Process.Pid)
+		}
+	}
 	done := make(chan error, 1)
 	go func() {
 		done <- cmd.Wait()
 	}()
-	after := time.After(timeout)
+	// 超时时间为0时不限制执行时长
+	var after <-chan time.Time
+	if opts.Timeout > 0 {
+		after = time.After(opts.Timeout)
+	}
 	select {
 	case <-after:
-		_ = cmd.Process.Kill()
+		killProcessGroup(cmd)
+		<-done
 		return errors.New(ERR_CMD_TIMEOUT)
+	case <-canceled:
+		killProcessGroup(cmd)
+		<-done
+		if errors.Is(opts.Context.Err(), context.DeadlineExceeded) {
+			return errors.New(ERR_CMD_TIMEOUT)
+		}
+		return errors.New(ERR_CMD_CANCELED)
 	case err := <-done:
-		if err != nil {
+		// 命令已成功结束，只是后台进程的输出不再读取
+		if err != nil && !errors.Is(err, exec.ErrWaitDelay) {
 			return err
 		}
 	}
@@ -0,0 +1,52 @@
+package cmd
+
+import (
+	"fmt"
+	"time"
+)
+
+// Limits 定时任务命令的资源限制，仅Linux支持，为0的项不限制
+// 最长执行时间使用CronjobOptions.Timeout
+type Limits struct {
+	Nice       int // CPU调度优先级，-20到19，越大优先级越低，负数需要root权限
+	IOClass    int // IO调度类别：1实时 2尽力而为 3空闲
+	IOLevel    int // IO优先级，0到7，越小优先级越高，空闲类别时忽略
+	MemoryMB   int // 内存上限(MB)，cgroup v2可用时限制物理内存，否则限制虚拟内存
+	NoFile     int // 最多打开的文件数
+	CPUPercent int // CPU上限百分比，100为一个核，需要cgroup v2
+}
+
+// Usage 定时任务命令的资源使用情况
+type Usage struct {
+	PeakMemory int64         // 内存峰值(字节)，无法获取时为0
+	CPUTime    time.Duration // 用户态和内核态CPU时间
+	Cgroup     bool          // 是否在独立的cgroup中执行
+	Warnings   []string      // 未能应用的资源限制
+}
+
+// Check 校验资源限制的取值范围
+func (l Limits) Check() error {
+	if l.Nice < -20 || l.Nice > 19 {
+		return fmt.Errorf("CPU优先级应在-20到19之间: %d", l.Nice)
+	}
+	if l.IOClass < 0 || l.IOClass > 3 {
+		return fmt.Errorf("IO调度类别应为1实时、2尽力而为或3空闲: %d", l.IOClass)
+	}
+	if l.IOLevel < 0 || l.IOLevel > 7 {
+		return fmt.Errorf("IO优先级应在0到7之间: %d", l.IOLevel)
+	}
+	if l.MemoryMB < 0 || l.NoFile < 0 || l.CPUPercent < 0 {
+		return fmt.Errorf("内存、文件数和CPU上限不能为负数")
+	}
+	return nil
+}
+
+// IsZero 是否没有设置任何限制
+func (l Limits) IsZero() bool {
+	return l == Limits{}
+}
+
+// warn 记录未能应用的资源限制
+func (u *Usage) warn(format string, a ...any) {
+	u.Warnings = append(u.Warnings, fmt.Sprintf(format, a...))
+}
@@ -0,0 +1,253 @@
+//go:build linux
+
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"syscall"
+	"time"
+)
+
+const (
+	cgroupRoot   = "/sys/fs/cgroup" // cgroup v2挂载位置
+	cgroupParent = "minas"          // 定时任务cgroup的父目录
+	cpuPeriod    = 100000           // cpu.max的周期(微秒)
+)
+
+// cgroupSeq 生成cgroup名称的序号
+var cgroupSeq atomic.Int64
+
+// limiter 在命令启动前准备资源限制，结束后统计资源使用并清理cgroup
+type limiter struct {
+	limits   Limits
+	cgroup   string   // 本次执行的cgroup目录，未使用cgroup时为空
+	cgroupFD *os.File // 命令启动时直接加入的cgroup目录，启动后关闭
+	usage    Usage
+}
+
+// newLimiter 创建资源限制，limits为nil时只统计资源使用
+func newLimiter(limits *Limits) *limiter {
+	l := &limiter{}
+	if limits != nil {
+		l.limits = *limits
+	}
+	return l
+}
+
+// prepare 在命令启动前准备资源限制，限制在命令执行第一条指令前就已生效，命令创建的子进程都会继承
+// cgroup v2可用时命令直接在本次执行的cgroup中启动；打开文件数、虚拟内存、CPU和IO优先级由prlimit、nice、ionice包装命令在exec前设置
+func (l *limiter) prepare(cmd *exec.Cmd) {
+	if l.limits.IsZero() {
+		return
+	}
+	if l.limits.MemoryMB > 0 || l.limits.CPUPercent > 0 {
+		l.createCgroup()
+	}
+	if l.cgroupFD != nil {
+		if cmd.SysProcAttr == nil {
+			cmd.SysProcAttr = &syscall.SysProcAttr{}
+		}
+		cmd.SysProcAttr.UseCgroupFD = true
+		cmd.SysProcAttr.CgroupFD = int(l.cgroupFD.Fd())
+	}
+	l.wrap(cmd)
+}
+
+// started 命令启动后关闭cgroup目录
+func (l *limiter) started() {
+	if l.cgroupFD != nil {
+		l.cgroupFD.Close()
+		l.cgroupFD = nil
+	}
+}
+
+// wrap 按资源限制在命令前依次加上prlimit、nice和ionice，由它们设置后exec原命令
+// 找不到对应的命令时跳过该项限制并记录警告
+func (l *limiter) wrap(cmd *exec.Cmd) {
+	if cmd.Err != nil {
+		return
+	}
+	var prefix []string
+	var rlimits []string
+	if l.limits.NoFile > 0 {
+		rlimits = append(rlimits, l.rlimitArg("nofile", "打开文件数", syscall.RLIMIT_NOFILE, uint64(l.limits.NoFile)))
+	}
+	if l.limits.MemoryMB > 0 && l.cgroup == "" {
+		// cgroup不可用时限制虚拟内存
+		rlimits = append(rlimits, l.rlimitArg("as", "虚拟内存", syscall.RLIMIT_AS, uint64(l.limits.MemoryMB)<<20))
+		l.usage.warn("cgroup v2不可用，内存上限改为限制虚拟内存")
+	}
+	if l.limits.CPUPercent > 0 && l.cgroup == "" {
+		l.usage.warn("cgroup v2不可用，无法限制CPU使用率")
+	}
+	if len(rlimits) > 0 {
+		if path, ok := l.lookTool("prlimit", "打开文件数和内存"); ok {
+			prefix = append(append(append(prefix, path), rlimits...), "--")
+		}
+	}
+	if l.limits.Nice != 0 {
+		// 没有权限设置时nice输出警告后仍执行命令
+		if path, ok := l.lookTool("nice", "CPU优先级"); ok {
+			prefix = append(prefix, path, "-n", strconv.Itoa(l.limits.Nice), "--")
+		}
+	}
+	if l.limits.IOClass > 0 {
+		if path, ok := l.lookTool("ionice", "IO优先级"); ok {
+			// -t 没有权限设置时忽略错误继续执行命令，空闲类别没有优先级
+			prefix = append(prefix, path, "-t", "-c", strconv.Itoa(l.limits.IOClass))
+			if l.limits.IOClass != 3 {
+				prefix = append(prefix, "-n", strconv.Itoa(l.limits.IOLevel))
+			}
+			prefix = append(prefix, "--")
+		}
+	}
+	if len(prefix) == 0 {
+		return
+	}
+	cmd.Args = append(append(prefix, cmd.Path), cmd.Args[1:]...)
+	cmd.Path = prefix[0]
+}
+
+// rlimitArg 生成prlimit的参数，软上限和硬上限相同
+// 非root用户不能提高硬上限，超过时改为当前的硬上限
+func (l *limiter) rlimitArg(name, desc string, resource int, value uint64) string {
+	var current syscall.Rlimit
+	if err := syscall.Getrlimit(resource, &current); err == nil && value > current.Max && os.Geteuid() != 0 {
+		l.usage.warn("%s上限%d超过硬上限，改为%d", desc, value, current.Max)
+		value = current.Max
+	}
+	return fmt.Sprintf("--%s=%d:%d", name, value, value)
+}
+
+// lookTool 查找设置资源限制的命令
+func (l *limiter) lookTool(name, desc string) (string, bool) {
+	path, err := exec.LookPath(name)
+	if err != nil {
+		l.usage.warn("未找到%s命令，无法设置%s", name, desc)
+		return "", false
+	}
+	return path, true
+}
+
+// createCgroup cgroup v2可用时为本次执行创建独立的cgroup并设置CPU和内存上限
+// 打开cgroup目录供命令启动时直接加入，需要Linux 5.7及以上版本
+func (l *limiter) createCgroup() {
+	if _, err := os.Stat(filepath.Join(cgroupRoot, "cgroup.controllers")); err != nil {
+		return
+	}
+	if !cloneIntoCgroup() {
+		l.usage.warn("内核版本低于5.7，无法在cgroup中启动命令")
+		return
+	}
+	parent := filepath.Join(cgroupRoot, cgroupParent)
+	if err := os.MkdirAll(parent, 0755); err != nil {
+		l.usage.warn("无法创建cgroup: %v", err)
+		return
+	}
+	// 启用父目录的cpu和memory控制器，已启用时写入不会报错
+	for _, dir := range []string{cgroupRoot, parent} {
+		if err := os.WriteFile(filepath.Join(dir, "cgroup.subtree_control"), []byte("+cpu +memory"), 0644); err != nil {
+			l.usage.warn("无法启用cgroup控制器: %v", err)
+			return
+		}
+	}
+	dir := filepath.Join(parent, fmt.Sprintf("job-%d-%d", os.Getpid(), cgroupSeq.Add(1)))
+	if err := os.Mkdir(dir, 0755); err != nil {
+		l.usage.warn("无法创建cgroup: %v", err)
+		return
+	}
+	if l.limits.MemoryMB > 0 {
+		if err := os.WriteFile(filepath.Join(dir, "memory.max"), []byte(strconv.Itoa(l.limits.MemoryMB<<20)), 0644); err != nil {
+			l.usage.warn("无法设置cgroup内存上限: %v", err)
+		}
+	}
+	if l.limits.CPUPercent > 0 {
+		quota := fmt.Sprintf("%d %d", l.limits.CPUPercent*cpuPeriod/100, cpuPeriod)
+		if err := os.WriteFile(filepath.Join(dir, "cpu.max"), []byte(quota), 0644); err != nil {
+			l.usage.warn("无法设置cgroup CPU上限: %v", err)
+		}
+	}
+	fd, err := os.Open(dir)
+	if err != nil {
+		l.usage.warn("无法打开cgroup: %v", err)
+		os.Remove(dir)
+		return
+	}
+	l.cgroup = dir
+	l.cgroupFD = fd
+	l.usage.Cgroup = true
+}
+
+// cloneIntoCgroup 内核是否支持创建进程时直接加入cgroup（Linux 5.7）
+func cloneIntoCgroup() bool {
+	var uts syscall.Utsname
+	if err := syscall.Uname(&uts); err != nil {
+		return false
+	}
+	release := make([]byte, 0, len(uts.Release))
+	for _, c := range uts.Release {
+		if c == 0 {
+			break
+		}
+		release = append(release, byte(c))
+	}
+	var major, minor int
+	fmt.Sscanf(string(release), "%d.%d", &major, &minor)
+	return major > 5 || major == 5 && minor >= 7
+}
+
+// finish 命令结束后统计资源使用并删除cgroup
+// 使用cgroup时读取memory.peak和cpu.stat，否则使用命令进程及其已回收子进程的rusage
+func (l *limiter) finish(cmd *exec.Cmd, usage *Usage) {
+	if cmd.ProcessState != nil {
+		l.usage.CPUTime = cmd.ProcessState.UserTime() + cmd.ProcessState.SystemTime()
+		if rusage, ok := cmd.ProcessState.SysUsage().(*syscall.Rusage); ok {
+			l.usage.PeakMemory = rusage.Maxrss * 1024
+		}
+	}
+	if l.cgroup != "" {
+		if peak, err := readInt(filepath.Join(l.cgroup, "memory.peak")); err == nil {
+			l.usage.PeakMemory = peak
+		}
+		if stat, err := os.ReadFile(filepath.Join(l.cgroup, "cpu.stat")); err == nil {
+			for _, line := range strings.Split(string(stat), "\n") {
+				if value, ok := strings.CutPrefix(line, "usage_usec "); ok {
+					if usec, err := strconv.ParseInt(value, 10, 64); err == nil {
+						l.usage.CPUTime = time.Duration(usec) * time.Microsecond
+					}
+				}
+			}
+		}
+		l.removeCgroup()
+	}
+	if usage != nil {
+		*usage = l.usage
+	}
+}
+
+// removeCgroup 删除本次执行的cgroup，进程结束后cgroup可能需要短暂时间才能删除
+func (l *limiter) removeCgroup() {
+	var err error
+	for i := 0; i < 10; i++ {
+		if err = os.Remove(l.cgroup); err == nil || os.IsNotExist(err) {
+			return
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+	l.usage.warn("无法删除cgroup %s，可能有后台进程仍在运行: %v", l.cgroup, err)
+}
+
+// readInt 读取cgroup文件中的整数
+func readInt(path string) (int64, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return 0, err
+	}
+	return strconv.ParseInt(strings.TrimSpace(string(data)), 10, 64)
+}
@@ -0,0 +1,40 @@
+//go:build !linux
+
+package cmd
+
+import "os/exec"
+
+// limiter 非Linux系统不支持资源限制，只统计CPU时间
+type limiter struct {
+	limits Limits
+	usage  Usage
+}
+
+// newLimiter 创建资源限制，limits为nil时只统计资源使用
+func newLimiter(limits *Limits) *limiter {
+	l := &limiter{}
+	if limits != nil {
+		l.limits = *limits
+	}
+	return l
+}
+
+// prepare 非Linux系统设置了资源限制时记录警告
+func (l *limiter) prepare(cmd *exec.Cmd) {
+	if !l.limits.IsZero() {
+		l.usage.warn("当前系统不支持资源限制，仅Linux支持")
+	}
+}
+
+// started 命令启动后无需处理
+func (l *limiter) started() {}
+
+// finish 命令结束后统计CPU时间
+func (l *limiter) finish(cmd *exec.Cmd, usage *Usage) {
+	if cmd.ProcessState != nil {
+		l.usage.CPUTime = cmd.ProcessState.UserTime() + cmd.ProcessState.SystemTime()
+	}
+	if usage != nil {
+		*usage = l.usage
+	}
+}
@@ -0,0 +1,76 @@
+//go:build !windows
+
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"os/user"
+	"strconv"
+	"syscall"
+)
+
+// setProcessGroup 使命令在新的进程组中执行，便于结束时连同子进程一起结束
+func setProcessGroup(cmd *exec.Cmd) {
+	if cmd.SysProcAttr == nil {
+		cmd.SysProcAttr = &syscall.SysProcAttr{}
+	}
+	cmd.SysProcAttr.Setpgid = true
+}
+
+// lookupUser 查询系统用户的用户ID和组ID
+func lookupUser(name string) (*user.User, uint32, uint32, error) {
+	u, err := user.Lookup(name)
+	if err != nil {
+		return nil, 0, 0, fmt.Errorf("执行用户 %s 不存在: %v", name, err)
+	}
+	uid, err := strconv.ParseUint(u.Uid, 10, 32)
+	if err != nil {
+		return nil, 0, 0, err
+	}
+	gid, err := strconv.ParseUint(u.Gid, 10, 32)
+	if err != nil {
+		return nil, 0, 0, err
+	}
+	return u, uint32(uid), uint32(gid), nil
+}
+
+// CheckUser 校验执行命令的系统用户是否存在
+func CheckUser(name string) error {
+	_, _, _, err := lookupUser(name)
+	return err
+}
+
+// ChownUser 将文件或目录的所有者改为指定用户，用于以其他用户执行时的临时目录
+func ChownUser(path, name string) error {
+	_, uid, gid, err := lookupUser(name)
+	if err != nil {
+		return err
+	}
+	return os.Chown(path, int(uid), int(gid))
+}
+
+// setUser 以指定的系统用户执行命令，并设置该用户的HOME、USER和LOGNAME环境变量
+func setUser(cmd *exec.Cmd, name string) error {
+	u, uid, gid, err := lookupUser(name)
+	if err != nil {
+		return err
+	}
+	if cmd.SysProcAttr == nil {
+		cmd.SysProcAttr = &syscall.SysProcAttr{}
+	}
+	cmd.SysProcAttr.Credential = &syscall.Credential{Uid: uid, Gid: gid}
+	if cmd.Env == nil {
+		cmd.Env = os.Environ()
+	}
+	cmd.Env = append(cmd.Env, "HOME="+u.HomeDir, "USER="+u.Username, "LOGNAME="+u.Username)
+	return nil
+}
+
+// killProcessGroup 结束命令所在的整个进程组
+func killProcessGroup(cmd *exec.Cmd) {
+	if err := syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL); err != nil {
+		_ = cmd.Process.Kill()
+	}
+}
@@ -0,0 +1,37 @@
+//go:build windows
+
+package cmd
+
+import (
+	"errors"
+	"os/exec"
+	"strconv"
+)
+
+// errUserUnsupported Windows下不支持指定执行用户
+var errUserUnsupported = errors.New("Windows下不支持指定执行用户")
+
+// setProcessGroup Windows下使用taskkill结束进程树，无需设置进程组
+func setProcessGroup(cmd *exec.Cmd) {}
+
+// killProcessGroup 结束命令的整个进程树
+func killProcessGroup(cmd *exec.Cmd) {
+	if err := exec.Command("taskkill", "/T", "/F", "/PID", strconv.Itoa(cmd.Process.Pid)).Run(); err != nil {
+		_ = cmd.Process.Kill()
+	}
+}
+
+// CheckUser Windows下不支持指定执行用户
+func CheckUser(name string) error {
+	return errUserUnsupported
+}
+
+// ChownUser Windows下不支持指定执行用户
+func ChownUser(path, name string) error {
+	return errUserUnsupported
+}
+
+// setUser Windows下不支持指定执行用户
+func setUser(cmd *exec.Cmd, name string) error {
+	return errUserUnsupported
+}
@@ -66,6 +66,23 @@ type AppConfig struct {
 		CmdTimeOut uint   `mapstructure:"cmd-timeout" json:"cmdTimeout" yaml:"cmdTimeout"`  // RClone命令超时时间
 	} `mapstructure:"rclone" json:"rclone" yaml:"rclone"` // RClone相关配置
 
+	DagFlow struct {
+		ArtifactStore     string `mapstructure:"artifact-store" json:"artifactStore" yaml:"artifact-store"`             // 制品存储位置，为空时保存在工作目录，否则为外部存储的rclone标识
+		ArtifactPath      string `mapstructure:"artifact-path" json:"artifactPath" yaml:"artifact-path"`                // 外部存储中保存制品的目录
+		ArtifactThreshold int    `mapstructure:"artifact-threshold" json:"artifactThreshold" yaml:"artifact-threshold"` // 节点结果超过该大小(KB)时转存为制品，0使用默认值256，-1不转存
+		ArtifactRetention int    `mapstructure:"artifact-retention" json:"artifactRetention" yaml:"artifact-retention"` // 制品保留天数，0使用默认值7
+	} `mapstructure:"dagflow" json:"dagflow" yaml:"dagflow"` // DAGFlow相关配置
+
+	JobLog struct {
+		KeepDays int `mapstructure:"keep-days" json:"keepDays" yaml:"keep-days"` // 计划任务和流程日志保留天数，0不限制
+		MaxSize  int `mapstructure:"max-size" json:"maxSize" yaml:"max-size"`    // 日志文件总大小上限(MB)，超出时删除较早的日志，0不限制
+	} `mapstructure:"job-log" json:"jobLog" yaml:"job-log"` // 执行日志保留相关配置
+
+	Cluster struct {
+		Enable bool   `mapstructure:"enable" json:"enable" yaml:"enable"`   // 强制启用集群调度，使用MySQL/PostgreSQL或Redis时自动启用
+		NodeId string `mapstructure:"node-id" json:"nodeId" yaml:"node-id"` // 节点标识，为空时使用主机名
+	} `mapstructure:"cluster" json:"cluster" yaml:"cluster"` // 集群调度相关配置
+
 	Md5 struct {
 		Hash string `mapstructure:"hash" json:"hash" yaml:"hash"` // MD5哈希值
 	} `mapstructure:"md5" json:"md5" yaml:"md5"` // MD5相关配置
@@ -4,17 +4,29 @@ import (
 	"fmt"
 	"server/utils/global"
 	"server/utils/logger"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/robfig/cron/v3"
 )
 
 // SchJob 定时任务结构体
 type SchJob struct {
-	Id   string   // 任务唯一标识
-	Cron string   // 定时表达式，如 "0 0 * * *" 表示每天零点执行
-	Job  cron.Job // 实际执行的任务
+	Id       string   // 任务唯一标识
+	Cron     string   // 定时表达式，如 "0 0 * * *" 表示每天零点执行
+	Timezone string   // 时区，如 "Asia/Shanghai"，为空时使用服务器时区
+	Job      cron.Job // 实际执行的任务
 }
 
+// parser 与调度器一致的表达式解析器，支持秒级的6段表达式和@daily等描述符
+var parser = cron.NewParser(cron.Second | cron.Minute | cron.Hour | cron.Dom | cron.Month | cron.Dow | cron.Descriptor)
+
+// OnRun 定时任务被调度执行时的回调，参数为任务标识、本次调度的计划时间和执行任务的函数
+// 设置后由回调决定是否调用run执行任务，用于多节点协调和更新上次、下次执行时间
+var OnRun func(id string, scheduled time.Time, run func())
+
 // init 初始化函数，在包被导入时自动执行
 func init() {
 	// 创建一个新的定时任务调度器
@@ -28,10 +40,20 @@ func init() {
 // mapCron 用于存储已添加的定时任务，键为任务ID，值为用户定义的任务标识
 var mapCron = make(map[cron.EntryID]string)
 
+// cronLock 保护mapCron，任务执行时的回调会并发读取
+var cronLock sync.RWMutex
+
 // HashCron 检查指定ID的任务是否已存在
 // 参数 id: 任务标识
 // 返回值: 若任务已存在则返回true，否则返回false
 func HashCron(id any) bool {
+	cronLock.RLock()
+	defer cronLock.RUnlock()
+	return hashCron(id)
+}
+
+// hashCron 检查指定ID的任务是否已存在，调用方需持有cronLock
+func hashCron(id any) bool {
 	for ei := range mapCron {
 		if mapCron[ei] == fmt.Sprint(id) {
 			return true
@@ -54,12 +76,31 @@ func Start(jobs []SchJob) error {
 // 参数 job: 要添加的定时任务
 // 返回值: 错误信息
 func AddJobTask(job SchJob) error {
+	cronLock.Lock()
+	defer cronLock.Unlock()
 	//防止任务添加重复
-	if !HashCron(job.Id) && job.Cron != "" {
-		cid, err := global.Cron.AddJob(job.Cron, job.Job)
+	if !hashCron(job.Id) && job.Cron != "" {
+		if _, err := Parse(job.Cron, job.Timezone); err != nil {
+			return err
+		}
+		id, run := job.Id, job.Job
+		var entryID atomic.Int64
+		cid, err := global.Cron.AddJob(Spec(job.Cron, job.Timezone), cron.FuncJob(func() {
+			if OnRun == nil {
+				run.Run()
+				return
+			}
+			// 调度器触发执行时已将计划时间记录为上次执行时间
+			scheduled := global.Cron.Entry(cron.EntryID(entryID.Load())).Prev
+			if scheduled.IsZero() {
+				scheduled = time.Now().Truncate(time.Second)
+			}
+			OnRun(id, scheduled, run.Run)
+		}))
 		if err != nil {
 			return err
 		}
+		entryID.Store(int64(cid))
 		mapCron[cid] = job.Id
 		logger.LOG.Debugf("AddJobID:%v\n", cid)
 	}
@@ -69,6 +110,8 @@ func AddJobTask(job SchJob) error {
 // RemoveJobTask 删除指定标识的定时调度任务
 // 参数 id: 任务标识
 func RemoveJobTask(id any) {
+	cronLock.Lock()
+	defer cronLock.Unlock()
 	keys := []cron.EntryID{}
 	for ei := range mapCron {
 		if mapCron[ei] == fmt.Sprint(id) {
@@ -93,3 +136,74 @@ func UpdateJobTask(job SchJob) error {
 	RemoveJobTask(job.Id)
 	return AddJobTask(job)
 }
+
+// Spec 生成带时区的调度表达式，时区为空时使用服务器时区
+func Spec(expr, timezone string) string {
+	expr = strings.TrimSpace(expr)
+	if timezone == "" {
+		return expr
+	}
+	return "CRON_TZ=" + timezone + " " + expr
+}
+
+// Parse 校验并解析调度表达式
+// 参数 expr: 6段表达式（秒 分 时 日 月 周）或@daily、@every 1h等描述符
+// 参数 timezone: 时区，为空时使用服务器时区
+func Parse(expr, timezone string) (cron.Schedule, error) {
+	expr = strings.TrimSpace(expr)
+	if expr == "" {
+		return nil, fmt.Errorf("调度表达式不能为空")
+	}
+	if strings.HasPrefix(expr, "TZ=") || strings.HasPrefix(expr, "CRON_TZ=") {
+		return nil, fmt.Errorf("调度表达式中不能包含时区，请单独设置时区")
+	}
+	if timezone != "" {
+		if _, err := time.LoadLocation(timezone); err != nil {
+			return nil, fmt.Errorf("时区 %s 无效: %v", timezone, err)
+		}
+	}
+	schedule, err := parser.Parse(Spec(expr, timezone))
+	if err != nil {
+		return nil, fmt.Errorf("调度表达式 %s 无效: %v", expr, err)
+	}
+	return schedule, nil
+}
+
+// NextTimes 计算调度表达式从from开始的后n次执行时间
+// 时间使用表达式的时区，表达式不会再执行时返回的数量少于n
+func NextTimes(expr, timezone string, from time.Time, n int) ([]time.Time, error) {
+	schedule, err := Parse(expr, timezone)
+	if err != nil {
+		return nil, err
+	}
+	if timezone != "" {
+		loc, _ := time.LoadLocation(timezone)
+		from = from.In(loc)
+	}
+	times := make([]time.Time, 0, n)
+	next := from
+	for i := 0; i < n; i++ {
+		next = schedule.Next(next)
+		if next.IsZero() {
+			break
+		}
+		times = append(times, next)
+	}
+	return times, nil
+}
+
+// Next 获取指定标识的任务在调度器中的下次和上次执行时间
+// 返回值: 任务不在调度器中时ok为false，还没有执行过时prev为零值
+func Next(id any) (next, prev time.Time, ok bool) {
+	cronLock.RLock()
+	defer cronLock.RUnlock()
+	for ei := range mapCron {
+		if mapCron[ei] == fmt.Sprint(id) {
+			entry := global.Cron.Entry(ei)
+			if entry.Valid() {
+				return entry.Next, entry.Prev, true
+			}
+		}
+	}
+	return time.Time{}, time.Time{}, false
+}
@@ -0,0 +1,282 @@
+package cron
+
+// describe.go
+// 该文件实现了调度表达式的中英文描述
+// 支持6段表达式（秒 分 时 日 月 周）和@daily、@every 1h等描述符
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Description 调度表达式的中英文描述
+type Description struct {
+	Zh string `json:"zh"` // 中文描述
+	En string `json:"en"` // 英文描述
+}
+
+// Preview 调度表达式的预览结果
+type Preview struct {
+	Expr        string      `json:"expr"`        // 调度表达式
+	Timezone    string      `json:"timezone"`    // 时区，为空时为服务器时区
+	Description Description `json:"description"` // 中英文描述
+	NextTimes   []string    `json:"next_times"`  // 后续的执行时间
+}
+
+// 预览的执行次数限制
+const (
+	DefaultPreviewCount = 5  // 默认预览次数
+	MaxPreviewCount     = 50 // 最多预览次数
+)
+
+// field 表达式中一个字段的单位和取值描述
+type field struct {
+	zhUnit  string              // 中文单位，如“秒”“分钟”
+	enUnit  string              // 英文单位，如“second”
+	zhValue func(string) string // 单个取值的中文描述
+	enValue func(string) string // 单个取值的英文描述
+}
+
+var monthNames = []string{"", "January", "February", "March", "April", "May", "June", "July", "August", "September", "October", "November", "December"}
+var weekNames = []string{"Sunday", "Monday", "Tuesday", "Wednesday", "Thursday", "Friday", "Saturday"}
+var zhWeekNames = []string{"日", "一", "二", "三", "四", "五", "六"}
+
+// nameIndex 将月份或星期的英文缩写转换为数字，如jan为1、mon为1
+func nameIndex(value string, names []string) string {
+	for i, name := range names {
+		if name != "" && strings.EqualFold(value, name[:3]) {
+			return strconv.Itoa(i)
+		}
+	}
+	return value
+}
+
+var (
+	secondField = field{"秒", "second",
+		func(v string) string { return "第" + v + "秒" },
+		func(v string) string { return "second " + v }}
+	minuteField = field{"分钟", "minute",
+		func(v string) string { return "第" + v + "分钟" },
+		func(v string) string { return "minute " + v }}
+	hourField = field{"小时", "hour",
+		func(v string) string { return v + "点" },
+		func(v string) string { return "hour " + v }}
+	domField = field{"天", "day",
+		func(v string) string { return v + "号" },
+		func(v string) string { return "day " + v }}
+	monthField = field{"个月", "month",
+		func(v string) string { return nameIndex(v, monthNames) + "月" },
+		func(v string) string {
+			if i, err := strconv.Atoi(nameIndex(v, monthNames)); err == nil && i >= 1 && i <= 12 {
+				return monthNames[i]
+			}
+			return v
+		}}
+	dowField = field{"天", "day",
+		func(v string) string {
+			if i, err := strconv.Atoi(nameIndex(v, weekNames)); err == nil && i >= 0 && i <= 7 {
+				return "周" + zhWeekNames[i%7]
+			}
+			return v
+		},
+		func(v string) string {
+			if i, err := strconv.Atoi(nameIndex(v, weekNames)); err == nil && i >= 0 && i <= 7 {
+				return weekNames[i%7]
+			}
+			return v
+		}}
+)
+
+// isAny 字段是否为任意值
+func isAny(value string) bool {
+	return value == "*" || value == "?"
+}
+
+// isNumber 字段是否为单个数字
+func isNumber(value string) bool {
+	_, err := strconv.Atoi(value)
+	return err == nil
+}
+
+// describe 描述一个字段的取值，支持*、a、a-b、a/n、*/n、a-b/n和逗号分隔的列表
+func (f field) describe(value string) (string, string) {
+	if isAny(value) {
+		if f.zhUnit == "个月" {
+			return "每月", "every month"
+		}
+		return "每" + f.zhUnit, "every " + f.enUnit
+	}
+	items := strings.Split(value, ",")
+	zhs := make([]string, 0, len(items))
+	ens := make([]string, 0, len(items))
+	for _, item := range items {
+		zh, en := f.describeItem(item)
+		zhs = append(zhs, zh)
+		ens = append(ens, en)
+	}
+	return strings.Join(zhs, "、"), strings.Join(ens, ", ")
+}
+
+// describeItem 描述列表中的一项
+func (f field) describeItem(item string) (string, string) {
+	rangePart, step, hasStep := strings.Cut(item, "/")
+	start, end, hasRange := strings.Cut(rangePart, "-")
+	if !hasStep {
+		if hasRange {
+			return f.zhValue(start) + "至" + f.zhValue(end), f.enValue(start) + " through " + f.enValue(end)
+		}
+		return f.zhValue(item), f.enValue(item)
+	}
+	zhEvery := "每" + step + f.zhUnit
+	enEvery := "every " + step + " " + f.enUnit + "s"
+	switch {
+	case isAny(rangePart):
+		return zhEvery, enEvery
+	case hasRange:
+		return f.zhValue(start) + "至" + f.zhValue(end) + zhEvery,
+			enEvery + " from " + f.enValue(start) + " through " + f.enValue(end)
+	default:
+		return "从" + f.zhValue(start) + "开始" + zhEvery, enEvery + " starting at " + f.enValue(start)
+	}
+}
+
+// Describe 生成调度表达式的中英文描述，表达式无效时返回错误
+func Describe(expr string) (Description, error) {
+	if _, err := Parse(expr, ""); err != nil {
+		return Description{}, err
+	}
+	expr = strings.TrimSpace(expr)
+	if strings.HasPrefix(expr, "@") {
+		return describeDescriptor(expr), nil
+	}
+	fields := strings.Fields(expr)
+	zhTime, enTime := describeTime(fields[0], fields[1], fields[2])
+	zhDate, enDate := describeDate(fields[3], fields[4], fields[5])
+	if zhDate == "" && isNumber(fields[0]) && isNumber(fields[1]) && isNumber(fields[2]) {
+		zhDate, enDate = "每天", "every day"
+	}
+	desc := Description{Zh: zhTime, En: enTime}
+	if zhDate != "" {
+		desc.Zh, desc.En = zhDate+" "+zhTime, enTime+", "+enDate
+	}
+	desc.En = strings.ToUpper(desc.En[:1]) + desc.En[1:]
+	return desc, nil
+}
+
+// describeDescriptor 描述@daily、@every 1h等描述符
+func describeDescriptor(expr string) Description {
+	switch expr {
+	case "@yearly", "@annually":
+		return Description{"每年1月1号 00:00:00", "At 00:00:00 on January 1 every year"}
+	case "@monthly":
+		return Description{"每月1号 00:00:00", "At 00:00:00 on day 1 of every month"}
+	case "@weekly":
+		return Description{"每周日 00:00:00", "At 00:00:00 every Sunday"}
+	case "@daily", "@midnight":
+		return Description{"每天 00:00:00", "At 00:00:00 every day"}
+	case "@hourly":
+		return Description{"每小时整点", "At the start of every hour"}
+	}
+	interval := strings.TrimSpace(strings.TrimPrefix(expr, "@every"))
+	if d, err := time.ParseDuration(interval); err == nil {
+		interval = d.String()
+	}
+	return Description{"每隔" + interval, "Every " + interval}
+}
+
+// describeTime 描述时、分、秒字段
+// 都是单个数字时描述为时刻，否则从第一个不是任意值的字段开始逐级描述
+func describeTime(second, minute, hour string) (string, string) {
+	if isNumber(second) && isNumber(minute) && isNumber(hour) {
+		s, _ := strconv.Atoi(second)
+		m, _ := strconv.Atoi(minute)
+		h, _ := strconv.Atoi(hour)
+		clock := fmt.Sprintf("%02d:%02d:%02d", h, m, s)
+		return clock, "at " + clock
+	}
+	values := []string{hour, minute, second}
+	fields := []field{hourField, minuteField, secondField}
+	first := 0
+	for first < len(values) && isAny(values[first]) {
+		first++
+	}
+	if first == len(values) {
+		return "每秒", "every second"
+	}
+	// 单个取值、范围或列表需要上一级的任意值说明周期，如“每小时的第5分钟”
+	if first > 0 && !strings.HasPrefix(values[first], "*/") {
+		first--
+	}
+	zhs := make([]string, 0, len(values))
+	ens := make([]string, 0, len(values))
+	for i := first; i < len(values); i++ {
+		zh, en := fields[i].describe(values[i])
+		zhs = append(zhs, zh)
+		ens = append([]string{en}, ens...)
+	}
+	return strings.Join(zhs, "的"), strings.Join(ens, " of ")
+}
+
+// describeDate 描述日、月、周字段，日和周都有限定时满足其一即执行
+// 都是任意值时返回空字符串
+func describeDate(dom, month, dow string) (string, string) {
+	if isAny(dom) && isAny(month) && isAny(dow) {
+		return "", ""
+	}
+	zh, ens := "", make([]string, 0, 3)
+	if !isAny(month) {
+		z, e := monthField.describe(month)
+		zh += z
+		ens = append(ens, "in "+e)
+	} else if !isAny(dom) {
+		zh += "每月"
+	}
+	if !isAny(dom) {
+		z, e := domField.describe(dom)
+		zh += z
+		ens = append([]string{"on " + e + " of the month"}, ens...)
+	}
+	if !isAny(dow) {
+		z, e := dowField.describe(dow)
+		if !isAny(dom) {
+			zh += "或"
+			ens[0] += " or " + e
+		} else {
+			if isAny(month) {
+				zh += "每"
+			}
+			zh += z
+			ens = append([]string{"on " + e}, ens...)
+			return zh, strings.Join(ens, " ")
+		}
+		zh += z
+	} else if isAny(dom) {
+		zh += "每天"
+		ens = append([]string{"every day"}, ens...)
+	}
+	return zh, strings.Join(ens, " ")
+}
+
+// NewPreview 校验调度表达式，生成描述并计算从now开始的后n次执行时间
+// n不大于0时使用默认次数，最多计算MaxPreviewCount次
+func NewPreview(expr, timezone string, n int) (Preview, error) {
+	if n <= 0 {
+		n = DefaultPreviewCount
+	}
+	n = min(n, MaxPreviewCount)
+	desc, err := Describe(expr)
+	if err != nil {
+		return Preview{}, err
+	}
+	times, err := NextTimes(expr, timezone, time.Now(), n)
+	if err != nil {
+		return Preview{}, err
+	}
+	preview := Preview{Expr: strings.TrimSpace(expr), Timezone: timezone, Description: desc, NextTimes: make([]string, 0, len(times))}
+	for _, t := range times {
+		preview.NextTimes = append(preview.NextTimes, t.Format("2006-01-02 15:04:05 MST"))
+	}
+	return preview, nil
+}
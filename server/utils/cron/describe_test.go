@@ -0,0 +1,131 @@
+package cron
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestDescribe 测试6段表达式和描述符的中英文描述
+func TestDescribe(t *testing.T) {
+	tests := []struct {
+		name string
+		expr string
+		zh   string
+		en   string
+	}{
+		{"每天固定时刻", "0 0 3 * * *", "每天 03:00:00", "At 03:00:00, every day"},
+		{"前后空白", "  0 0 3 * * *  ", "每天 03:00:00", "At 03:00:00, every day"},
+		{"每秒", "* * * * * *", "每秒", "Every second"},
+		{"秒步长", "*/10 * * * * *", "每10秒", "Every 10 seconds"},
+		{"分钟步长", "0 */5 * * * *", "每5分钟的第0秒", "Second 0 of every 5 minutes"},
+		{"带起点的步长", "0 5/15 * * * *", "每小时的从第5分钟开始每15分钟的第0秒", "Second 0 of every 15 minutes starting at minute 5 of every hour"},
+		{"范围内的步长", "0 0 9-17/2 * * *", "9点至17点每2小时的第0分钟的第0秒", "Second 0 of minute 0 of every 2 hours from hour 9 through hour 17"},
+		{"单个分钟", "0 5 * * * *", "每小时的第5分钟的第0秒", "Second 0 of minute 5 of every hour"},
+		{"小时范围", "0 0 9-17 * * *", "9点至17点的第0分钟的第0秒", "Second 0 of minute 0 of hour 9 through hour 17"},
+		{"固定小时的每分钟", "0 * 3 * * *", "3点的每分钟的第0秒", "Second 0 of every minute of hour 3"},
+		{"列表", "0 0,30 8,20 * * *", "8点、20点的第0分钟、第30分钟的第0秒", "Second 0 of minute 0, minute 30 of hour 8, hour 20"},
+		{"星期范围", "30 15 10 * * 1-5", "每周一至周五 10:15:30", "At 10:15:30, on Monday through Friday"},
+		{"星期名称列表", "0 0 0 * * mon,fri", "每周一、周五 00:00:00", "At 00:00:00, on Monday, Friday"},
+		{"问号表示任意值", "0 0 12 ? * sun", "每周日 12:00:00", "At 12:00:00, on Sunday"},
+		{"每月固定日", "0 0 0 1 * *", "每月1号 00:00:00", "At 00:00:00, on day 1 of the month"},
+		{"月份名称和日期列表", "0 0 0 1,15 jan,jul *", "1月、7月1号、15号 00:00:00", "At 00:00:00, on day 1, day 15 of the month in January, July"},
+		{"月份范围", "0 0 0 * 2-4 *", "2月至4月每天 00:00:00", "At 00:00:00, every day in February through April"},
+		{"日期或星期", "0 0 8 1 * 1", "每月1号或周一 08:00:00", "At 08:00:00, on day 1 of the month or Monday"},
+		{"@daily", "@daily", "每天 00:00:00", "At 00:00:00 every day"},
+		{"@midnight", "@midnight", "每天 00:00:00", "At 00:00:00 every day"},
+		{"@weekly", "@weekly", "每周日 00:00:00", "At 00:00:00 every Sunday"},
+		{"@monthly", "@monthly", "每月1号 00:00:00", "At 00:00:00 on day 1 of every month"},
+		{"@yearly", "@yearly", "每年1月1号 00:00:00", "At 00:00:00 on January 1 every year"},
+		{"@hourly", "@hourly", "每小时整点", "At the start of every hour"},
+		{"@every", "@every 90m", "每隔1h30m0s", "Every 1h30m0s"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			desc, err := Describe(tt.expr)
+			require.NoError(t, err)
+			assert.Equal(t, tt.zh, desc.Zh)
+			assert.Equal(t, tt.en, desc.En)
+		})
+	}
+}
+
+// TestDescribeInvalid 测试无效表达式返回错误
+// 时区需单独设置，表达式中带CRON_TZ=或TZ=前缀时不生成描述
+func TestDescribeInvalid(t *testing.T) {
+	tests := []struct {
+		name    string
+		expr    string
+		wantErr string
+	}{
+		{"空表达式", "  ", "调度表达式不能为空"},
+		{"CRON_TZ前缀", "CRON_TZ=Asia/Shanghai 0 0 3 * * *", "调度表达式中不能包含时区"},
+		{"TZ前缀", "TZ=UTC 0 0 3 * * *", "调度表达式中不能包含时区"},
+		{"5段表达式", "0 0 * * *", "expected exactly 6 fields"},
+		{"7段表达式", "0 0 0 * * * 2024", "expected exactly 6 fields"},
+		{"小时超出范围", "0 0 25 * * *", "above maximum"},
+		{"星期超出范围", "0 0 0 * * 8", "above maximum"},
+		{"不支持L", "0 0 0 L * *", "无效"},
+		{"无效的间隔", "@every abc", "无效"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Describe(tt.expr)
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tt.wantErr)
+		})
+	}
+}
+
+// TestSpec 测试按时区生成带CRON_TZ=前缀的调度表达式
+func TestSpec(t *testing.T) {
+	tests := []struct {
+		expr     string
+		timezone string
+		want     string
+	}{
+		{"0 0 3 * * *", "", "0 0 3 * * *"},
+		{" 0 0 3 * * * ", "", "0 0 3 * * *"},
+		{"0 0 3 * * *", "Asia/Shanghai", "CRON_TZ=Asia/Shanghai 0 0 3 * * *"},
+		{"@daily", "UTC", "CRON_TZ=UTC @daily"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.want, func(t *testing.T) {
+			assert.Equal(t, tt.want, Spec(tt.expr, tt.timezone))
+		})
+	}
+}
+
+// TestNextTimes 测试按时区计算后续的执行时间
+func TestNextTimes(t *testing.T) {
+	from := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name     string
+		expr     string
+		timezone string
+		n        int
+		want     []string
+	}{
+		{"UTC每天3点", "0 0 3 * * *", "UTC", 2, []string{"2024-01-01T03:00:00Z", "2024-01-02T03:00:00Z"}},
+		{"上海时区每天3点", "0 0 3 * * *", "Asia/Shanghai", 2, []string{"2024-01-02T03:00:00+08:00", "2024-01-03T03:00:00+08:00"}},
+		{"步长", "0 */20 * * * *", "UTC", 3, []string{"2024-01-01T00:20:00Z", "2024-01-01T00:40:00Z", "2024-01-01T01:00:00Z"}},
+		{"工作日", "0 0 9 * * 1-5", "UTC", 2, []string{"2024-01-01T09:00:00Z", "2024-01-02T09:00:00Z"}},
+		{"不存在的日期", "0 0 0 30 2 *", "UTC", 2, []string{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			times, err := NextTimes(tt.expr, tt.timezone, from, tt.n)
+			require.NoError(t, err)
+			got := make([]string, 0, len(times))
+			for _, item := range times {
+				got = append(got, item.Format(time.RFC3339))
+			}
+			assert.Equal(t, tt.want, got)
+		})
+	}
+
+	_, err := NextTimes("0 0 3 * * *", "Mars/Base", from, 1)
+	assert.ErrorContains(t, err, "时区 Mars/Base 无效")
+}
@@ -1,6 +1,7 @@
 package rclone
 
 import (
+	"context"
 	"fmt"
 	"server/utils/cmd"
 	"server/utils/config"
@@ -12,6 +13,7 @@ import (
 // CmdBisync 执行RClone双向同步(bisync)命令
 // 在两个文件系统之间进行双向同步，并保留备份
 // 参数:
+//   - ctx: 上下文，取消时结束命令
 //   - workdir: 工作目录，命令执行的基础目录
 //   - logPath: 日志文件路径，用于记录执行过程的日志
 //   - srcFs: 源文件系统路径
@@ -23,7 +25,7 @@ import (
 //   - excludes: 排除的文件模式列表，格式为通配符
 //
 // 返回值: 命令执行过程中的错误，成功则为nil
-func CmdBisync(workdir string, logPath string, srcFs string, dstFs string, createEmptySrcDirs bool, removeEmptyDirs bool, showDebug bool, includes []string, excludes []string) error {
+func CmdBisync(ctx context.Context, workdir string, logPath string, srcFs string, dstFs string, createEmptySrcDirs bool, removeEmptyDirs bool, showDebug bool, includes []string, excludes []string) error {
 
 	// 构建bisync基础命令，设置源、目标和备份目录
 	shell := fmt.Sprintf("%s bisync %s %s --backup-dir1 %s --backup-dir2 %s", GetRClonePath(), srcFs, dstFs, srcFs+"_backupdir1", dstFs+"_backupdir2")
@@ -68,6 +70,6 @@ func CmdBisync(workdir string, logPath string, srcFs string, dstFs string, creat
 	logger.LOG.Infof("----------CmdSync------------:%s\n", shell)
 
 	// 执行命令，设置超时时间
-	err := cmd.ExecCronjobWithTimeOut(shell, workdir, logPath, time.Hour*time.Duration(config.CONF.RClone.CmdTimeOut))
+	err := cmd.ExecCronjobWithTimeOut(shell, workdir, logPath, cmd.CronjobOptions{Timeout: time.Hour * time.Duration(config.CONF.RClone.CmdTimeOut), Context: ctx})
 	return err
 }
@@ -1,6 +1,7 @@
 package rclone
 
 import (
+	"context"
 	"fmt"
 	"server/utils/cmd"
 	"server/utils/config"
@@ -12,6 +13,7 @@ import (
 // CmdDelete 执行RClone删除命令，根据指定条件删除文件
 // 可用于定期清理过期文件或备份
 // 参数:
+//   - ctx: 上下文，取消时结束命令
 //   - workdir: 工作目录，命令执行的基础目录
 //   - logPath: 日志文件路径，用于记录执行过程的日志
 //   - workDir: 要清理的目录路径，支持远程存储路径
@@ -22,7 +24,7 @@ import (
 //   - showDebug: 是否显示详细调试信息
 //
 // 返回值: 命令执行过程中的错误，成功则为nil
-func CmdDelete(workdir string, logPath string, workDir string, includes []string, excludes []string, offset time.Duration, isTest bool, showDebug bool) error {
+func CmdDelete(ctx context.Context, workdir string, logPath string, workDir string, includes []string, excludes []string, offset time.Duration, isTest bool, showDebug bool) error {
 
 	// 构建基础删除命令，设置目标目录和最小文件年龄
 	shell := fmt.Sprintf("%s delete %s --min-age %s", GetRClonePath(), workDir, offset)
@@ -66,6 +68,6 @@ func CmdDelete(workdir string, logPath string, workDir string, includes []string
 	logger.LOG.Infof("----------CmdDelete------------:%s\n", shell)
 
 	// 执行命令，设置超时时间
-	err := cmd.ExecCronjobWithTimeOut(shell, workdir, logPath, time.Hour*time.Duration(config.CONF.RClone.CmdTimeOut))
+	err := cmd.ExecCronjobWithTimeOut(shell, workdir, logPath, cmd.CronjobOptions{Timeout: time.Hour * time.Duration(config.CONF.RClone.CmdTimeOut), Context: ctx})
 	return err
 }
@@ -1,6 +1,7 @@
 package rclone
 
 import (
+	"context"
 	"fmt"
 	"server/utils/cmd"
 	"server/utils/config"
@@ -12,6 +13,7 @@ import (
 // CmdCopy 执行RClone复制命令，将文件从源位置复制到目标位置
 // 支持过滤器和各种复制选项
 // 参数:
+//   - ctx: 上下文，取消时结束命令
 //   - workdir: 工作目录，命令执行的基础目录
 //   - logPath: 日志文件路径，用于记录执行过程的日志
 //   - srcFs: 源文件系统路径，可以是本地路径或远程存储路径
@@ -22,7 +24,7 @@ import (
 //   - excludes: 排除的文件模式列表，格式为通配符
 //
 // 返回值: 命令执行过程中的错误，成功则为nil
-func CmdCopy(workdir string, logPath string, srcFs string, dstFs string, createEmptySrcDirs bool, showDebug bool, includes []string, excludes []string) error {
+func CmdCopy(ctx context.Context, workdir string, logPath string, srcFs string, dstFs string, createEmptySrcDirs bool, showDebug bool, includes []string, excludes []string) error {
 	// 构建基础复制命令，设置源和目标路径
 	shell := fmt.Sprintf("%s copy %s %s", GetRClonePath(), srcFs, dstFs)
 
@@ -65,6 +67,6 @@ func CmdCopy(workdir string, logPath string, srcFs string, dstFs string, createE
 	logger.LOG.Infof("----------CmdCopy------------:%s\n", shell)
 
 	// 执行命令，设置超时时间
-	err := cmd.ExecCronjobWithTimeOut(shell, workdir, logPath, time.Hour*time.Duration(config.CONF.RClone.CmdTimeOut))
+	err := cmd.ExecCronjobWithTimeOut(shell, workdir, logPath, cmd.CronjobOptions{Timeout: time.Hour * time.Duration(config.CONF.RClone.CmdTimeOut), Context: ctx})
 	return err
 }
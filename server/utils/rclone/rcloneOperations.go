@@ -0,0 +1,45 @@
+package rclone
+
+import (
+	"errors"
+	"server/utils/data"
+	"strconv"
+)
+
+// CopyFile 通过RClone API复制单个文件
+// 参数:
+//   - srcFs: 源文件系统，如本地目录或"remote:"
+//   - srcRemote: 源文件在文件系统中的路径
+//   - dstFs: 目标文件系统
+//   - dstRemote: 目标文件在文件系统中的路径
+//
+// 返回值: 复制过程中的错误，成功则为nil
+func CopyFile(srcFs string, srcRemote string, dstFs string, dstRemote string) error {
+	params := data.Map{"srcFs": srcFs, "srcRemote": srcRemote, "dstFs": dstFs, "dstRemote": dstRemote}
+	return operation("operations/copyfile", params, "复制文件失败")
+}
+
+// DeleteFile 通过RClone API删除单个文件
+// 参数:
+//   - fs: 文件系统，如"remote:"
+//   - remote: 文件在文件系统中的路径
+//
+// 返回值: 删除过程中的错误，成功则为nil
+func DeleteFile(fs string, remote string) error {
+	return operation("operations/deletefile", data.Map{"fs": fs, "remote": remote}, "删除文件失败")
+}
+
+// operation 调用RClone文件操作API
+func operation(url string, params data.Map, message string) error {
+	if GetRConfigPath() != "" {
+		params["_config"] = data.Map{"config": GetRConfigPath()}
+	}
+	code, data, err := Api(url, params)
+	if err != nil {
+		return err
+	}
+	if code != 200 {
+		return errors.New(message + ": code:" + strconv.Itoa(code) + ", msg:" + getResultError(data))
+	}
+	return nil
+}
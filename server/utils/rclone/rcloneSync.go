@@ -1,6 +1,7 @@
 package rclone
 
 import (
+	"context"
 	"fmt"
 	"server/utils/cmd"
 	"server/utils/logger"
@@ -13,6 +14,7 @@ import (
 // CmdSync 执行RClone同步命令，将源目录同步到目标目录
 // 同步操作会使目标目录与源目录的内容完全一致
 // 参数:
+//   - ctx: 上下文，取消时结束命令
 //   - workdir: 工作目录，命令执行的基础目录
 //   - logPath: 日志文件路径，用于记录执行过程的日志
 //   - srcFs: 源文件系统路径，可以是本地路径或远程存储路径
@@ -23,7 +25,7 @@ import (
 //   - excludes: 排除的文件模式列表，格式为通配符
 //
 // 返回值: 命令执行过程中的错误，成功则为nil
-func CmdSync(workdir string, logPath string, srcFs string, dstFs string, createEmptySrcDirs bool, showDebug bool, includes []string, excludes []string) error {
+func CmdSync(ctx context.Context, workdir string, logPath string, srcFs string, dstFs string, createEmptySrcDirs bool, showDebug bool, includes []string, excludes []string) error {
 	// 构建基础同步命令，设置源和目标路径
 	shell := fmt.Sprintf("%s sync %s %s", GetRClonePath(), srcFs, dstFs)
 
@@ -66,6 +68,6 @@ func CmdSync(workdir string, logPath string, srcFs string, dstFs string, createE
 	logger.LOG.Infof("----------CmdSync------------:%s\n", shell)
 
 	// 执行命令，设置超时时间
-	err := cmd.ExecCronjobWithTimeOut(shell, workdir, logPath, time.Hour*time.Duration(config.CONF.RClone.CmdTimeOut))
+	err := cmd.ExecCronjobWithTimeOut(shell, workdir, logPath, cmd.CronjobOptions{Timeout: time.Hour * time.Duration(config.CONF.RClone.CmdTimeOut), Context: ctx})
 	return err
 }